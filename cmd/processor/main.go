@@ -1,6 +1,7 @@
 package main
 
 import (
+	"CS6650_Online_Store/internal/store"
 	"CS6650_Online_Store/internal/worker"
 	"log"
 	"os"
@@ -23,6 +24,17 @@ func main() {
 		return
 	}
 
+	// Order statuses go to the store the server reads them from
+	orders, closeOrders, err := store.OpenOrderStore(os.Getenv("ORDER_STORE"), os.Getenv("ORDER_STORE_DSN"))
+	if err != nil {
+		log.Fatalf("Failed to open order store: %v", err)
+	}
+	defer closeOrders()
+	if _, inMemory := orders.(*store.MemoryOrderStore); inMemory {
+		log.Println("Warning: ORDER_STORE is memory, so order statuses are not visible to the server")
+	}
+	processor.SetOrderStore(orders)
+
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -2,11 +2,19 @@ package main
 
 import (
 	"CS6650_Online_Store/internal/handlers"
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/payment"
 	"CS6650_Online_Store/internal/store"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -19,25 +27,160 @@ func main() {
 	}
 
 	// Initialize store
-	productStore := store.NewProductStore()
+	productStore, closeStore := newProductRepository()
+	defer closeStore()
+	warnUnpricedProducts(productStore)
 
+	categoryStore := newCategoryStore(productStore)
+	brandRegistry, manufacturerRegistry := newNameRegistries(productStore)
+	inventoryStore := newInventoryStore()
+	priceHistory := newPriceHistory()
+	orderStore, closeOrders := newOrderStore()
+	defer closeOrders()
+
+	// Scheduled price changes are applied to the catalog in the background
+	priceScheduler := store.NewPriceScheduler(priceHistory, productStore, priceScheduleInterval())
+	priceScheduler.Start()
+	defer priceScheduler.Close()
+
+	// Stock held for async orders is sold or released once they are finished
+	stockSettler := store.NewStockSettler(inventoryStore, orderStore, stockSettleInterval())
+	stockSettler.Start()
+	defer stockSettler.Close()
+
+	router := newRouter(routerDeps{
+		products:      productStore,
+		categories:    categoryStore,
+		brands:        brandRegistry,
+		manufacturers: manufacturerRegistry,
+		inventory:     inventoryStore,
+		prices:        priceHistory,
+		orders:        orderStore,
+		idempotency:   store.NewIdempotencyStore(idempotencyTTL()),
+	})
+
+	// Start server
+	addr := fmt.Sprintf(":%s", port)
+	server := &http.Server{Addr: addr, Handler: router}
+
+	go func() {
+		log.Printf("Starting server on %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for shutdown signal so the store can flush a final snapshot
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+	log.Println("Shutdown signal received...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
+	}
+}
+
+// routerDeps are the stores and services the routes are served from
+type routerDeps struct {
+	products      store.ProductRepository
+	categories    *store.CategoryStore
+	brands        *store.BrandRegistry
+	manufacturers *store.BrandRegistry
+	inventory     *store.InventoryStore
+	prices        *store.PriceHistory
+	orders        store.OrderStore
+	idempotency   *store.IdempotencyStore
+
+	// Payment gateway orders are charged through; nil keeps the order
+	// handler's simulated gateway
+	payments payment.Gateway
+}
+
+// newRouter creates the handlers over deps and registers every route
+func newRouter(deps routerDeps) *mux.Router {
 	// Initialize handlers
-	productHandler := handlers.NewProductHandler(productStore)
+	productHandler := handlers.NewProductHandler(deps.products)
+	productHandler.SetNameRegistries(deps.brands, deps.manufacturers)
+	productHandler.SetPriceHistory(deps.prices)
+	productHandler.SetCategories(deps.categories)
+	categoryHandler := handlers.NewCategoryHandler(deps.categories, deps.products)
+	brandHandler := handlers.NewBrandHandler(deps.brands, deps.products, handlers.BrandField)
+	manufacturerHandler := handlers.NewBrandHandler(deps.manufacturers, deps.products, handlers.ManufacturerField)
+	inventoryHandler := handlers.NewInventoryHandler(deps.inventory, deps.products)
+	priceHandler := handlers.NewPriceHandler(deps.prices, deps.products)
 	orderHandler := handlers.NewOrderHandler()
+	orderHandler.SetInventory(deps.inventory)
+	orderHandler.SetOrderStore(deps.orders)
+	orderHandler.SetPriceBook(store.NewScheduledPriceBook(store.NewCatalogPriceBook(deps.products), deps.prices))
+	if deps.payments != nil {
+		orderHandler.SetPaymentGateway(deps.payments)
+	}
+	idempotency := handlers.NewIdempotencyHandler(deps.idempotency)
 
 	// Setup router
 	router := mux.NewRouter()
 
 	// Order endpoints for Homework 7
-	router.HandleFunc("/orders/sync", orderHandler.ProcessOrderSync).Methods("POST")
-	router.HandleFunc("/orders/async", orderHandler.ProcessOrderAsync).Methods("POST")
+	// Submissions have a capped body size, and those carrying an Idempotency-Key
+	// are handled once and replayed for retries
+	submitOrder := func(next http.HandlerFunc) http.HandlerFunc {
+		return handlers.LimitBody(handlers.MaxOrderBodyBytes, idempotency.Wrap(next))
+	}
+	router.HandleFunc("/orders/sync", submitOrder(orderHandler.ProcessOrderSync)).Methods("POST")
+	router.HandleFunc("/orders/async", submitOrder(orderHandler.ProcessOrderAsync)).Methods("POST")
+	router.HandleFunc("/orders/{orderId}", orderHandler.GetOrder).Methods("GET")
+	router.HandleFunc("/orders/{orderId}/cancel", orderHandler.CancelOrder).Methods("POST")
+	router.HandleFunc("/orders/{orderId}/refund", orderHandler.RefundOrder).Methods("POST")
 
 	// Product endpoints - order matters! Specific routes before parameterized ones
 	// Search endpoint for Homework 6 - searches exactly 100 products per request
 	router.HandleFunc("/products/search", productHandler.SearchProducts).Methods("GET")
+	router.HandleFunc("/products/suggest", productHandler.SuggestProducts).Methods("GET")
+	router.HandleFunc("/products/export", productHandler.ExportProducts).Methods("GET")
+	router.HandleFunc("/products/import", productHandler.ImportProducts).Methods("POST")
 
+	router.HandleFunc("/products/batch-get", productHandler.BatchGetProducts).Methods("POST")
+
+	router.HandleFunc("/products", productHandler.ListProducts).Methods("GET")
+	router.HandleFunc("/products", productHandler.CreateProduct).Methods("POST")
 	router.HandleFunc("/products/{productId}", productHandler.GetProduct).Methods("GET")
+	router.HandleFunc("/products/{productId}", productHandler.PatchProduct).Methods("PATCH")
+	router.HandleFunc("/products/{productId}", productHandler.DeleteProduct).Methods("DELETE")
 	router.HandleFunc("/products/{productId}/details", productHandler.AddProductDetails).Methods("POST")
+	router.HandleFunc("/products/{productId}/inventory", inventoryHandler.GetInventory).Methods("GET")
+	router.HandleFunc("/products/{productId}/inventory", inventoryHandler.SetInventory).Methods("PUT")
+	router.HandleFunc("/products/{productId}/prices", priceHandler.GetPrices).Methods("GET")
+	router.HandleFunc("/products/{productId}/prices", priceHandler.SchedulePrice).Methods("POST")
+	router.HandleFunc("/products/{productId}/prices/{changeId}", priceHandler.CancelPriceChange).Methods("DELETE")
+
+	// Category endpoints
+	router.HandleFunc("/categories", categoryHandler.ListCategories).Methods("GET")
+	router.HandleFunc("/categories", categoryHandler.CreateCategory).Methods("POST")
+	router.HandleFunc("/categories/tree", categoryHandler.CategoryTree).Methods("GET")
+	router.HandleFunc("/categories/{categoryId}", categoryHandler.GetCategory).Methods("GET")
+	router.HandleFunc("/categories/{categoryId}", categoryHandler.UpdateCategory).Methods("PUT")
+	router.HandleFunc("/categories/{categoryId}", categoryHandler.DeleteCategory).Methods("DELETE")
+	router.HandleFunc("/categories/{categoryId}/products", categoryHandler.CategoryProducts).Methods("GET")
+
+	// Warehouse and stock administration
+	router.HandleFunc("/warehouses", inventoryHandler.ListWarehouses).Methods("GET")
+	router.HandleFunc("/warehouses", inventoryHandler.CreateWarehouse).Methods("POST")
+	router.HandleFunc("/warehouses/{warehouseId}", inventoryHandler.GetWarehouse).Methods("GET")
+	router.HandleFunc("/warehouses/{warehouseId}/inventory", inventoryHandler.WarehouseStock).Methods("GET")
+	router.HandleFunc("/inventory/transfers", inventoryHandler.TransferStock).Methods("POST")
+
+	// Brand and manufacturer registries share one API shape
+	for prefix, handler := range map[string]*handlers.BrandHandler{"/brands": brandHandler, "/manufacturers": manufacturerHandler} {
+		router.HandleFunc(prefix, handler.ListBrands).Methods("GET")
+		router.HandleFunc(prefix, handler.CreateBrand).Methods("POST")
+		router.HandleFunc(prefix+"/{brandId}", handler.GetBrand).Methods("GET")
+		router.HandleFunc(prefix+"/{brandId}/aliases", handler.AddBrandAlias).Methods("POST")
+		router.HandleFunc(prefix+"/{brandId}/aliases/{alias}", handler.RemoveBrandAlias).Methods("DELETE")
+		router.HandleFunc(prefix+"/{brandId}/merge", handler.MergeBrands).Methods("POST")
+	}
 
 	// Health check endpoint with circuit breaker status
 	router.HandleFunc("/health", productHandler.HealthCheck).Methods("GET")
@@ -45,10 +188,300 @@ func main() {
 	// Logging middleware
 	router.Use(loggingMiddleware)
 
-	// Start server
-	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Starting server on %s", addr)
-	log.Fatal(http.ListenAndServe(addr, router))
+	return router
+}
+
+// newProductRepository picks the product storage backend from STORE_BACKEND:
+//   - memory (default): products live only in memory and are regenerated on every boot
+//   - file: durable WAL + snapshot store in STORE_DATA_DIR
+//   - sqlite: SQLite database file at STORE_DSN (default products.db)
+//   - postgres: Postgres database at STORE_DSN
+//
+// For backward compatibility, setting only STORE_DATA_DIR selects the file backend.
+func newProductRepository() (store.ProductRepository, func()) {
+	backend := os.Getenv("STORE_BACKEND")
+	dataDir := os.Getenv("STORE_DATA_DIR")
+	if backend == "" && dataDir != "" {
+		backend = "file"
+	}
+
+	seed := seedConfigFromEnv()
+
+	switch backend {
+	case "", "memory":
+		memoryStore, err := store.NewSeededProductStore(seed)
+		if err != nil {
+			log.Fatalf("Failed to seed product store: %v", err)
+		}
+		return memoryStore, func() {}
+
+	case "file":
+		if dataDir == "" {
+			log.Fatal("STORE_BACKEND=file requires STORE_DATA_DIR")
+		}
+
+		opts := store.PersistenceOptions{
+			Dir:              dataDir,
+			SnapshotInterval: 5 * time.Minute,
+			SnapshotEvery:    10000,
+			Seed:             &seed,
+		}
+		if interval, err := time.ParseDuration(os.Getenv("STORE_SNAPSHOT_INTERVAL")); err == nil {
+			opts.SnapshotInterval = interval
+		}
+		if every, err := strconv.Atoi(os.Getenv("STORE_SNAPSHOT_EVERY")); err == nil {
+			opts.SnapshotEvery = every
+		}
+
+		persistentStore, err := store.OpenPersistentProductStore(opts)
+		if err != nil {
+			log.Fatalf("Failed to open product store in %s: %v", dataDir, err)
+		}
+		log.Printf("Using durable product store in %s", dataDir)
+
+		return persistentStore, func() {
+			if err := persistentStore.Close(); err != nil {
+				log.Printf("Failed to close product store: %v", err)
+			}
+		}
+
+	case store.DriverSQLite, store.DriverPostgres:
+		dsn := os.Getenv("STORE_DSN")
+		if dsn == "" {
+			if backend == store.DriverPostgres {
+				log.Fatal("STORE_BACKEND=postgres requires STORE_DSN")
+			}
+			dsn = "products.db"
+		}
+
+		sqlStore, err := store.OpenSQLProductStore(backend, dsn, &seed)
+		if err != nil {
+			log.Fatalf("Failed to open %s product store: %v", backend, err)
+		}
+		log.Printf("Using %s product store", backend)
+
+		return sqlStore, func() {
+			if err := sqlStore.Close(); err != nil {
+				log.Printf("Failed to close product store: %v", err)
+			}
+		}
+	}
+
+	log.Fatalf("Unknown STORE_BACKEND %q (expected memory, file, sqlite or postgres)", backend)
+	return nil, nil
+}
+
+// warnUnpricedProducts logs how many products have no price, which is the
+// case for products stored before prices were added; they cannot be ordered
+// until an operator prices them
+func warnUnpricedProducts(products store.ProductRepository) {
+	unpriced := 0
+	err := products.Scan(func(product *models.Product) bool {
+		if product.Price <= 0 {
+			unpriced++
+		}
+		return true
+	})
+	if err != nil {
+		log.Printf("Warning: failed to check product prices: %v", err)
+		return
+	}
+	if unpriced > 0 {
+		log.Printf("Warning: %d products have no price and cannot be ordered until priced (see README)", unpriced)
+	}
+}
+
+// newCategoryStore opens the category tree - kept in STORE_DATA_DIR when it is
+// set, otherwise in memory - and adopts every category the catalog already uses
+func newCategoryStore(products store.ProductRepository) *store.CategoryStore {
+	categoryStore := store.NewCategoryStore()
+	if dataDir := os.Getenv("STORE_DATA_DIR"); dataDir != "" {
+		var err error
+		categoryStore, err = store.OpenCategoryStore(filepath.Join(dataDir, "categories.json"))
+		if err != nil {
+			log.Fatalf("Failed to open category store: %v", err)
+		}
+	}
+
+	added, err := categoryStore.RegisterProductCategories(products)
+	if err != nil {
+		log.Fatalf("Failed to register product categories: %v", err)
+	}
+	if added > 0 {
+		log.Printf("Registered %d categories used by existing products", added)
+	}
+
+	return categoryStore
+}
+
+// newNameRegistries opens the brand and manufacturer registries - kept in
+// STORE_DATA_DIR when it is set, otherwise in memory - registers every name
+// the catalog already uses and rewrites products stored under a non-canonical
+// spelling
+func newNameRegistries(products store.ProductRepository) (brands, manufacturers *store.BrandRegistry) {
+	brands, manufacturers = store.NewBrandRegistry(), store.NewBrandRegistry()
+	if dataDir := os.Getenv("STORE_DATA_DIR"); dataDir != "" {
+		var err error
+		if brands, err = store.OpenBrandRegistry(filepath.Join(dataDir, "brands.json")); err != nil {
+			log.Fatalf("Failed to open brand registry: %v", err)
+		}
+		if manufacturers, err = store.OpenBrandRegistry(filepath.Join(dataDir, "manufacturers.json")); err != nil {
+			log.Fatalf("Failed to open manufacturer registry: %v", err)
+		}
+	}
+
+	normalized, err := store.NormalizeProductNames(products, brands, manufacturers)
+	if err != nil {
+		log.Fatalf("Failed to normalize brand names: %v", err)
+	}
+	if normalized > 0 {
+		log.Printf("Normalized brand or manufacturer names of %d products", normalized)
+	}
+	return brands, manufacturers
+}
+
+// newInventoryStore opens the warehouses and stock levels - kept in STORE_DATA_DIR when it is
+// set, otherwise in memory
+func newInventoryStore() *store.InventoryStore {
+	dataDir := os.Getenv("STORE_DATA_DIR")
+	if dataDir == "" {
+		return store.NewInventoryStore()
+	}
+
+	inventoryStore, err := store.OpenInventoryStore(filepath.Join(dataDir, "inventory.json"))
+	if err != nil {
+		log.Fatalf("Failed to open inventory store: %v", err)
+	}
+	return inventoryStore
+}
+
+// newOrderStore opens the order records from ORDER_STORE: memory (default),
+// sqlite or postgres, at ORDER_STORE_DSN. The order processor must use the
+// same durable store for GET /orders/{id} to see async orders complete.
+func newOrderStore() (store.OrderStore, func()) {
+	orders, closeOrders, err := store.OpenOrderStore(os.Getenv("ORDER_STORE"), os.Getenv("ORDER_STORE_DSN"))
+	if err != nil {
+		log.Fatalf("Failed to open order store: %v", err)
+	}
+	return orders, func() {
+		if err := closeOrders(); err != nil {
+			log.Printf("Failed to close order store: %v", err)
+		}
+	}
+}
+
+// newPriceHistory opens the price timelines - kept in STORE_DATA_DIR when it
+// is set, otherwise in memory
+func newPriceHistory() *store.PriceHistory {
+	dataDir := os.Getenv("STORE_DATA_DIR")
+	if dataDir == "" {
+		return store.NewPriceHistory()
+	}
+
+	history, err := store.OpenPriceHistory(filepath.Join(dataDir, "prices.json"))
+	if err != nil {
+		log.Fatalf("Failed to open price history: %v", err)
+	}
+	return history
+}
+
+// priceScheduleInterval is how often scheduled prices are checked, from
+// PRICE_SCHEDULE_INTERVAL (default 30s)
+func priceScheduleInterval() time.Duration {
+	value := os.Getenv("PRICE_SCHEDULE_INTERVAL")
+	if value == "" {
+		return 30 * time.Second
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Fatalf("Invalid PRICE_SCHEDULE_INTERVAL %q: must be a positive duration", value)
+	}
+	return interval
+}
+
+// stockSettleInterval is how often the stock reserved for async orders is
+// settled against their status, from STOCK_SETTLE_INTERVAL (default 10s)
+func stockSettleInterval() time.Duration {
+	value := os.Getenv("STOCK_SETTLE_INTERVAL")
+	if value == "" {
+		return 10 * time.Second
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Fatalf("Invalid STOCK_SETTLE_INTERVAL %q: must be a positive duration", value)
+	}
+	return interval
+}
+
+// idempotencyTTL is how long the response to an Idempotency-Key is replayed,
+// from IDEMPOTENCY_TTL (default 24h)
+func idempotencyTTL() time.Duration {
+	value := os.Getenv("IDEMPOTENCY_TTL")
+	if value == "" {
+		return 24 * time.Hour
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Fatalf("Invalid IDEMPOTENCY_TTL %q: must be a positive duration", value)
+	}
+	return ttl
+}
+
+// seedConfigFromEnv builds the catalog a new store starts with. Durable
+// backends only seed when they hold no data yet.
+//
+//   - CATALOG_SEED: none, synthetic (default) or fixture
+//   - CATALOG_FIXTURE: .jsonl or .csv file of products; setting it alone selects fixture
+//   - CATALOG_SIZE, CATALOG_BRANDS, CATALOG_CATEGORIES: synthetic catalog shape
+//   - CATALOG_DISTRIBUTION: cyclic (default), uniform or zipf brand/category spread
+//   - CATALOG_ZIPF_S: zipf skew exponent, > 1
+//   - CATALOG_RANDOM_SEED: seed for the uniform and zipf generators
+func seedConfigFromEnv() store.SeedConfig {
+	cfg := store.DefaultSeedConfig()
+
+	cfg.FixturePath = os.Getenv("CATALOG_FIXTURE")
+	if cfg.FixturePath != "" {
+		cfg.Mode = store.SeedFixture
+	}
+	if mode := os.Getenv("CATALOG_SEED"); mode != "" {
+		cfg.Mode = mode
+	}
+	if distribution := os.Getenv("CATALOG_DISTRIBUTION"); distribution != "" {
+		cfg.Distribution = distribution
+	}
+
+	for name, target := range map[string]*int{
+		"CATALOG_SIZE":       &cfg.Size,
+		"CATALOG_BRANDS":     &cfg.Brands,
+		"CATALOG_CATEGORIES": &cfg.Categories,
+	} {
+		if value := os.Getenv(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				log.Fatalf("Invalid %s %q: %v", name, value, err)
+			}
+			*target = n
+		}
+	}
+	if value := os.Getenv("CATALOG_ZIPF_S"); value != "" {
+		s, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Fatalf("Invalid CATALOG_ZIPF_S %q: %v", value, err)
+		}
+		cfg.ZipfS = s
+	}
+	if value := os.Getenv("CATALOG_RANDOM_SEED"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid CATALOG_RANDOM_SEED %q: %v", value, err)
+		}
+		cfg.RandomSeed = seed
+	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid catalog seed configuration: %v", err)
+	}
+	return cfg
 }
 
 // loggingMiddleware logs all incoming requests
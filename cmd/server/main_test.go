@@ -3,12 +3,22 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"CS6650_Online_Store/internal/handlers"
 	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/payment"
 	"CS6650_Online_Store/internal/store"
 
 	"github.com/gorilla/mux"
@@ -16,8 +26,13 @@ import (
 
 // setupTestServer creates a test server with the same routes as main
 func setupTestServer() *mux.Router {
-	// Initialize store and handlers (same as main.go)
-	productStore := store.NewProductStore()
+	// Initialize store and handlers (same as main.go), over a small catalog
+	cfg := store.DefaultSeedConfig()
+	cfg.Size = 100
+	productStore, err := store.NewSeededProductStore(cfg)
+	if err != nil {
+		panic(err)
+	}
 	productHandler := handlers.NewProductHandler(productStore)
 
 	// Setup router (same as main.go)
@@ -292,6 +307,1353 @@ func TestConcurrentRequests(t *testing.T) {
 	}
 }
 
+// newTestDeps returns the dependencies of main over in-memory stores and an
+// empty catalog, charging orders through a gateway that never waits
+func newTestDeps() routerDeps {
+	return routerDeps{
+		products:      store.NewEmptyProductStore(),
+		categories:    store.NewCategoryStore(),
+		brands:        store.NewBrandRegistry(),
+		manufacturers: store.NewBrandRegistry(),
+		inventory:     store.NewInventoryStore(),
+		prices:        store.NewPriceHistory(),
+		orders:        store.NewMemoryOrderStore(),
+		idempotency:   store.NewIdempotencyStore(time.Hour),
+		payments:      &countingGateway{},
+	}
+}
+
+// seededCatalog returns a synthetic catalog of size products
+func seededCatalog(t *testing.T, size int) *store.ProductStore {
+	t.Helper()
+	cfg := store.DefaultSeedConfig()
+	cfg.Size = size
+	catalog, err := store.NewSeededProductStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return catalog
+}
+
+// doRequest serves one request through router and returns the response
+func doRequest(router http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestOrdersForProductsStoredBeforePrices(t *testing.T) {
+	// A data directory written before products had a price column
+	dir := t.TempDir()
+	snapshot := `{"seq":0,"count":1}
+{"product_id":1,"sku":"SKU-1","manufacturer":"Acme","category_id":1,"weight":100,"some_other_id":1,"name":"Widget","category":"Electronics","description":"A widget","brand":"Acme","version":1}
+`
+	if err := os.WriteFile(filepath.Join(dir, "products.snapshot"), []byte(snapshot), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	productStore, err := store.OpenPersistentProductStore(store.PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	defer productStore.Close()
+
+	deps := newTestDeps()
+	deps.products = productStore
+	router := newRouter(deps)
+
+	order := `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 2}]}`
+
+	// The product loads unpriced, and the order says so instead of charging $0
+	rr := doRequest(router, "POST", "/orders/sync", order, nil)
+	var errorResponse models.Error
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	if rr.Code != http.StatusConflict || errorResponse.Error != "PRODUCT_UNPRICED" {
+		t.Fatalf("Expected 409 PRODUCT_UNPRICED, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// The documented operator step: export, fill in prices, import
+	rr = doRequest(router, "GET", "/products/export?format=csv", "", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Export failed: %d %s", rr.Code, rr.Body.String())
+	}
+	catalog := strings.Replace(rr.Body.String(), ",Acme,0.00,", ",Acme,12.50,", 1)
+	rr = doRequest(router, "POST", "/products/import", catalog, map[string]string{"Content-Type": "text/csv"})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"updated":1`) {
+		t.Fatalf("Import failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = doRequest(router, "POST", "/orders/sync", order, nil)
+	var placed models.Order
+	json.Unmarshal(rr.Body.Bytes(), &placed)
+	if rr.Code != http.StatusOK || placed.Total != 25 {
+		t.Errorf("Expected the order to be charged $25.00 once priced, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+// countingGateway is a payment gateway that records the orders it charges and
+// can be made to fail
+type countingGateway struct {
+	mu             sync.Mutex
+	delay          time.Duration
+	failures       int // charges to reject before accepting any
+	refundFailures int // refunds to reject before accepting any
+	charged        []string
+	refunded       []string
+}
+
+func (g *countingGateway) Charge(orderID string, amount float64) error {
+	time.Sleep(g.delay)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failures > 0 {
+		g.failures--
+		return errors.New("card declined")
+	}
+	g.charged = append(g.charged, orderID)
+	return nil
+}
+
+func (g *countingGateway) Refund(orderID string, amount float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.refundFailures > 0 {
+		g.refundFailures--
+		return errors.New("refund rejected")
+	}
+	g.refunded = append(g.refunded, orderID)
+	return nil
+}
+
+func (g *countingGateway) charges() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.charged)
+}
+
+// newOrderTestDeps returns test dependencies over a catalog of product 1
+// ($12.50, 10 in stock) and product 2 ($4.00, untracked), charging orders
+// through payments
+func newOrderTestDeps(payments payment.Gateway) routerDeps {
+	deps := newTestDeps()
+	for _, product := range []*models.Product{
+		{ProductID: 1, SKU: "SKU-1", Manufacturer: "Acme", CategoryID: 1, Weight: 100, SomeOtherID: 1,
+			Name: "Widget", Category: "Electronics", Description: "A widget", Brand: "Acme", Price: 12.50},
+		{ProductID: 2, SKU: "SKU-2", Manufacturer: "Acme", CategoryID: 1, Weight: 50, SomeOtherID: 1,
+			Name: "Gadget", Category: "Electronics", Description: "A gadget", Brand: "Acme", Price: 4},
+	} {
+		deps.products.Upsert(product)
+	}
+	deps.inventory.SetOnHand(1, store.DefaultWarehouseID, 10)
+	deps.payments = payments
+	return deps
+}
+
+func TestIdempotentOrderSubmission(t *testing.T) {
+	payments := &countingGateway{delay: 20 * time.Millisecond}
+	router := newRouter(newOrderTestDeps(payments))
+	order := `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 2}]}`
+	headers := map[string]string{handlers.IdempotencyKeyHeader: "checkout-1"}
+
+	// A client retrying while its first request is still being charged
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 10)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = doRequest(router, "POST", "/orders/sync", order, headers)
+		}(i)
+	}
+	wg.Wait()
+
+	if payments.charges() != 1 {
+		t.Errorf("Expected one charge for duplicate submissions, got %d", payments.charges())
+	}
+	replayed := 0
+	for i, rr := range responses {
+		if rr.Code != http.StatusOK || rr.Body.String() != responses[0].Body.String() {
+			t.Errorf("Expected response %d to match the original, got %d %s", i, rr.Code, rr.Body.String())
+		}
+		if rr.Header().Get("Idempotent-Replayed") == "true" {
+			replayed++
+		}
+	}
+	if replayed != len(responses)-1 {
+		t.Errorf("Expected %d replayed responses, got %d", len(responses)-1, replayed)
+	}
+
+	t.Run("Key reused for a different order", func(t *testing.T) {
+		other := `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 3}]}`
+		rr := doRequest(router, "POST", "/orders/sync", other, headers)
+		if rr.Code != http.StatusUnprocessableEntity || !strings.Contains(rr.Body.String(), "IDEMPOTENCY_KEY_REUSED") {
+			t.Errorf("Expected 422 IDEMPOTENCY_KEY_REUSED, got %d %s", rr.Code, rr.Body.String())
+		}
+		if payments.charges() != 1 {
+			t.Errorf("Expected no further charge, got %d", payments.charges())
+		}
+	})
+}
+
+func TestIdempotentOrderRetriedAfterServerError(t *testing.T) {
+	payments := &countingGateway{failures: 1}
+	router := newRouter(newOrderTestDeps(payments))
+	order := `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 1}]}`
+	headers := map[string]string{handlers.IdempotencyKeyHeader: "checkout-2"}
+
+	// A 5xx is not recorded, so the key is free for the retry
+	if rr := doRequest(router, "POST", "/orders/sync", order, headers); rr.Code != http.StatusBadGateway {
+		t.Fatalf("Expected the declined payment to fail with 502, got %d %s", rr.Code, rr.Body.String())
+	}
+	rr := doRequest(router, "POST", "/orders/sync", order, headers)
+	if rr.Code != http.StatusOK || rr.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("Expected the retry to be processed, got %d %v %s", rr.Code, rr.Header(), rr.Body.String())
+	}
+
+	// Once it succeeded, the key replays it
+	replay := doRequest(router, "POST", "/orders/sync", order, headers)
+	if replay.Header().Get("Idempotent-Replayed") != "true" || replay.Body.String() != rr.Body.String() {
+		t.Errorf("Expected the successful response to be replayed, got %d %s", replay.Code, replay.Body.String())
+	}
+	if payments.charges() != 1 {
+		t.Errorf("Expected one successful charge, got %d", payments.charges())
+	}
+}
+
+func TestIdempotentOrderRetriedAfterStockArrives(t *testing.T) {
+	deps := newOrderTestDeps(&countingGateway{})
+	router := newRouter(deps)
+	order := `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 12}]}`
+	headers := map[string]string{handlers.IdempotencyKeyHeader: "checkout-3"}
+
+	// Running out of stock depends on the moment, so it is not replayed
+	if rr := doRequest(router, "POST", "/orders/sync", order, headers); rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 OUT_OF_STOCK, got %d %s", rr.Code, rr.Body.String())
+	}
+	if _, err := deps.inventory.SetOnHand(1, store.DefaultWarehouseID, 20); err != nil {
+		t.Fatalf("SetOnHand() error = %v", err)
+	}
+	if rr := doRequest(router, "POST", "/orders/sync", order, headers); rr.Code != http.StatusOK || rr.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected the retry to be processed once stocked, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// An invalid order is invalid whenever it is retried, so it is replayed
+	invalid := `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 0}]}`
+	headers = map[string]string{handlers.IdempotencyKeyHeader: "checkout-4"}
+	doRequest(router, "POST", "/orders/sync", invalid, headers)
+	if rr := doRequest(router, "POST", "/orders/sync", invalid, headers); rr.Code != http.StatusBadRequest || rr.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the validation error to be replayed, got %d %v", rr.Code, rr.Header())
+	}
+}
+
+// widgetJSON is a valid product for POST /products
+const widgetJSON = `{"sku": "SKU-1", "manufacturer": "Acme", "category_id": 1, "weight": 100,
+	"some_other_id": 1, "name": "Widget", "category": "Electronics",
+	"description": "A widget", "brand": "Acme", "price": 12.5}`
+
+func TestProductCRUD(t *testing.T) {
+	router := newRouter(newTestDeps())
+
+	t.Run("Create assigns the ID", func(t *testing.T) {
+		rr := doRequest(router, "POST", "/products", widgetJSON, nil)
+		if rr.Code != http.StatusCreated || rr.Header().Get("Location") != "/products/1" {
+			t.Fatalf("Expected 201 at /products/1, got %d %v %s", rr.Code, rr.Header(), rr.Body.String())
+		}
+
+		withID := strings.Replace(widgetJSON, "{", `{"product_id": 5, `, 1)
+		if rr := doRequest(router, "POST", "/products", withID, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a client-chosen product_id, got %d", rr.Code)
+		}
+	})
+
+	t.Run("PATCH merges into the stored product", func(t *testing.T) {
+		patch := `{"name": "Renamed Widget", "description": null}`
+		rr := doRequest(router, "PATCH", "/products/1", patch, map[string]string{"If-Match": `"1"`})
+		var patched models.Product
+		json.Unmarshal(rr.Body.Bytes(), &patched)
+		if rr.Code != http.StatusOK || patched.Name != "Renamed Widget" || patched.Description != "" ||
+			patched.SKU != "SKU-1" || patched.Brand != "Acme" || patched.Price != 12.5 {
+			t.Errorf("Expected name replaced, description cleared and the rest kept, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Invalid PATCH leaves the product alone", func(t *testing.T) {
+		for _, patch := range []string{`{"weight": -1}`, `{"product_id": 9}`, `{"colour": "red"}`, `[1]`} {
+			rr := doRequest(router, "PATCH", "/products/1", patch, map[string]string{"If-Match": `"2"`})
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d %s", patch, rr.Code, rr.Body.String())
+			}
+		}
+		rr := doRequest(router, "GET", "/products/1", "", nil)
+		if !strings.Contains(rr.Body.String(), `"weight":100`) || rr.Header().Get("ETag") != `"2"` {
+			t.Errorf("Expected the product unchanged, got %v %s", rr.Header(), rr.Body.String())
+		}
+	})
+
+	t.Run("DELETE and missing products", func(t *testing.T) {
+		if rr := doRequest(router, "DELETE", "/products/1", "", nil); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d %s", rr.Code, rr.Body.String())
+		}
+		for _, req := range []struct{ method, body string }{
+			{"DELETE", ""}, {"GET", ""}, {"PATCH", `{"name": "Ghost"}`},
+		} {
+			if rr := doRequest(router, req.method, "/products/1", req.body, nil); rr.Code != http.StatusNotFound {
+				t.Errorf("Expected 404 for %s of a deleted product, got %d", req.method, rr.Code)
+			}
+		}
+		if rr := doRequest(router, "DELETE", "/products/0", "", nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an invalid ID, got %d", rr.Code)
+		}
+	})
+}
+
+func TestProductETags(t *testing.T) {
+	router := newRouter(newTestDeps())
+	if rr := doRequest(router, "POST", "/products", widgetJSON, nil); rr.Header().Get("ETag") != `"1"` {
+		t.Fatalf("Expected a new product to have ETag \"1\", got %d %v", rr.Code, rr.Header())
+	}
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		cases := map[string]int{
+			`"1"`:      http.StatusNotModified,
+			`W/"1"`:    http.StatusNotModified,
+			`"0", "1"`: http.StatusNotModified,
+			"*":        http.StatusNotModified,
+			`"7"`:      http.StatusOK,
+		}
+		for header, want := range cases {
+			rr := doRequest(router, "GET", "/products/1", "", map[string]string{"If-None-Match": header})
+			if rr.Code != want || rr.Header().Get("ETag") != `"1"` {
+				t.Errorf("Expected %d for If-None-Match %s, got %d %v", want, header, rr.Code, rr.Header())
+			}
+			if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("Expected an empty 304 body, got %q", rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("If-Match on PATCH", func(t *testing.T) {
+		patch := `{"name": "Renamed Widget"}`
+		if rr := doRequest(router, "PATCH", "/products/1", patch, nil); rr.Code != http.StatusPreconditionRequired {
+			t.Errorf("Expected 428 without If-Match, got %d", rr.Code)
+		}
+		for _, stale := range []string{`"0"`, `"2"`, `W/"1"`} {
+			if rr := doRequest(router, "PATCH", "/products/1", patch, map[string]string{"If-Match": stale}); rr.Code != http.StatusPreconditionFailed {
+				t.Errorf("Expected 412 for If-Match %s, got %d", stale, rr.Code)
+			}
+		}
+		rr := doRequest(router, "PATCH", "/products/1", patch, map[string]string{"If-Match": `"1"`})
+		if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"2"` {
+			t.Errorf("Expected 200 with ETag \"2\", got %d %v", rr.Code, rr.Header())
+		}
+		// The ETag the first writer used is stale now
+		if rr := doRequest(router, "PATCH", "/products/1", `{"name": "Lost Update"}`, map[string]string{"If-Match": `"1"`}); rr.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected 412 for a lost update, got %d", rr.Code)
+		}
+	})
+
+	t.Run("If-Match on POST details", func(t *testing.T) {
+		details := strings.Replace(widgetJSON, "{", `{"product_id": 1, `, 1)
+		if rr := doRequest(router, "POST", "/products/1/details", details, nil); rr.Code != http.StatusPreconditionRequired {
+			t.Errorf("Expected 428 without If-Match, got %d", rr.Code)
+		}
+		if rr := doRequest(router, "POST", "/products/1/details", details, map[string]string{"If-Match": `"1"`}); rr.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected 412 for a stale If-Match, got %d", rr.Code)
+		}
+		rr := doRequest(router, "POST", "/products/1/details", details, map[string]string{"If-Match": `"2"`})
+		if rr.Code != http.StatusNoContent || rr.Header().Get("ETag") != `"3"` {
+			t.Errorf("Expected 204 with ETag \"3\", got %d %v", rr.Code, rr.Header())
+		}
+
+		// Creating a product through details needs no ETag
+		created := strings.Replace(widgetJSON, "{", `{"product_id": 2, `, 1)
+		if rr := doRequest(router, "POST", "/products/2/details", created, nil); rr.Code != http.StatusNoContent || rr.Header().Get("ETag") != `"1"` {
+			t.Errorf("Expected 204 with ETag \"1\" for a new product, got %d %v %s", rr.Code, rr.Header(), rr.Body.String())
+		}
+	})
+
+	t.Run("REQUIRE_IF_MATCH=false", func(t *testing.T) {
+		t.Setenv("REQUIRE_IF_MATCH", "false")
+		legacy := newRouter(newTestDeps())
+		doRequest(legacy, "POST", "/products", widgetJSON, nil)
+		if rr := doRequest(legacy, "PATCH", "/products/1", `{"name": "Renamed Widget"}`, nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200 without If-Match, got %d", rr.Code)
+		}
+		details := strings.Replace(widgetJSON, "{", `{"product_id": 1, `, 1)
+		if rr := doRequest(legacy, "POST", "/products/1/details", details, nil); rr.Code != http.StatusNoContent || rr.Header().Get("ETag") != `"3"` {
+			t.Errorf("Expected an overwrite without If-Match to return ETag \"3\", got %d %v", rr.Code, rr.Header())
+		}
+	})
+}
+
+// importProducts posts a bulk import and decodes its report
+func importProducts(t *testing.T, router http.Handler, query, body string) models.ImportReport {
+	t.Helper()
+	rr := doRequest(router, "POST", "/products/import"+query, body, nil)
+	var report models.ImportReport
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &report) != nil {
+		t.Fatalf("Import failed: %d %s", rr.Code, rr.Body.String())
+	}
+	return report
+}
+
+func TestProductImport(t *testing.T) {
+	router := newRouter(newTestDeps())
+	widget := strings.ReplaceAll(widgetJSON, "\n\t", " ") // one JSONL line
+	lines := []string{
+		strings.Replace(widget, "{", `{"product_id": 1, `, 1),
+		strings.Replace(widget, "{", `{"product_id": 2, `, 1),
+		`{"product_id": 3, "sku": ""}`,
+		`{not json`,
+	}
+	body := strings.Join(lines, "\n")
+
+	t.Run("Dry run writes nothing", func(t *testing.T) {
+		report := importProducts(t, router, "?dry_run=true", body)
+		if !report.DryRun || report.Total != 4 || report.Created != 2 || report.Failed != 2 || len(report.Rows) != 4 {
+			t.Errorf("Expected 2 rows to create and 2 to fail, got %+v", report)
+		}
+		if rr := doRequest(router, "GET", "/products/1", "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected a dry run not to create products, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Import reports each row", func(t *testing.T) {
+		report := importProducts(t, router, "", body)
+		if report.DryRun || report.Created != 2 || report.Failed != 2 || len(report.Rows) != 2 {
+			t.Errorf("Expected 2 rows created and the 2 failures listed, got %+v", report)
+		}
+		for _, row := range report.Rows {
+			if row.Action != models.ImportFailed || row.Error == "" {
+				t.Errorf("Expected only failed rows with their error, got %+v", row)
+			}
+		}
+		if rr := doRequest(router, "GET", "/products/2", "", nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected product 2 to be imported, got %d", rr.Code)
+		}
+
+		// Importing again changes nothing; a changed row replaces the product
+		if report := importProducts(t, router, "", body); report.Unchanged != 2 {
+			t.Errorf("Expected 2 unchanged rows, got %+v", report)
+		}
+		renamed := strings.Replace(lines[0], `"Widget"`, `"Renamed Widget"`, 1)
+		if report := importProducts(t, router, "?dry_run=true", renamed); report.Updated != 1 || report.Rows[0].ProductID != 1 {
+			t.Errorf("Expected product 1 to be reported as updated, got %+v", report)
+		}
+	})
+
+	t.Run("Repeated IDs compare against the earlier row", func(t *testing.T) {
+		// Enough rows in between that the first one is written in an earlier batch
+		row := func(id int, name string) string {
+			return strings.Replace(strings.Replace(widget, "{", fmt.Sprintf(`{"product_id": %d, `, id), 1), `"Widget"`, strconv.Quote(name), 1)
+		}
+		repeated := []string{row(5, "Widget")}
+		for id := 10; id < 610; id++ {
+			repeated = append(repeated, row(id, "Widget"))
+		}
+		repeated = append(repeated, row(5, "Widget"), row(5, "Renamed Widget"))
+		file := strings.Join(repeated, "\n")
+
+		for _, query := range []string{"?dry_run=true", ""} {
+			report := importProducts(t, router, query, file)
+			if report.Created != 601 || report.Unchanged != 1 || report.Updated != 1 {
+				t.Errorf("Expected 601 created, 1 unchanged and 1 updated for %q, got %d/%d/%d",
+					query, report.Created, report.Unchanged, report.Updated)
+			}
+		}
+		if rr := doRequest(router, "GET", "/products/5", "", nil); !strings.Contains(rr.Body.String(), `"name":"Renamed Widget"`) {
+			t.Errorf("Expected the last row to win, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		if rr := doRequest(router, "POST", "/products/import?format=xml", body, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", rr.Code)
+		}
+	})
+}
+
+func TestProductExport(t *testing.T) {
+	const size = 2500
+	deps := newTestDeps()
+	deps.products = seededCatalog(t, size)
+	router := newRouter(deps)
+
+	t.Run("JSONL is streamed", func(t *testing.T) {
+		rr := doRequest(router, "GET", "/products/export", "", nil)
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/x-ndjson" {
+			t.Fatalf("Expected a JSONL export, got %d %v", rr.Code, rr.Header())
+		}
+		// More products than one flush holds, so the client gets them in pieces
+		if !rr.Flushed {
+			t.Errorf("Expected the export to be flushed while it was written")
+		}
+		seen := make(map[int32]bool)
+		for _, line := range strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n") {
+			var product models.Product
+			if err := json.Unmarshal([]byte(line), &product); err != nil {
+				t.Fatalf("Expected one product per line, got %q: %v", line, err)
+			}
+			seen[product.ProductID] = true
+		}
+		if len(seen) != size {
+			t.Errorf("Expected all %d products, got %d", size, len(seen))
+		}
+	})
+
+	t.Run("CSV round-trips through import", func(t *testing.T) {
+		rr := doRequest(router, "GET", "/products/export", "", map[string]string{"Accept": "text/csv"})
+		header := strings.SplitN(rr.Body.String(), "\n", 2)[0]
+		if rr.Code != http.StatusOK || header != strings.Join(models.ProductCSVColumns, ",") {
+			t.Fatalf("Expected a CSV export with the product columns, got %d %q", rr.Code, header)
+		}
+
+		copyRouter := newRouter(newTestDeps())
+		report := importProducts(t, copyRouter, "?format=csv", rr.Body.String())
+		if report.Created != size || report.Failed != 0 {
+			t.Errorf("Expected all %d products to import, got %+v", size, report)
+		}
+	})
+}
+
+// idList formats product IDs first..last as a JSON list
+func idList(first, last int) string {
+	ids := make([]string, 0, last-first+1)
+	for id := first; id <= last; id++ {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return "[" + strings.Join(ids, ", ") + "]"
+}
+
+func TestBatchGetProducts(t *testing.T) {
+	deps := newTestDeps()
+	deps.products = seededCatalog(t, 300)
+	router := newRouter(deps)
+
+	t.Run("Found and missing IDs", func(t *testing.T) {
+		for _, rr := range []*httptest.ResponseRecorder{
+			doRequest(router, "POST", "/products/batch-get", `{"ids": [3, 1, 999, 3]}`, nil),
+			doRequest(router, "GET", "/products?ids=3,1,999,3", "", nil),
+		} {
+			var response models.BatchGetResponse
+			json.Unmarshal(rr.Body.Bytes(), &response)
+			if rr.Code != http.StatusOK || len(response.Products) != 2 ||
+				response.Products[0].ProductID != 3 || response.Products[1].ProductID != 1 ||
+				len(response.Missing) != 1 || response.Missing[0] != 999 {
+				t.Errorf("Expected products 3 and 1 in order and 999 missing, got %d %s", rr.Code, rr.Body.String())
+			}
+		}
+
+		rr := doRequest(router, "POST", "/products/batch-get", `{"ids": [1]}`, nil)
+		if !strings.Contains(rr.Body.String(), `"missing":[]`) {
+			t.Errorf("Expected an empty missing list, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("At most 200 distinct IDs", func(t *testing.T) {
+		if rr := doRequest(router, "POST", "/products/batch-get", `{"ids": `+idList(1, 200)+`}`, nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected 200 IDs to be allowed, got %d", rr.Code)
+		}
+		repeated := strings.Replace(idList(1, 200), "]", ", 1, 2, 3]", 1)
+		if rr := doRequest(router, "POST", "/products/batch-get", `{"ids": `+repeated+`}`, nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected repeated IDs not to count against the cap, got %d", rr.Code)
+		}
+		rr := doRequest(router, "POST", "/products/batch-get", `{"ids": `+idList(1, 201)+`}`, nil)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "at most 200") {
+			t.Errorf("Expected 400 for 201 IDs, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		for _, body := range []string{`{"ids": []}`, `{"ids": [0]}`, `{"ids": [1], "all": true}`, `{"ids": "1"}`} {
+			if rr := doRequest(router, "POST", "/products/batch-get", body, nil); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+			}
+		}
+		if rr := doRequest(router, "GET", "/products?ids=1,x", "", nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a malformed ID, got %d", rr.Code)
+		}
+	})
+}
+
+// listAll follows a listing's next_after cursors to the end and returns every
+// product it lists
+func listAll(t *testing.T, router http.Handler, query string) []models.Product {
+	t.Helper()
+	var products []models.Product
+	after := int32(0)
+	for page := 0; page < 1000; page++ {
+		rr := doRequest(router, "GET", fmt.Sprintf("/products?%s&after=%d", query, after), "", nil)
+		var response models.ProductListResponse
+		if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &response) != nil {
+			t.Fatalf("Listing failed: %d %s", rr.Code, rr.Body.String())
+		}
+		products = append(products, response.Products...)
+		if response.NextAfter == 0 {
+			return products
+		}
+		after = response.NextAfter
+	}
+	t.Fatalf("Listing %q did not end", query)
+	return nil
+}
+
+func TestListProducts(t *testing.T) {
+	deps := newTestDeps()
+	deps.products = seededCatalog(t, 300)
+	router := newRouter(deps)
+
+	all := listAll(t, router, "limit=100")
+	if len(all) != 300 {
+		t.Fatalf("Expected the cursor to walk all 300 products, got %d", len(all))
+	}
+	for i, product := range all {
+		if product.ProductID != int32(i+1) {
+			t.Fatalf("Expected products in ID order without gaps, got %d at %d", product.ProductID, i)
+		}
+	}
+
+	t.Run("Filtered pages", func(t *testing.T) {
+		var want []int32
+		for _, product := range all {
+			if (product.Category == "Books" || product.Category == "Toys") && product.Weight >= 200 {
+				want = append(want, product.ProductID)
+			}
+		}
+		// Small pages, lower-case names and a comma-separated list
+		listed := listAll(t, router, "limit=7&category=books,TOYS&min_weight=200")
+		var got []int32
+		for _, product := range listed {
+			got = append(got, product.ProductID)
+		}
+		if len(want) == 0 || !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Cursor survives deletes", func(t *testing.T) {
+		rr := doRequest(router, "GET", "/products?limit=10", "", nil)
+		var page models.ProductListResponse
+		json.Unmarshal(rr.Body.Bytes(), &page)
+		if page.NextAfter != 10 {
+			t.Fatalf("Expected next_after 10, got %s", rr.Body.String())
+		}
+
+		// Products before and at the cursor go away; the next page is unaffected
+		doRequest(router, "DELETE", "/products/5", "", nil)
+		doRequest(router, "DELETE", "/products/11", "", nil)
+		rr = doRequest(router, "GET", "/products?limit=10&after=10", "", nil)
+		json.Unmarshal(rr.Body.Bytes(), &page)
+		if len(page.Products) != 10 || page.Products[0].ProductID != 12 || page.NextAfter != 21 {
+			t.Errorf("Expected products 12 to 21, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=101", "after=-1", "after=x", "min_weight=x"} {
+			if rr := doRequest(router, "GET", "/products?"+query, "", nil); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", query, rr.Code)
+			}
+		}
+	})
+}
+
+func TestCategoryEndpoints(t *testing.T) {
+	router := newRouter(newTestDeps())
+
+	var list models.CategoryListResponse
+	json.Unmarshal(doRequest(router, "GET", "/categories", "", nil).Body.Bytes(), &list)
+	var toys models.Category
+	for _, category := range list.Categories {
+		if category.Name == "Toys" {
+			toys = category
+		}
+	}
+	if len(list.Categories) != 8 || toys.ID == 0 {
+		t.Fatalf("Expected the 8 default categories, got %+v", list.Categories)
+	}
+
+	// A subcategory gets a server-assigned ID and a derived slug
+	rr := doRequest(router, "POST", "/categories", fmt.Sprintf(`{"name": "Board Games", "parent_id": %d}`, toys.ID), nil)
+	var games models.Category
+	json.Unmarshal(rr.Body.Bytes(), &games)
+	if rr.Code != http.StatusCreated || games.Slug != "board-games" || rr.Header().Get("Location") != fmt.Sprintf("/categories/%d", games.ID) {
+		t.Fatalf("Expected 201 with slug board-games, got %d %v %s", rr.Code, rr.Header(), rr.Body.String())
+	}
+
+	t.Run("Create conflicts and invalid bodies", func(t *testing.T) {
+		cases := map[string]int{
+			`{"name": "board games"}`:               http.StatusConflict,
+			`{"id": 99, "name": "Puzzles"}`:         http.StatusBadRequest,
+			`{"name": "Puzzles", "parent_id": 999}`: http.StatusBadRequest,
+			`{"name": ""}`:                          http.StatusBadRequest,
+			`{"name": "Puzzles", "icon": "x"}`:      http.StatusBadRequest,
+		}
+		for body, want := range cases {
+			if rr := doRequest(router, "POST", "/categories", body, nil); rr.Code != want {
+				t.Errorf("Expected %d for %s, got %d %s", want, body, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("Tree nests subcategories", func(t *testing.T) {
+		var tree models.CategoryTreeResponse
+		json.Unmarshal(doRequest(router, "GET", "/categories/tree", "", nil).Body.Bytes(), &tree)
+		for _, node := range tree.Categories {
+			if node.ID == toys.ID && (len(node.Children) != 1 || node.Children[0].ID != games.ID) {
+				t.Errorf("Expected Board Games under Toys, got %+v", node)
+			}
+			if node.ID == games.ID {
+				t.Errorf("Expected Board Games not to be a top-level category")
+			}
+		}
+	})
+
+	// Products must name a category by its own ID
+	product := func(category string, categoryID int32) string {
+		return fmt.Sprintf(`{"sku": "SKU-1", "manufacturer": "Acme", "category_id": %d, "weight": 100,
+			"some_other_id": 1, "name": "Chess", "category": %q, "brand": "Acme"}`, categoryID, category)
+	}
+	if rr := doRequest(router, "POST", "/products", product("Board Games", toys.ID), nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a mismatched category_id, got %d", rr.Code)
+	}
+	if rr := doRequest(router, "POST", "/products", product("Card Games", games.ID), nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown category, got %d", rr.Code)
+	}
+	if rr := doRequest(router, "POST", "/products", product("Board Games", games.ID), nil); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("Category products include subcategories", func(t *testing.T) {
+		path := fmt.Sprintf("/categories/%d/products", toys.ID)
+		var listed models.ProductListResponse
+		json.Unmarshal(doRequest(router, "GET", path, "", nil).Body.Bytes(), &listed)
+		if len(listed.Products) != 1 || listed.Products[0].Name != "Chess" {
+			t.Errorf("Expected the Board Games product under Toys, got %+v", listed.Products)
+		}
+		json.Unmarshal(doRequest(router, "GET", path+"?descendants=false", "", nil).Body.Bytes(), &listed)
+		if len(listed.Products) != 0 {
+			t.Errorf("Expected no products in Toys itself, got %+v", listed.Products)
+		}
+		if rr := doRequest(router, "GET", path+"?category=Toys", "", nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a category filter, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Rename moves products", func(t *testing.T) {
+		path := fmt.Sprintf("/categories/%d", games.ID)
+		rr := doRequest(router, "PUT", path, fmt.Sprintf(`{"name": "Tabletop Games", "parent_id": %d}`, toys.ID), nil)
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"slug":"tabletop-games"`) {
+			t.Fatalf("Expected the rename to succeed, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "GET", "/products/1", "", nil); !strings.Contains(rr.Body.String(), `"category":"Tabletop Games"`) {
+			t.Errorf("Expected the product to follow the rename, got %s", rr.Body.String())
+		}
+		if rr := doRequest(router, "PUT", path, `{"id": 1, "name": "Other"}`, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an ID mismatch, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Delete only unused categories", func(t *testing.T) {
+		gamesPath := fmt.Sprintf("/categories/%d", games.ID)
+		if rr := doRequest(router, "DELETE", fmt.Sprintf("/categories/%d", toys.ID), "", nil); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a category with subcategories, got %d", rr.Code)
+		}
+		if rr := doRequest(router, "DELETE", gamesPath, "", nil); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a category with products, got %d", rr.Code)
+		}
+
+		doRequest(router, "DELETE", "/products/1", "", nil)
+		if rr := doRequest(router, "DELETE", gamesPath, "", nil); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204 once the category is unused, got %d %s", rr.Code, rr.Body.String())
+		}
+		for _, method := range []string{"GET", "DELETE"} {
+			if rr := doRequest(router, method, gamesPath, "", nil); rr.Code != http.StatusNotFound {
+				t.Errorf("Expected 404 for %s of a deleted category, got %d", method, rr.Code)
+			}
+		}
+		if rr := doRequest(router, "GET", "/categories/0", "", nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an invalid ID, got %d", rr.Code)
+		}
+	})
+}
+
+// failingCatalog is a product store whose compare-and-swap fails for one product
+type failingCatalog struct {
+	store.ProductRepository
+	failID int32
+}
+
+func (c failingCatalog) CompareAndSwap(product *models.Product, expectedVersion int64) (*models.Product, error) {
+	if product.ProductID == c.failID {
+		return nil, errors.New("disk full")
+	}
+	return c.ProductRepository.CompareAndSwap(product, expectedVersion)
+}
+
+func TestCategoryRenameRollsBack(t *testing.T) {
+	deps := newTestDeps()
+	toys, _ := deps.categories.CategoryByName("Toys")
+	for id := int32(1); id <= 3; id++ {
+		deps.products.Upsert(&models.Product{ProductID: id, SKU: fmt.Sprintf("SKU-%d", id), Manufacturer: "Acme",
+			CategoryID: toys.ID, Weight: 100, SomeOtherID: 1, Name: "Yo-yo", Category: "Toys", Brand: "Acme"})
+	}
+	deps.products = failingCatalog{ProductRepository: deps.products, failID: 2}
+	router := newRouter(deps)
+
+	// Product 1 is moved before product 2 fails
+	path := fmt.Sprintf("/categories/%d", toys.ID)
+	if rr := doRequest(router, "PUT", path, `{"name": "Playthings"}`, nil); rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := doRequest(router, "GET", path, "", nil); !strings.Contains(rr.Body.String(), `"name":"Toys"`) {
+		t.Errorf("Expected the rename to be rolled back, got %s", rr.Body.String())
+	}
+	for id := int32(1); id <= 3; id++ {
+		if product, _ := deps.products.Get(id); product.Category != "Toys" {
+			t.Errorf("Expected product %d back in Toys, got %q", id, product.Category)
+		}
+	}
+}
+
+func TestBrandEndpoints(t *testing.T) {
+	router := newRouter(newTestDeps())
+
+	rr := doRequest(router, "POST", "/brands", `{"name": "Acme", "aliases": ["ACME Corp"]}`, nil)
+	var acme models.Brand
+	json.Unmarshal(rr.Body.Bytes(), &acme)
+	if rr.Code != http.StatusCreated || rr.Header().Get("Location") != fmt.Sprintf("/brands/%d", acme.ID) {
+		t.Fatalf("Expected 201, got %d %v %s", rr.Code, rr.Header(), rr.Body.String())
+	}
+	cases := map[string]int{
+		`{"name": " acme "}`:                            http.StatusConflict,
+		`{"name": "Initech", "aliases": ["acme corp"]}`: http.StatusConflict,
+		`{"name": "   "}`:                               http.StatusBadRequest,
+		`{"id": 7, "name": "Initech"}`:                  http.StatusBadRequest,
+	}
+	for body, want := range cases {
+		if rr := doRequest(router, "POST", "/brands", body, nil); rr.Code != want {
+			t.Errorf("Expected %d for %s, got %d %s", want, body, rr.Code, rr.Body.String())
+		}
+	}
+
+	// Products are stored under the canonical names; new names are registered
+	product := func(brand string) string {
+		return fmt.Sprintf(`{"sku": "SKU-1", "manufacturer": "  globex  inc ", "category_id": 1, "weight": 100,
+			"some_other_id": 1, "name": "Widget", "category": "Electronics", "brand": %q}`, brand)
+	}
+	doRequest(router, "POST", "/products", product("acme   corp"), nil)
+	doRequest(router, "POST", "/products", product("Globex"), nil)
+	if rr := doRequest(router, "GET", "/products/1", "", nil); !strings.Contains(rr.Body.String(), `"brand":"Acme"`) ||
+		!strings.Contains(rr.Body.String(), `"manufacturer":"globex inc"`) {
+		t.Errorf("Expected canonical brand and cleaned manufacturer, got %s", rr.Body.String())
+	}
+	var brands models.BrandListResponse
+	json.Unmarshal(doRequest(router, "GET", "/brands", "", nil).Body.Bytes(), &brands)
+	if len(brands.Brands) != 2 || brands.Brands[1].Name != "Globex" {
+		t.Fatalf("Expected Globex to be registered, got %+v", brands.Brands)
+	}
+	globex := brands.Brands[1]
+	if rr := doRequest(router, "GET", "/manufacturers", "", nil); !strings.Contains(rr.Body.String(), `"name":"globex inc"`) {
+		t.Errorf("Expected the manufacturer to be registered, got %s", rr.Body.String())
+	}
+
+	t.Run("Filters match aliases", func(t *testing.T) {
+		var listed models.ProductListResponse
+		json.Unmarshal(doRequest(router, "GET", "/products?brand=ACME%20CORP", "", nil).Body.Bytes(), &listed)
+		if len(listed.Products) != 1 || listed.Products[0].ProductID != 1 {
+			t.Errorf("Expected the Acme product, got %+v", listed.Products)
+		}
+	})
+
+	t.Run("Names are registered only once written", func(t *testing.T) {
+		// One JSONL line for the product with the given ID
+		line := func(id int, brand string) string {
+			return strings.Join(strings.Fields(strings.Replace(product(brand), "{", fmt.Sprintf(`{"product_id": %d, `, id), 1)), " ")
+		}
+		if rr := doRequest(router, "POST", "/products/1/details", line(1, "Initech"), map[string]string{"If-Match": `"999"`}); rr.Code != http.StatusPreconditionFailed {
+			t.Fatalf("Expected 412 for a stale If-Match, got %d %s", rr.Code, rr.Body.String())
+		}
+		importProducts(t, router, "?dry_run=true", line(3, "Hooli"))
+		if rr := doRequest(router, "GET", "/brands", "", nil); strings.Contains(rr.Body.String(), "Initech") || strings.Contains(rr.Body.String(), "Hooli") {
+			t.Errorf("Expected rejected and dry-run writes not to register brands, got %s", rr.Body.String())
+		}
+
+		// Rows of one import agree on the first spelling of a new name
+		importProducts(t, router, "", line(3, "Umbrella")+"\n"+line(4, "UMBRELLA"))
+		if rr := doRequest(router, "GET", "/products/4", "", nil); !strings.Contains(rr.Body.String(), `"brand":"Umbrella"`) {
+			t.Errorf("Expected the first spelling, got %s", rr.Body.String())
+		}
+		if rr := doRequest(router, "GET", "/brands", "", nil); strings.Count(rr.Body.String(), "mbrella") != 1 {
+			t.Errorf("Expected Umbrella to be registered once, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Aliases", func(t *testing.T) {
+		aliases := fmt.Sprintf("/brands/%d/aliases", acme.ID)
+		if rr := doRequest(router, "POST", aliases, `{"alias": "Acme Inc"}`, nil); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Acme Inc") {
+			t.Errorf("Expected the alias to be added, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "POST", aliases, `{"alias": "globex"}`, nil); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for another brand's name, got %d", rr.Code)
+		}
+		if rr := doRequest(router, "DELETE", aliases+"/Acme%20Inc", "", nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected the alias to be removed, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "DELETE", aliases+"/Acme%20Inc", "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing alias, got %d", rr.Code)
+		}
+		if rr := doRequest(router, "POST", "/brands/999/aliases", `{"alias": "Other"}`, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing brand, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Merge moves products", func(t *testing.T) {
+		merge := fmt.Sprintf("/brands/%d/merge", acme.ID)
+		rr := doRequest(router, "POST", merge, fmt.Sprintf(`{"from": [%d]}`, globex.ID), nil)
+		var merged models.BrandMergeResponse
+		json.Unmarshal(rr.Body.Bytes(), &merged)
+		if rr.Code != http.StatusOK || merged.ProductsUpdated != 1 {
+			t.Fatalf("Expected one product moved, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "GET", "/products/2", "", nil); !strings.Contains(rr.Body.String(), `"brand":"Acme"`) {
+			t.Errorf("Expected the Globex product to be renamed, got %s", rr.Body.String())
+		}
+		if rr := doRequest(router, "GET", fmt.Sprintf("/brands/%d", globex.ID), "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected the merged brand to be gone, got %d", rr.Code)
+		}
+		for _, body := range []string{`{"from": []}`, fmt.Sprintf(`{"from": [%d]}`, acme.ID)} {
+			if rr := doRequest(router, "POST", merge, body, nil); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+			}
+		}
+	})
+}
+
+// heldGateway blocks every charge until it is released, so a test can act
+// while an order's payment is in flight
+type heldGateway struct {
+	countingGateway
+	charging chan struct{} // receives when a charge starts
+	release  chan struct{} // closed to let charges finish
+}
+
+func (g *heldGateway) Charge(orderID string, amount float64) error {
+	g.charging <- struct{}{}
+	<-g.release
+	return g.countingGateway.Charge(orderID, amount)
+}
+
+// getInventory fetches a product's stock
+func getInventory(t *testing.T, router http.Handler, productID int) models.Inventory {
+	t.Helper()
+	rr := doRequest(router, "GET", fmt.Sprintf("/products/%d/inventory", productID), "", nil)
+	var inventory models.Inventory
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &inventory) != nil {
+		t.Fatalf("Inventory lookup failed: %d %s", rr.Code, rr.Body.String())
+	}
+	return inventory
+}
+
+func TestInventoryEndpoints(t *testing.T) {
+	payments := &heldGateway{charging: make(chan struct{}), release: make(chan struct{})}
+	router := newRouter(newOrderTestDeps(payments))
+
+	if inventory := getInventory(t, router, 2); inventory.Tracked {
+		t.Errorf("Expected product 2 not to be tracked, got %+v", inventory)
+	}
+	if inventory := getInventory(t, router, 1); !inventory.Tracked || inventory.OnHand != 10 || inventory.Available != 10 {
+		t.Errorf("Expected 10 units of product 1, got %+v", inventory)
+	}
+
+	t.Run("Invalid stock updates", func(t *testing.T) {
+		cases := []struct {
+			path, body string
+			want       int
+		}{
+			{"/products/1/inventory", `{"on_hand": -1}`, http.StatusBadRequest},
+			{"/products/1/inventory", `{"on_hand": 5, "warehouse": "main"}`, http.StatusBadRequest},
+			{"/products/1/inventory", `{"on_hand": 5, "warehouse_id": "nowhere"}`, http.StatusNotFound},
+			{"/products/99/inventory", `{"on_hand": 5}`, http.StatusNotFound},
+		}
+		for _, tc := range cases {
+			if rr := doRequest(router, "PUT", tc.path, tc.body, nil); rr.Code != tc.want {
+				t.Errorf("Expected %d for PUT %s %s, got %d", tc.want, tc.path, tc.body, rr.Code)
+			}
+		}
+		if rr := doRequest(router, "GET", "/products/99/inventory", "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown product, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Orders hold stock until paid", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- doRequest(router, "POST", "/orders/sync", `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 4}]}`, nil)
+		}()
+		<-payments.charging
+
+		if inventory := getInventory(t, router, 1); inventory.Reserved != 4 || inventory.Available != 6 {
+			t.Errorf("Expected 4 units reserved while the payment runs, got %+v", inventory)
+		}
+		if rr := doRequest(router, "PUT", "/products/1/inventory", `{"on_hand": 3}`, nil); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for stock below the reserved units, got %d", rr.Code)
+		}
+		close(payments.release)
+
+		if rr := <-done; rr.Code != http.StatusOK {
+			t.Fatalf("Expected the order to succeed, got %d %s", rr.Code, rr.Body.String())
+		}
+		if inventory := getInventory(t, router, 1); inventory.OnHand != 6 || inventory.Reserved != 0 {
+			t.Errorf("Expected 4 units sold, got %+v", inventory)
+		}
+	})
+
+	t.Run("Orders fail when stock runs short", func(t *testing.T) {
+		rr := doRequest(router, "POST", "/orders/sync", `{"customer_id": 7, "items": [{"product_id": 2, "quantity": 50}, {"product_id": 1, "quantity": 7}]}`, nil)
+		if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "OUT_OF_STOCK") {
+			t.Errorf("Expected 409 OUT_OF_STOCK, got %d %s", rr.Code, rr.Body.String())
+		}
+		if inventory := getInventory(t, router, 1); inventory.Available != 6 {
+			t.Errorf("Expected nothing to be held for a rejected order, got %+v", inventory)
+		}
+		go func() { <-payments.charging }()
+		if rr := doRequest(router, "POST", "/orders/sync", `{"customer_id": 7, "items": [{"product_id": 2, "quantity": 50}]}`, nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected untracked products never to run out, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Warehouses and transfers", func(t *testing.T) {
+		west := `{"id": "west", "name": "West", "location": {"latitude": 47.6, "longitude": -122.3}}`
+		if rr := doRequest(router, "POST", "/warehouses", west, nil); rr.Code != http.StatusCreated || rr.Header().Get("Location") != "/warehouses/west" {
+			t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "POST", "/warehouses", west, nil); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a duplicate warehouse, got %d", rr.Code)
+		}
+
+		transfer := `{"product_id": 1, "from": "main", "to": "west", "quantity": %d}`
+		if rr := doRequest(router, "POST", "/inventory/transfers", fmt.Sprintf(transfer, 2), nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected the transfer to succeed, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "POST", "/inventory/transfers", fmt.Sprintf(transfer, 100), nil); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a short source, got %d", rr.Code)
+		}
+		rr := doRequest(router, "GET", "/warehouses/west/inventory", "", nil)
+		if !strings.Contains(rr.Body.String(), `"product_id":1`) || !strings.Contains(rr.Body.String(), `"on_hand":2`) {
+			t.Errorf("Expected 2 units of product 1 in west, got %s", rr.Body.String())
+		}
+		if inventory := getInventory(t, router, 1); inventory.OnHand != 6 || len(inventory.Warehouses) != 2 {
+			t.Errorf("Expected 6 units over two warehouses, got %+v", inventory)
+		}
+		if rr := doRequest(router, "GET", "/warehouses/nowhere", "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown warehouse, got %d", rr.Code)
+		}
+	})
+}
+
+// getPrices fetches a product's price timeline
+func getPrices(t *testing.T, router http.Handler, productID int) models.PriceTimelineResponse {
+	t.Helper()
+	rr := doRequest(router, "GET", fmt.Sprintf("/products/%d/prices", productID), "", nil)
+	var timeline models.PriceTimelineResponse
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &timeline) != nil {
+		t.Fatalf("Price lookup failed: %d %s", rr.Code, rr.Body.String())
+	}
+	return timeline
+}
+
+func TestPriceEndpoints(t *testing.T) {
+	router := newRouter(newTestDeps())
+	doRequest(router, "POST", "/products", widgetJSON, nil)
+	doRequest(router, "PATCH", "/products/1", `{"price": 15}`, map[string]string{"If-Match": `"1"`})
+
+	timeline := getPrices(t, router, 1)
+	if timeline.CurrentPrice != 15 || len(timeline.Prices) != 2 || timeline.Prices[0].Price != 12.5 ||
+		timeline.Prices[0].Status != models.PriceApplied || timeline.Prices[1].Status != models.PriceApplied {
+		t.Fatalf("Expected 12.50 then 15.00 applied, got %+v", timeline)
+	}
+
+	schedule := func(price float64, from time.Time) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"price": %v, "effective_from": %q}`, price, from.Format(time.RFC3339Nano))
+		return doRequest(router, "POST", "/products/1/prices", body, nil)
+	}
+
+	t.Run("Invalid schedules", func(t *testing.T) {
+		for _, rr := range []*httptest.ResponseRecorder{
+			schedule(9.99, time.Now().Add(-time.Minute)),
+			schedule(-1, time.Now().Add(time.Hour)),
+			schedule(9.999, time.Now().Add(time.Hour)),
+			doRequest(router, "POST", "/products/1/prices", `{"price": 9.99}`, nil),
+			doRequest(router, "POST", "/products/1/prices", `{"price": 9.99, "effective_from": "2099-01-01T00:00:00Z", "note": "x"}`, nil),
+		} {
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d %s", rr.Code, rr.Body.String())
+			}
+		}
+		if rr := doRequest(router, "POST", "/products/99/prices", `{"price": 9.99, "effective_from": "2099-01-01T00:00:00Z"}`, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown product, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Cancel a future change", func(t *testing.T) {
+		rr := schedule(9.99, time.Now().Add(time.Hour))
+		var change models.PriceChange
+		json.Unmarshal(rr.Body.Bytes(), &change)
+		path := fmt.Sprintf("/products/1/prices/%d", change.ID)
+		if rr.Code != http.StatusCreated || rr.Header().Get("Location") != path || change.Status != models.PriceScheduled {
+			t.Fatalf("Expected 201 for a scheduled change, got %d %v %s", rr.Code, rr.Header(), rr.Body.String())
+		}
+		if timeline := getPrices(t, router, 1); timeline.CurrentPrice != 15 || len(timeline.Prices) != 3 {
+			t.Errorf("Expected the change listed but not in effect, got %+v", timeline)
+		}
+
+		if rr := doRequest(router, "DELETE", path, "", nil); rr.Code != http.StatusNoContent {
+			t.Errorf("Expected 204, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "DELETE", path, "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 once cancelled, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Due changes are charged before the scheduler runs", func(t *testing.T) {
+		rr := schedule(9.99, time.Now().Add(20*time.Millisecond))
+		var change models.PriceChange
+		json.Unmarshal(rr.Body.Bytes(), &change)
+		time.Sleep(40 * time.Millisecond)
+
+		if rr := doRequest(router, "GET", "/products/1", "", nil); !strings.Contains(rr.Body.String(), `"price":9.99`) {
+			t.Errorf("Expected the due price to be served, got %s", rr.Body.String())
+		}
+		if timeline := getPrices(t, router, 1); timeline.CurrentPrice != 9.99 {
+			t.Errorf("Expected the current price to be 9.99, got %+v", timeline)
+		}
+		rr = doRequest(router, "POST", "/orders/sync", `{"customer_id": 7, "items": [{"product_id": 1, "quantity": 2}]}`, nil)
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"total":19.98`) {
+			t.Errorf("Expected the order to be charged 19.98, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := doRequest(router, "DELETE", fmt.Sprintf("/products/1/prices/%d", change.ID), "", nil); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a change already in effect, got %d", rr.Code)
+		}
+	})
+}
+
+// getOrder fetches a recorded order
+func getOrder(t *testing.T, router http.Handler, orderID string) models.Order {
+	t.Helper()
+	rr := doRequest(router, "GET", "/orders/"+orderID, "", nil)
+	var order models.Order
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &order) != nil {
+		t.Fatalf("Order lookup failed: %d %s", rr.Code, rr.Body.String())
+	}
+	return order
+}
+
+func TestGetOrder(t *testing.T) {
+	t.Setenv("ORDER_TAX_RATE", "0.1")
+	router := newRouter(newOrderTestDeps(&countingGateway{failures: 1}))
+
+	// The first charge is declined, the second goes through
+	rr := doRequest(router, "POST", "/orders/sync",
+		`{"order_id": "declined-1", "customer_id": 7, "items": [{"product_id": 1, "quantity": 1}]}`, nil)
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("Expected the declined payment to fail with 502, got %d %s", rr.Code, rr.Body.String())
+	}
+	rr = doRequest(router, "POST", "/orders/sync",
+		`{"customer_id": 7, "items": [{"product_id": 1, "quantity": 2}, {"product_id": 2, "quantity": 1}]}`, nil)
+	var placed struct {
+		OrderID string `json:"order_id"`
+	}
+	if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &placed) != nil || placed.OrderID == "" {
+		t.Fatalf("Expected the order to be processed, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("Completed order", func(t *testing.T) {
+		order := getOrder(t, router, placed.OrderID)
+		if order.Status != models.StatusCompleted || order.CustomerID != 7 || len(order.Items) != 2 ||
+			order.Items[0].Price != 12.50 || order.Items[1].Price != 4 {
+			t.Errorf("Expected the completed order with catalog prices, got %+v", order)
+		}
+		if order.Subtotal != 29 || order.Tax != 2.9 || order.Total != 31.9 {
+			t.Errorf("Expected 29.00 + 2.90 tax = 31.90, got %v + %v = %v", order.Subtotal, order.Tax, order.Total)
+		}
+		if len(order.Allocations) != 1 || order.Allocations[0].Quantity != 2 {
+			t.Errorf("Expected the tracked item to be allocated, got %+v", order.Allocations)
+		}
+
+		var statuses []string
+		for _, change := range order.History {
+			statuses = append(statuses, change.To)
+		}
+		want := []string{models.StatusPending, models.StatusProcessing, models.StatusCompleted}
+		if !reflect.DeepEqual(statuses, want) || order.History[0].From != "" {
+			t.Errorf("Expected history %v, got %+v", want, order.History)
+		}
+	})
+
+	t.Run("Failed order", func(t *testing.T) {
+		order := getOrder(t, router, "declined-1")
+		last := order.History[len(order.History)-1]
+		if order.Status != models.StatusFailed || last.To != models.StatusFailed || last.Reason == "" {
+			t.Errorf("Expected the declined order to be failed with a reason, got %+v", order)
+		}
+	})
+
+	t.Run("Unknown order", func(t *testing.T) {
+		if rr := doRequest(router, "GET", "/orders/no-such-order", "", nil); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+}
+
+func TestCancelAndRefundOrders(t *testing.T) {
+	payments := &countingGateway{}
+	deps := newOrderTestDeps(payments)
+	router := newRouter(deps)
+
+	for _, orderID := range []string{"completed-1", "completed-2"} {
+		rr := doRequest(router, "POST", "/orders/sync",
+			fmt.Sprintf(`{"order_id": %q, "customer_id": 7, "items": [{"product_id": 1, "quantity": 1}]}`, orderID), nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected the order to be processed, got %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	// An order as /orders/async leaves it once queued: pending, with its
+	// stock reserved until the order processor has charged it
+	queued := &models.Order{OrderID: "queued-1", CustomerID: 7,
+		Items: []models.Item{{ProductID: 1, Quantity: 2, Price: 12.50}}}
+	allocations, err := deps.inventory.Reserve(queued.OrderID, []store.ReservationLine{{ProductID: 1, Quantity: 2}}, store.ReserveOptions{})
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	queued.Allocations = allocations
+	queued.Begin(time.Now())
+	queued.ComputeTotals(0)
+	if err := deps.orders.Create(queued); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	t.Run("Cancel", func(t *testing.T) {
+		before := getInventory(t, router, 1)
+		if rr := doRequest(router, "POST", "/orders/queued-1/cancel", "", nil); rr.Code != http.StatusOK {
+			t.Fatalf("Expected a pending order to be cancelled, got %d %s", rr.Code, rr.Body.String())
+		}
+		if order := getOrder(t, router, "queued-1"); order.Status != models.StatusCancelled {
+			t.Errorf("Expected the order to be cancelled, got %s", order.Status)
+		}
+		if after := getInventory(t, router, 1); after.OnHand != before.OnHand || after.Available != before.Available+2 {
+			t.Errorf("Expected the cancelled items to be released, %+v -> %+v", before, after)
+		}
+
+		for path, want := range map[string]int{
+			"/orders/queued-1/cancel":    http.StatusConflict, // already cancelled
+			"/orders/completed-1/cancel": http.StatusConflict,
+			"/orders/no-such/cancel":     http.StatusNotFound,
+		} {
+			if rr := doRequest(router, "POST", path, "", nil); rr.Code != want {
+				t.Errorf("POST %s: expected %d, got %d %s", path, want, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("Refund", func(t *testing.T) {
+		rr := doRequest(router, "POST", "/orders/completed-1/refund", "", nil)
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"refunded"`) {
+			t.Fatalf("Expected a completed order to be refunded, got %d %s", rr.Code, rr.Body.String())
+		}
+		if !reflect.DeepEqual(payments.refunded, []string{"completed-1"}) {
+			t.Errorf("Expected one refund through the gateway, got %v", payments.refunded)
+		}
+
+		for path, want := range map[string]int{
+			"/orders/completed-1/refund": http.StatusOK, // already refunded
+			"/orders/queued-1/refund":    http.StatusConflict,
+			"/orders/no-such/refund":     http.StatusNotFound,
+		} {
+			if rr := doRequest(router, "POST", path, "", nil); rr.Code != want {
+				t.Errorf("POST %s: expected %d, got %d %s", path, want, rr.Code, rr.Body.String())
+			}
+		}
+		if len(payments.refunded) != 1 {
+			t.Errorf("Expected repeated and refused refunds to skip the gateway, got %v", payments.refunded)
+		}
+	})
+
+	t.Run("Rejected refund is retried", func(t *testing.T) {
+		payments.refundFailures = 1
+		if rr := doRequest(router, "POST", "/orders/completed-2/refund", "", nil); rr.Code != http.StatusBadGateway {
+			t.Fatalf("Expected 502 for a rejected refund, got %d %s", rr.Code, rr.Body.String())
+		}
+		if order := getOrder(t, router, "completed-2"); order.Status != models.StatusRefunding {
+			t.Errorf("Expected the order to stay refunding, got %s", order.Status)
+		}
+		if rr := doRequest(router, "POST", "/orders/completed-2/refund", "", nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected the retry to finish the refund, got %d %s", rr.Code, rr.Body.String())
+		}
+		if order := getOrder(t, router, "completed-2"); order.Status != models.StatusRefunded {
+			t.Errorf("Expected the order to be refunded, got %s", order.Status)
+		}
+	})
+}
+
+func TestOrderValidation(t *testing.T) {
+	payments := &countingGateway{}
+	router := newRouter(newOrderTestDeps(payments))
+
+	t.Run("Field errors", func(t *testing.T) {
+		rr := doRequest(router, "POST", "/orders/sync",
+			`{"status": "completed", "items": [{"product_id": 1, "quantity": 1}, {"product_id": 2, "quantity": 0}]}`, nil)
+		var errorResponse models.Error
+		json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+
+		var fields []string
+		for _, fieldErr := range errorResponse.Fields {
+			fields = append(fields, fieldErr.Field)
+		}
+		want := []string{"customer_id", "items[1].quantity", "status"}
+		if rr.Code != http.StatusBadRequest || !reflect.DeepEqual(fields, want) {
+			t.Errorf("Expected 400 listing %v, got %d %s", want, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Malformed bodies", func(t *testing.T) {
+		for _, body := range []string{
+			`{"customer_id": 7, "items": [{"product_id": 1, "quantity": 1}], "coupon": "FREE"}`,
+			`{"customer_id": 7, "items": [{"product_id": 1, "quantity": 1, "discount": 5}]}`,
+			`{"customer_id": 7, "items": []}`,
+			`{"customer_id": 7, "items": [`,
+			`{"customer_id": 7, "items": [{"product_id": 4294967297, "quantity": 1}]}`,
+		} {
+			if rr := doRequest(router, "POST", "/orders/sync", body, nil); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d %s", body, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("Oversized bodies", func(t *testing.T) {
+		padding := strings.Repeat(" ", handlers.MaxOrderBodyBytes)
+		body := `{"customer_id": 7,` + padding + `"items": [{"product_id": 1, "quantity": 1}]}`
+		for _, headers := range []map[string]string{nil, {handlers.IdempotencyKeyHeader: "big-1"}} {
+			rr := doRequest(router, "POST", "/orders/sync", body, headers)
+			if rr.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rr.Body.String(), "PAYLOAD_TOO_LARGE") {
+				t.Errorf("Expected 413 with headers %v, got %d %s", headers, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	if payments.charges() != 0 {
+		t.Errorf("Expected rejected orders not to be charged, got %d charges", payments.charges())
+	}
+	if rr := doRequest(router, "POST", "/orders/sync",
+		`{"customer_id": 7, "items": [{"product_id": 1, "quantity": 1}]}`, nil); rr.Code != http.StatusOK {
+		t.Errorf("Expected a valid order to be processed, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
 // Benchmark test for performance
 func BenchmarkHealthEndpoint(b *testing.B) {
 	router := setupTestServer()
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// brandMergeBatch is how many products are rewritten at a time when brands are merged
+const brandMergeBatch = 500
+
+// Product fields a BrandHandler can manage
+const (
+	BrandField        = "brand"
+	ManufacturerField = "manufacturer"
+)
+
+// SetNameRegistries makes product writes store the canonical brand and
+// manufacturer names, registering names seen for the first time, and makes
+// brand and manufacturer filters match every alias of a name
+func (h *ProductHandler) SetNameRegistries(brands, manufacturers *store.BrandRegistry) {
+	h.brands, h.manufacturers = brands, manufacturers
+}
+
+// newSpellings maps the NameKey of each name that is not registered yet to
+// the first spelling seen, per registry, so the rows of one import agree on
+// a spelling before any of them is written and registered
+type newSpellings map[*store.BrandRegistry]map[string]string
+
+// nameField is a product name field and the registry it is kept canonical in
+type nameField struct {
+	registry *store.BrandRegistry
+	name     *string
+}
+
+// nameFields pairs the product's brand and manufacturer with their registries
+func (h *ProductHandler) nameFields(product *models.Product) []nameField {
+	return []nameField{{h.brands, &product.Brand}, {h.manufacturers, &product.Manufacturer}}
+}
+
+// canonicalNames replaces the product's brand and manufacturer with their
+// canonical names. Names that are not registered yet are only cleaned up
+// (or, with spellings, given the spelling seen first); registerNames adds
+// them once the product is saved.
+func (h *ProductHandler) canonicalNames(product *models.Product, spellings newSpellings) error {
+	for _, field := range h.nameFields(product) {
+		if field.registry == nil {
+			continue
+		}
+		if canonical, ok := field.registry.Lookup(*field.name); ok {
+			*field.name = canonical
+			continue
+		}
+
+		name := models.CleanName(*field.name)
+		if len(name) < 1 || len(name) > 100 {
+			return store.ErrBrandName
+		}
+		if spellings != nil {
+			seen := spellings[field.registry]
+			if seen == nil {
+				seen = make(map[string]string)
+				spellings[field.registry] = seen
+			}
+			if first, ok := seen[models.NameKey(name)]; ok {
+				name = first
+			} else {
+				seen[models.NameKey(name)] = name
+			}
+		}
+		*field.name = name
+	}
+	return nil
+}
+
+// normalizeNames applies canonicalNames to a validated product, writing an
+// error response if a name cannot be used
+func (h *ProductHandler) normalizeNames(w http.ResponseWriter, product *models.Product) bool {
+	if err := h.canonicalNames(product, nil); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid product data", "brand and manufacturer must be between 1 and 100 characters")
+		return false
+	}
+	return true
+}
+
+// registerNames adds the brand and manufacturer names of saved products that
+// the registries don't know yet, saving each registry once for the whole
+// batch. The products are already saved, so a failure is only logged; the
+// names are registered again at the next startup.
+func (h *ProductHandler) registerNames(saved ...*models.Product) {
+	brands := make([]string, len(saved))
+	manufacturers := make([]string, len(saved))
+	for i, product := range saved {
+		brands[i], manufacturers[i] = product.Brand, product.Manufacturer
+	}
+
+	for _, field := range []struct {
+		registry *store.BrandRegistry
+		names    []string
+	}{
+		{h.brands, brands},
+		{h.manufacturers, manufacturers},
+	} {
+		if field.registry == nil {
+			continue
+		}
+		if err := field.registry.Register(field.names...); err != nil {
+			log.Printf("Failed to register names of %d saved products: %v", len(saved), err)
+		}
+	}
+}
+
+// canonicalFilter maps brand and manufacturer filter values to their
+// canonical names, so filtering on an alias finds the brand's products
+func (h *ProductHandler) canonicalFilter(filter *store.SearchFilter) {
+	if h.brands != nil {
+		for i, brand := range filter.Brands {
+			filter.Brands[i] = h.brands.Canonical(brand)
+		}
+	}
+	if h.manufacturers != nil {
+		for i, manufacturer := range filter.Manufacturers {
+			filter.Manufacturers[i] = h.manufacturers.Canonical(manufacturer)
+		}
+	}
+}
+
+type BrandHandler struct {
+	registry *store.BrandRegistry
+
+	// products is rewritten when brands are merged
+	products store.ProductRepository
+
+	// field is the product field the registry names, BrandField or ManufacturerField
+	field string
+}
+
+// NewBrandHandler creates a handler for the brand or manufacturer registry
+func NewBrandHandler(registry *store.BrandRegistry, products store.ProductRepository, field string) *BrandHandler {
+	return &BrandHandler{registry: registry, products: products, field: field}
+}
+
+// ListBrands handles GET /brands - every brand with its aliases, ordered by name
+func (h *BrandHandler) ListBrands(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, models.BrandListResponse{Brands: h.registry.List()})
+}
+
+// GetBrand handles GET /brands/{brandId}
+func (h *BrandHandler) GetBrand(w http.ResponseWriter, r *http.Request) {
+	brandID, ok := parseBrandID(w, r)
+	if !ok {
+		return
+	}
+
+	brand, err := h.registry.Get(brandID)
+	if err != nil {
+		respondWithBrandError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, brand)
+}
+
+// CreateBrand handles POST /brands - registers a canonical name, optionally
+// with aliases. Neither may already be a name or alias of another brand.
+func (h *BrandHandler) CreateBrand(w http.ResponseWriter, r *http.Request) {
+	var brand models.Brand
+	if !decodeStrict(w, r, &brand) {
+		return
+	}
+	if brand.ID != 0 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid brand data", "id is assigned by the server and must be omitted")
+		return
+	}
+
+	created, err := h.registry.Create(brand)
+	if err != nil {
+		respondWithBrandError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, created.ID))
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// AddBrandAlias handles POST /brands/{brandId}/aliases - makes another
+// spelling resolve to the brand. Products already stored under that spelling
+// keep it until they are next written or the server restarts.
+func (h *BrandHandler) AddBrandAlias(w http.ResponseWriter, r *http.Request) {
+	brandID, ok := parseBrandID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.BrandAliasRequest
+	if !decodeStrict(w, r, &req) {
+		return
+	}
+
+	brand, err := h.registry.AddAlias(brandID, req.Alias)
+	if err != nil {
+		respondWithBrandError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, brand)
+}
+
+// RemoveBrandAlias handles DELETE /brands/{brandId}/aliases/{alias}
+func (h *BrandHandler) RemoveBrandAlias(w http.ResponseWriter, r *http.Request) {
+	brandID, ok := parseBrandID(w, r)
+	if !ok {
+		return
+	}
+
+	brand, err := h.registry.RemoveAlias(brandID, mux.Vars(r)["alias"])
+	if err != nil {
+		respondWithBrandError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, brand)
+}
+
+// MergeBrands handles POST /brands/{brandId}/merge - folds the brands listed
+// in from into this one. Their names become aliases and their products are
+// moved to this brand's canonical name.
+func (h *BrandHandler) MergeBrands(w http.ResponseWriter, r *http.Request) {
+	brandID, ok := parseBrandID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.BrandMergeRequest
+	if !decodeStrict(w, r, &req) {
+		return
+	}
+	if len(req.From) == 0 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid merge request", "from must list at least one brand ID")
+		return
+	}
+
+	merged, removed, err := h.registry.Merge(brandID, req.From)
+	if err != nil {
+		respondWithBrandError(w, err)
+		return
+	}
+
+	updated, err := h.renameProducts(removed, merged.Name)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Brands merged but their products could not all be updated", err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.BrandMergeResponse{Brand: *merged, ProductsUpdated: updated})
+}
+
+// renameProducts moves every product carrying one of the removed names to the
+// canonical name and returns how many products were rewritten
+func (h *BrandHandler) renameProducts(removed []models.Brand, to string) (int, error) {
+	var names []string
+	for _, brand := range removed {
+		names = append(names, brand.Name)
+		names = append(names, brand.Aliases...)
+	}
+
+	req := store.ListRequest{Limit: brandMergeBatch}
+	if h.field == ManufacturerField {
+		req.Filter.Manufacturers = names
+	} else {
+		req.Filter.Brands = names
+	}
+
+	updated := 0
+	for {
+		page, err := h.products.List(req)
+		if err != nil {
+			return updated, err
+		}
+
+		batch := make([]*models.Product, len(page.Products))
+		for i := range page.Products {
+			if h.field == ManufacturerField {
+				page.Products[i].Manufacturer = to
+			} else {
+				page.Products[i].Brand = to
+			}
+			batch[i] = &page.Products[i]
+		}
+		if err := h.products.UpsertBatch(batch); err != nil {
+			return updated, err
+		}
+		updated += len(batch)
+
+		if page.NextAfter == 0 {
+			return updated, nil
+		}
+		req.After = page.NextAfter
+	}
+}
+
+// parseBrandID reads the brandId path variable, writing a 400 response if it is invalid
+func parseBrandID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	brandID, err := strconv.ParseInt(mux.Vars(r)["brandId"], 10, 32)
+	if err != nil || brandID < 1 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid brand ID", "Brand ID must be a positive integer")
+		return 0, false
+	}
+	return int32(brandID), true
+}
+
+// respondWithBrandError maps brand registry errors to HTTP responses
+func respondWithBrandError(w http.ResponseWriter, err error) {
+	switch err {
+	case store.ErrBrandNotFound:
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Brand not found", "No brand exists with the given ID")
+	case store.ErrBrandAlias:
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Alias not found", "The brand has no such alias")
+	case store.ErrBrandExists:
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Brand conflict", err.Error())
+	case store.ErrBrandName, store.ErrBrandMerge:
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid brand data", err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to save brand", err.Error())
+	}
+}
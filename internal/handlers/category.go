@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// categoryRenameBatch is how many products are read at a time when a
+// category is renamed
+const categoryRenameBatch = 500
+
+// SetCategories makes product writes require that the product's category
+// exists and that its category_id is that category's ID
+func (h *ProductHandler) SetCategories(categories models.CategoryResolver) {
+	h.categories = categories
+}
+
+// validate runs Product.Validate and then checks the product's category
+func (h *ProductHandler) validate(product *models.Product) error {
+	if err := product.Validate(); err != nil {
+		return err
+	}
+	return h.validateCategory(product)
+}
+
+// validateCategory checks the product's category against the categories set
+// with SetCategories, if any
+func (h *ProductHandler) validateCategory(product *models.Product) error {
+	if h.categories == nil {
+		return nil
+	}
+	return product.ValidateCategory(h.categories)
+}
+
+type CategoryHandler struct {
+	categories *store.CategoryStore
+
+	// products is used to list category products and follow renames
+	products store.ProductRepository
+}
+
+// NewCategoryHandler creates a category handler
+func NewCategoryHandler(categories *store.CategoryStore, products store.ProductRepository) *CategoryHandler {
+	return &CategoryHandler{categories: categories, products: products}
+}
+
+// ListCategories handles GET /categories - every category, ordered by ID
+func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, models.CategoryListResponse{Categories: h.categories.List()})
+}
+
+// CategoryTree handles GET /categories/tree - top-level categories with subcategories nested
+func (h *CategoryHandler) CategoryTree(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, models.CategoryTreeResponse{Categories: h.categories.Tree()})
+}
+
+// GetCategory handles GET /categories/{categoryId}
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := parseCategoryID(w, r)
+	if !ok {
+		return
+	}
+
+	category, err := h.categories.Get(categoryID)
+	if err != nil {
+		respondWithCategoryError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, category)
+}
+
+// CreateCategory handles POST /categories - the server assigns the ID and,
+// if it is omitted, derives the slug from the name
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if !decodeCategory(w, r, &category) {
+		return
+	}
+	if category.ID != 0 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid category data", "id is assigned by the server and must be omitted")
+		return
+	}
+
+	created, err := h.categories.Create(category)
+	if err != nil {
+		respondWithCategoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/categories/%d", created.ID))
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// UpdateCategory handles PUT /categories/{categoryId} - replaces the name, slug
+// and parent. Products in a renamed category are moved to the new name; if
+// that fails the rename is rolled back.
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := parseCategoryID(w, r)
+	if !ok {
+		return
+	}
+
+	var category models.Category
+	if !decodeCategory(w, r, &category) {
+		return
+	}
+	if category.ID != 0 && category.ID != categoryID {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Category ID mismatch", "Category ID in URL must match id in request body")
+		return
+	}
+	category.ID = categoryID
+
+	updated, previous, err := h.categories.Update(category)
+	if err != nil {
+		respondWithCategoryError(w, err)
+		return
+	}
+
+	if updated.Name != previous.Name {
+		if err := h.renameProducts(previous.Name, updated.Name); err != nil {
+			h.rollBackRename(previous, updated.Name)
+			respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+				"Category products could not be moved, so the rename was rolled back", err.Error())
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// rollBackRename restores a category's previous version and moves the
+// products already renamed back to it, so no product is left naming a
+// category that does not exist
+func (h *CategoryHandler) rollBackRename(previous *models.Category, renamedTo string) {
+	if _, _, err := h.categories.Update(*previous); err != nil {
+		log.Printf("Failed to roll back the rename of category %d: %v", previous.ID, err)
+		return
+	}
+	if err := h.renameProducts(renamedTo, previous.Name); err != nil {
+		log.Printf("Failed to move products back to category %q: %v", previous.Name, err)
+	}
+}
+
+// renameProducts moves every product from one category name to another.
+// Each product is rewritten with a compare-and-swap, so a concurrent update
+// of a product is never overwritten.
+func (h *CategoryHandler) renameProducts(from, to string) error {
+	req := store.ListRequest{Filter: store.SearchFilter{Categories: []string{from}}, Limit: categoryRenameBatch}
+	for {
+		page, err := h.products.List(req)
+		if err != nil {
+			return err
+		}
+
+		for i := range page.Products {
+			if err := h.moveProduct(&page.Products[i], from, to); err != nil {
+				return err
+			}
+		}
+
+		if page.NextAfter == 0 {
+			return nil
+		}
+		req.After = page.NextAfter
+	}
+}
+
+// moveProduct moves one product from category from to category to. When the
+// product changed since it was read, it is read again and moved only if it
+// is still in from.
+func (h *CategoryHandler) moveProduct(product *models.Product, from, to string) error {
+	for {
+		moved := *product
+		moved.Category = to
+		_, err := h.products.CompareAndSwap(&moved, product.Version)
+		if err == nil || err == store.ErrProductNotFound {
+			return nil // moved, or deleted in the meantime
+		}
+		if err != store.ErrVersionConflict {
+			return err
+		}
+
+		product, err = h.products.Get(moved.ProductID)
+		if err == store.ErrProductNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(product.Category, from) {
+			return nil
+		}
+	}
+}
+
+// DeleteCategory handles DELETE /categories/{categoryId}. Categories that
+// still have subcategories or products cannot be deleted (409).
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := parseCategoryID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.categories.Delete(categoryID, h.products); err != nil {
+		respondWithCategoryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CategoryProducts handles GET /categories/{categoryId}/products. Products of
+// every subcategory are included unless descendants=false; the listing
+// filters, limit and after cursor of GET /products apply.
+func (h *CategoryHandler) CategoryProducts(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := parseCategoryID(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	req, err := parseListRequest(params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid listing parameters", err.Error())
+		return
+	}
+	if len(req.Filter.Categories) > 0 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid filter", "The category comes from the URL; 'category' cannot be used here")
+		return
+	}
+
+	descendants := true
+	if value := params.Get("descendants"); value != "" {
+		if descendants, err = strconv.ParseBool(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid descendants flag", "Query parameter 'descendants' must be 'true' or 'false'")
+			return
+		}
+	}
+
+	var categories []models.Category
+	if descendants {
+		categories, err = h.categories.Descendants(categoryID)
+	} else {
+		var category *models.Category
+		if category, err = h.categories.Get(categoryID); err == nil {
+			categories = []models.Category{*category}
+		}
+	}
+	if err != nil {
+		respondWithCategoryError(w, err)
+		return
+	}
+
+	for _, c := range categories {
+		req.Filter.Categories = append(req.Filter.Categories, c.Name)
+	}
+	response, err := h.products.List(req)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to list products", err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// parseCategoryID reads the categoryId path variable, writing a 400 response if it is invalid
+func parseCategoryID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	categoryID, err := strconv.ParseInt(mux.Vars(r)["categoryId"], 10, 32)
+	if err != nil || categoryID < 1 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid category ID", "Category ID must be a positive integer")
+		return 0, false
+	}
+	return int32(categoryID), true
+}
+
+// decodeCategory parses and validates a category body, deriving the slug from
+// the name if it is omitted, and writes a 400 response if anything is wrong
+func decodeCategory(w http.ResponseWriter, r *http.Request, category *models.Category) bool {
+	defer r.Body.Close()
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(category); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid JSON format", err.Error())
+		return false
+	}
+
+	if category.Slug == "" {
+		category.Slug = models.Slugify(category.Name)
+	}
+	if err := category.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid category data", err.Error())
+		return false
+	}
+	return true
+}
+
+// respondWithCategoryError maps category store errors to HTTP responses
+func respondWithCategoryError(w http.ResponseWriter, err error) {
+	switch err {
+	case store.ErrCategoryNotFound:
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Category not found", "No category exists with the given ID")
+	case store.ErrCategoryExists:
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Category conflict", err.Error())
+	case store.ErrCategoryInUse:
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Category in use", "Move or delete its subcategories and products first")
+	case store.ErrCategoryParent:
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid parent category", err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to save category", err.Error())
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// productETag formats a product version as a strong entity tag
+func productETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists etag
+// (or is "*"). If-None-Match uses weak comparison, so W/ prefixes are ignored
+// when weak is set; If-Match always compares strongly.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch evaluates the If-Match precondition of an update against the
+// stored version (0 when the product does not exist yet). It writes the error
+// response and returns false when the update must not go ahead.
+func (h *ProductHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, currentVersion int64) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		if currentVersion != 0 && h.requireIfMatch {
+			respondWithError(w, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED",
+				"If-Match header required", "Send the product's current ETag in If-Match to update it")
+			return false
+		}
+		return true
+	}
+
+	if currentVersion == 0 || !etagMatches(ifMatch, productETag(currentVersion), false) {
+		respondWithPreconditionFailed(w)
+		return false
+	}
+	return true
+}
+
+func respondWithPreconditionFailed(w http.ResponseWriter) {
+	respondWithError(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED",
+		"Product has been modified", "If-Match does not match the product's current ETag; fetch it again and retry")
+}
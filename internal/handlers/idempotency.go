@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+const (
+	// IdempotencyKeyHeader names the header clients send to make a request safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// maxIdempotencyKeyLength bounds the keys held in memory
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodyBytes bounds the request bodies read and fingerprinted
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// IdempotencyHandler makes POST endpoints safe to retry: a request carrying an
+// Idempotency-Key is handled once, and duplicates with the same key and body
+// get the original response replayed instead of repeating the work
+type IdempotencyHandler struct {
+	keys *store.IdempotencyStore
+}
+
+// NewIdempotencyHandler creates the middleware around a key store
+func NewIdempotencyHandler(keys *store.IdempotencyStore) *IdempotencyHandler {
+	return &IdempotencyHandler{keys: keys}
+}
+
+// replayableErrors are the error codes of requests rejected for their own
+// content, which a retry with the same body would get again
+var replayableErrors = map[string]bool{"INVALID_INPUT": true, "PAYLOAD_TOO_LARGE": true}
+
+// Wrap applies Idempotency-Key handling to next. Requests without the header
+// pass straight through. Only successes and rejections of the request itself
+// are recorded; other errors leave the key free, so the request can be retried
+// with it.
+func (h *IdempotencyHandler) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid Idempotency-Key", "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		// The body is held in memory to fingerprint it, so it is capped
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			if !bodyTooLarge(w, err) {
+				respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+					"Invalid request body", err.Error())
+			}
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		replay, err := h.keys.Begin(r.Context(), key, requestFingerprint(r, body))
+		if err == store.ErrIdempotencyKeyReused {
+			respondWithError(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+				"Idempotency-Key reused", "The key was already used for a different request; use a new key")
+			return
+		}
+		if err != nil {
+			// The request was cancelled while the first one with its key was in flight
+			respondWithError(w, http.StatusConflict, "CONFLICT",
+				"Request in progress", "A request with this Idempotency-Key is still being processed; retry later")
+			return
+		}
+		if replay != nil {
+			for name, values := range replay.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(replay.Status)
+			w.Write(replay.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// Also reached when next panics
+			if !completed {
+				h.keys.Abandon(key)
+			}
+		}()
+
+		next(recorder, r)
+
+		if !replayable(recorder.statusOrOK(), recorder.body.Bytes()) {
+			return
+		}
+		h.keys.Complete(key, &store.StoredResponse{
+			Status: recorder.statusOrOK(),
+			Header: w.Header().Clone(),
+			Body:   recorder.body.Bytes(),
+		})
+		completed = true
+	}
+}
+
+// replayable reports whether a response is recorded for its key: 2xx
+// responses and 4xx validation errors. Outcomes that depend on the state of
+// the store when the request ran, such as 409 OUT_OF_STOCK, and server errors
+// are not.
+func replayable(status int, body []byte) bool {
+	if status >= 200 && status < 300 {
+		return true
+	}
+	if status < 400 || status >= 500 {
+		return false
+	}
+	var errorResponse models.Error
+	return json.Unmarshal(body, &errorResponse) == nil && replayableErrors[errorResponse.Error]
+}
+
+// requestFingerprint identifies a request by its method, path and body, so a
+// key cannot be replayed against a different endpoint or order
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) statusOrOK() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type InventoryHandler struct {
+	inventory *store.InventoryStore
+
+	// products is checked so stock is only kept for products that exist
+	products store.ProductRepository
+}
+
+// NewInventoryHandler creates an inventory handler
+func NewInventoryHandler(inventory *store.InventoryStore, products store.ProductRepository) *InventoryHandler {
+	return &InventoryHandler{inventory: inventory, products: products}
+}
+
+// GetInventory handles GET /products/{productId}/inventory - on-hand, reserved
+// and available units in total and per warehouse. Products whose stock was
+// never set report tracked=false.
+func (h *InventoryHandler) GetInventory(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseProductID(w, r)
+	if !ok || !h.productExists(w, productID) {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, h.inventory.Get(productID))
+}
+
+// SetInventory handles PUT /products/{productId}/inventory - sets the on-hand
+// units at one warehouse (the main one unless warehouse_id is given), which
+// starts tracking stock for the product. on_hand cannot drop below the units
+// reserved there by orders in flight (409).
+func (h *InventoryHandler) SetInventory(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseProductID(w, r)
+	if !ok {
+		return
+	}
+
+	var update models.InventoryUpdate
+	if !decodeStrict(w, r, &update) {
+		return
+	}
+	if update.WarehouseID == "" {
+		update.WarehouseID = store.DefaultWarehouseID
+	}
+
+	if !h.productExists(w, productID) {
+		return
+	}
+
+	inventory, err := h.inventory.SetOnHand(productID, update.WarehouseID, update.OnHand)
+	if err != nil {
+		respondWithInventoryError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, inventory)
+}
+
+// ListWarehouses handles GET /warehouses
+func (h *InventoryHandler) ListWarehouses(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, models.WarehouseListResponse{Warehouses: h.inventory.Warehouses()})
+}
+
+// GetWarehouse handles GET /warehouses/{warehouseId}
+func (h *InventoryHandler) GetWarehouse(w http.ResponseWriter, r *http.Request) {
+	warehouse, err := h.inventory.Warehouse(mux.Vars(r)["warehouseId"])
+	if err != nil {
+		respondWithInventoryError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, warehouse)
+}
+
+// CreateWarehouse handles POST /warehouses - adds a warehouse with no stock
+func (h *InventoryHandler) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	var warehouse models.Warehouse
+	if !decodeStrict(w, r, &warehouse) {
+		return
+	}
+	if err := warehouse.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid warehouse data", err.Error())
+		return
+	}
+
+	created, err := h.inventory.CreateWarehouse(warehouse)
+	if err != nil {
+		respondWithInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/warehouses/%s", created.ID))
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// WarehouseStock handles GET /warehouses/{warehouseId}/inventory - the stock
+// of every product held in the warehouse
+func (h *InventoryHandler) WarehouseStock(w http.ResponseWriter, r *http.Request) {
+	warehouseID := mux.Vars(r)["warehouseId"]
+	stock, err := h.inventory.WarehouseStock(warehouseID)
+	if err != nil {
+		respondWithInventoryError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.WarehouseStockResponse{WarehouseID: warehouseID, Stock: stock})
+}
+
+// TransferStock handles POST /inventory/transfers - moves available units of
+// a product between warehouses and returns the product's new stock
+func (h *InventoryHandler) TransferStock(w http.ResponseWriter, r *http.Request) {
+	var transfer models.TransferRequest
+	if !decodeStrict(w, r, &transfer) {
+		return
+	}
+	if transfer.ProductID < 1 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid product ID", "product_id must be a positive integer")
+		return
+	}
+
+	inventory, err := h.inventory.Transfer(transfer.ProductID, transfer.From, transfer.To, transfer.Quantity)
+	if err != nil {
+		respondWithInventoryError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, inventory)
+}
+
+// productExists writes a 404 (or 500) response unless the product is in the catalog
+func (h *InventoryHandler) productExists(w http.ResponseWriter, productID int32) bool {
+	_, err := h.products.Get(productID)
+	if err == store.ErrProductNotFound {
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Product not found", "No product exists with the given ID")
+		return false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Internal server error", err.Error())
+		return false
+	}
+	return true
+}
+
+// respondWithInventoryError maps inventory store errors to HTTP responses
+func respondWithInventoryError(w http.ResponseWriter, err error) {
+	switch {
+	case err == store.ErrWarehouseNotFound:
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Warehouse not found", "No warehouse exists with the given ID")
+	case errors.Is(err, store.ErrInsufficientStock):
+		respondWithError(w, http.StatusConflict, "OUT_OF_STOCK",
+			"Insufficient stock", err.Error())
+	case err == store.ErrStockBelowReserved:
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Stock is reserved", err.Error())
+	case err == store.ErrWarehouseExists:
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Warehouse conflict", err.Error())
+	case err == store.ErrInvalidStock, err == store.ErrInvalidQuantity, err == store.ErrInvalidTransfer:
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid inventory data", err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to save inventory", err.Error())
+	}
+}
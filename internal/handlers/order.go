@@ -2,33 +2,77 @@ package handlers
 
 import (
 	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/payment"
+	"CS6650_Online_Store/internal/store"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
+// MaxOrderBodyBytes caps the size of a submitted order; MaxOrderItems items
+// fit with plenty of room
+const MaxOrderBodyBytes = 64 << 10
+
 type OrderHandler struct {
-	// Buffered channel to simulate payment processor bottleneck
-	// Only 1 payment can be processed at a time (3 seconds each)
-	paymentGateway chan struct{}
+	// Payment processor orders are charged and refunded through. The default
+	// simulates the bottleneck: 1 payment at a time, 3 seconds each.
+	payments payment.Gateway
 
 	// AWS SNS client for publishing order events
-	snsClient  *sns.SNS
+	snsClient   *sns.SNS
 	snsTopicArn string
+
+	// Stock reserved for incoming orders; nil accepts orders without checking stock
+	inventory *store.InventoryStore
+
+	// Warehouse allocation strategy for orders that don't pick one
+	allocationStrategy string
+
+	// Catalog prices charged for order items; nil keeps the client's prices
+	prices store.PriceBook
+
+	// Sales tax applied to the subtotal, e.g. 0.08 for 8%
+	taxRate float64
+
+	// Accepted orders and their status; nil keeps no record
+	orders store.OrderStore
 }
 
 // NewOrderHandler creates a new order handler with payment gateway simulation and AWS SNS
 func NewOrderHandler() *OrderHandler {
 	handler := &OrderHandler{
-		// Buffer size of 1 means only 1 payment can process at a time
-		paymentGateway: make(chan struct{}, 1),
+		payments:           payment.NewSimulatedGateway(3 * time.Second),
+		allocationStrategy: store.AllocateSplit,
+	}
+
+	// ALLOCATION_STRATEGY picks how order items are spread over warehouses
+	if strategy := os.Getenv("ALLOCATION_STRATEGY"); strategy != "" {
+		if store.ValidAllocationStrategy(strategy) {
+			handler.allocationStrategy = strategy
+		} else {
+			log.Printf("Warning: ignoring unknown ALLOCATION_STRATEGY %q", strategy)
+		}
+	}
+
+	// ORDER_TAX_RATE is the sales tax fraction added to every order
+	if value := os.Getenv("ORDER_TAX_RATE"); value != "" {
+		if rate, err := strconv.ParseFloat(value, 64); err != nil || rate < 0 || rate > 1 {
+			log.Printf("Warning: ignoring ORDER_TAX_RATE %q: must be a fraction between 0 and 1", value)
+		} else {
+			handler.taxRate = rate
+		}
 	}
 
 	// Initialize SNS client if topic ARN is provided
@@ -49,14 +93,239 @@ func NewOrderHandler() *OrderHandler {
 	return handler
 }
 
+// SetPaymentGateway replaces the simulated payment processor
+func (h *OrderHandler) SetPaymentGateway(payments payment.Gateway) {
+	h.payments = payments
+}
+
+// SetPriceBook makes orders pay catalog prices: item prices sent by the
+// client are replaced, and orders for unknown or unpriced products are rejected
+func (h *OrderHandler) SetPriceBook(prices store.PriceBook) {
+	h.prices = prices
+}
+
+// priceOrder sets the order's item prices and totals, writing an error
+// response if an item cannot be priced
+func (h *OrderHandler) priceOrder(w http.ResponseWriter, order *models.Order) bool {
+	if h.prices == nil {
+		order.ComputeTotals(h.taxRate)
+		return true
+	}
+
+	var productIDs []int32
+	var unknown []int
+	seen := make(map[int]bool, len(order.Items))
+	for _, item := range order.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		seen[item.ProductID] = true
+		if item.ProductID < 1 || item.ProductID > math.MaxInt32 {
+			unknown = append(unknown, item.ProductID)
+			continue
+		}
+		productIDs = append(productIDs, int32(item.ProductID))
+	}
+
+	prices, missing, err := h.prices.Prices(productIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to look up prices", err.Error())
+		return false
+	}
+	for _, productID := range missing {
+		unknown = append(unknown, int(productID))
+	}
+	if len(unknown) > 0 {
+		respondWithError(w, http.StatusBadRequest, "UNKNOWN_PRODUCT",
+			"Unknown products", "No products exist with IDs "+joinIDs(unknown))
+		return false
+	}
+
+	var unpriced []int
+	for _, productID := range productIDs {
+		if prices[productID] <= 0 {
+			unpriced = append(unpriced, int(productID))
+		}
+	}
+	if len(unpriced) > 0 {
+		// Also the case for products stored before prices were added, until
+		// an operator prices them
+		respondWithError(w, http.StatusConflict, "PRODUCT_UNPRICED",
+			"Products not priced", "Products "+joinIDs(unpriced)+" have no price yet and cannot be ordered")
+		return false
+	}
+
+	order.ApplyPrices(prices, h.taxRate)
+	return true
+}
+
+// joinIDs formats product IDs for an error message, e.g. "7, 9"
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetOrderStore records every accepted order so its status can be looked up
+func (h *OrderHandler) SetOrderStore(orders store.OrderStore) {
+	h.orders = orders
+}
+
+// saveOrder records a new order, writing an error response and releasing its
+// stock if it cannot
+func (h *OrderHandler) saveOrder(w http.ResponseWriter, order *models.Order) bool {
+	if h.orders == nil {
+		return true
+	}
+
+	err := h.orders.Create(order)
+	if err == nil {
+		return true
+	}
+	h.releaseStock(order)
+	if err == store.ErrOrderExists {
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Duplicate order", "An order with this order_id already exists")
+		return false
+	}
+	respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+		"Failed to save order", err.Error())
+	return false
+}
+
+// setStatus moves a saved order to a new status through the order state
+// machine, in the store as well. The response does not depend on the record,
+// so a failure is only logged.
+func (h *OrderHandler) setStatus(order *models.Order, status, reason string) {
+	if h.orders == nil {
+		if err := order.Transition(status, time.Now(), reason); err != nil {
+			log.Printf("Failed to move order %s to %s: %v", order.OrderID, status, err)
+		}
+		return
+	}
+
+	updated, err := h.orders.Update(order.OrderID, func(stored *models.Order) error {
+		return stored.Transition(status, time.Now(), reason)
+	})
+	if err != nil {
+		log.Printf("Failed to move order %s to %s: %v", order.OrderID, status, err)
+		return
+	}
+	*order = *updated
+}
+
+// GetOrder handles GET /orders/{orderId} - the order's current status, items,
+// totals and timestamps
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	if !h.ordersRecorded(w) {
+		return
+	}
+
+	order, err := h.orders.Get(mux.Vars(r)["orderId"])
+	if err != nil {
+		respondWithOrderError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// SetInventory makes orders reserve stock for their items and fail with 409
+// when a tracked product runs out
+func (h *OrderHandler) SetInventory(inventory *store.InventoryStore) {
+	h.inventory = inventory
+}
+
+// reserveStock holds stock for the order's items under its order ID and
+// records the warehouses it comes from, writing an error response if it cannot
+func (h *OrderHandler) reserveStock(w http.ResponseWriter, order *models.Order) bool {
+	order.Allocations = []models.Allocation{}
+	if h.inventory == nil {
+		return true
+	}
+
+	if order.Allocation == "" {
+		order.Allocation = h.allocationStrategy
+	}
+	if order.ShipTo != nil {
+		if err := order.ShipTo.Validate(); err != nil {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid ship_to location", err.Error())
+			return false
+		}
+	}
+
+	// Item.Validate keeps product IDs within int32
+	lines := make([]store.ReservationLine, len(order.Items))
+	for i, item := range order.Items {
+		lines[i] = store.ReservationLine{ProductID: int32(item.ProductID), Quantity: item.Quantity}
+	}
+
+	allocations, err := h.inventory.Reserve(order.OrderID, lines, store.ReserveOptions{Strategy: order.Allocation, ShipTo: order.ShipTo})
+	switch {
+	case err == nil:
+		order.Allocations = allocations
+		return true
+	case errors.Is(err, store.ErrInsufficientStock):
+		respondWithError(w, http.StatusConflict, "OUT_OF_STOCK",
+			"Insufficient stock", err.Error())
+	case err == store.ErrReservationExists:
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Duplicate order", "An order with this order_id already exists")
+	case err == store.ErrInvalidQuantity:
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid order items", "Every item quantity must be positive")
+	case err == store.ErrInvalidAllocation:
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid allocation strategy", err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to reserve stock", err.Error())
+	}
+	return false
+}
+
+// commitStock turns the order's reservation into a sale
+func (h *OrderHandler) commitStock(order *models.Order) {
+	if h.inventory == nil || len(order.Allocations) == 0 {
+		return
+	}
+	if err := h.inventory.Commit(order.OrderID); err != nil {
+		log.Printf("Failed to commit stock for order %s: %v", order.OrderID, err)
+	}
+}
+
+// releaseStock returns the stock held for an order that was not accepted
+func (h *OrderHandler) releaseStock(order *models.Order) {
+	if h.inventory == nil || len(order.Allocations) == 0 {
+		return
+	}
+	if err := h.inventory.Release(order.OrderID); err != nil {
+		log.Printf("Failed to release stock for order %s: %v", order.OrderID, err)
+	}
+}
+
+// decodeOrder parses a submitted order, which may only hold known fields,
+// writing a 400 response listing every invalid field if it is not acceptable
+func decodeOrder(w http.ResponseWriter, r *http.Request, order *models.Order) bool {
+	if !decodeStrict(w, r, order) {
+		return false
+	}
+	if err := order.Validate(); err != nil {
+		respondWithValidationError(w, "Invalid order", err)
+		return false
+	}
+	return true
+}
+
 // ProcessOrderSync handles POST /orders/sync
 // This is the synchronous approach - customer waits for payment verification
 func (h *OrderHandler) ProcessOrderSync(w http.ResponseWriter, r *http.Request) {
-	// Parse request body
+	// Parse and validate request body
 	var order models.Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
-			"Invalid request body", err.Error())
+	if !decodeOrder(w, r, &order) {
 		return
 	}
 
@@ -65,27 +334,45 @@ func (h *OrderHandler) ProcessOrderSync(w http.ResponseWriter, r *http.Request)
 		order.OrderID = uuid.New().String()
 	}
 
-	// Set initial status and timestamp
-	order.Status = models.StatusProcessing
-	order.CreatedAt = time.Now()
-
-	// Simulate payment processing bottleneck
-	// This blocks until payment gateway is available
-	h.paymentGateway <- struct{}{} // Acquire lock (blocks if channel is full)
+	// Start the order's status history
+	order.Begin(time.Now())
 
-	// Simulate 3-second payment verification
-	time.Sleep(3 * time.Second)
+	// Charge catalog prices, then hold the items' stock while the payment is verified
+	if !h.priceOrder(w, &order) || !h.reserveStock(w, &order) {
+		return
+	}
+	if err := order.Transition(models.StatusProcessing, time.Now(), ""); err != nil {
+		h.releaseStock(&order)
+		respondWithOrderError(w, err)
+		return
+	}
+	if !h.saveOrder(w, &order) {
+		return
+	}
 
-	<-h.paymentGateway // Release lock
+	// Charge the order; this blocks until the payment gateway is available
+	if err := h.payments.Charge(order.OrderID, order.Total); err != nil {
+		h.releaseStock(&order)
+		h.setStatus(&order, models.StatusFailed, "payment failed")
+		respondWithError(w, http.StatusBadGateway, "PAYMENT_FAILED",
+			"Payment failed", err.Error())
+		return
+	}
 
-	// Payment successful - mark order as completed
-	order.Status = models.StatusCompleted
+	// Payment successful - the reserved stock is sold
+	h.commitStock(&order)
+	h.setStatus(&order, models.StatusCompleted, "payment verified")
 
 	// Return success response
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"message":  "Order processed successfully",
-		"order_id": order.OrderID,
-		"status":   order.Status,
+		"message":     "Order processed successfully",
+		"order_id":    order.OrderID,
+		"status":      order.Status,
+		"items":       order.Items,
+		"subtotal":    order.Subtotal,
+		"tax":         order.Tax,
+		"total":       order.Total,
+		"allocations": order.Allocations,
 	})
 }
 
@@ -93,11 +380,9 @@ func (h *OrderHandler) ProcessOrderSync(w http.ResponseWriter, r *http.Request)
 // This is the asynchronous approach - customer gets immediate acknowledgment
 // Order is published to SNS and processed by background workers
 func (h *OrderHandler) ProcessOrderAsync(w http.ResponseWriter, r *http.Request) {
-	// Parse request body
+	// Parse and validate request body
 	var order models.Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
-			"Invalid request body", err.Error())
+	if !decodeOrder(w, r, &order) {
 		return
 	}
 
@@ -106,9 +391,8 @@ func (h *OrderHandler) ProcessOrderAsync(w http.ResponseWriter, r *http.Request)
 		order.OrderID = uuid.New().String()
 	}
 
-	// Set initial status and timestamp
-	order.Status = models.StatusPending
-	order.CreatedAt = time.Now()
+	// Start the order's status history
+	order.Begin(time.Now())
 
 	// Check if SNS is configured
 	if h.snsClient == nil {
@@ -117,9 +401,17 @@ func (h *OrderHandler) ProcessOrderAsync(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Charge catalog prices, hold the items' stock and record the order; the
+	// stock is released again if the order cannot be queued
+	if !h.priceOrder(w, &order) || !h.reserveStock(w, &order) || !h.saveOrder(w, &order) {
+		return
+	}
+
 	// Publish order to SNS topic
 	orderJSON, err := json.Marshal(order)
 	if err != nil {
+		h.releaseStock(&order)
+		h.setStatus(&order, models.StatusFailed, "could not be serialized")
 		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
 			"Failed to serialize order", err.Error())
 		return
@@ -139,6 +431,8 @@ func (h *OrderHandler) ProcessOrderAsync(w http.ResponseWriter, r *http.Request)
 	result, err := h.snsClient.Publish(input)
 	if err != nil {
 		log.Printf("Failed to publish to SNS: %v", err)
+		h.releaseStock(&order)
+		h.setStatus(&order, models.StatusFailed, "could not be queued")
 		respondWithError(w, http.StatusInternalServerError, "PUBLISH_FAILED",
 			"Failed to queue order for processing", err.Error())
 		return
@@ -146,11 +440,19 @@ func (h *OrderHandler) ProcessOrderAsync(w http.ResponseWriter, r *http.Request)
 
 	log.Printf("Order %s published to SNS. MessageID: %s", order.OrderID, *result.MessageId)
 
+	// The stock stays reserved until the order processor has charged the
+	// order; a StockSettler then sells it, or releases it if payment failed
+
 	// Return 202 Accepted - order is queued for processing
 	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
-		"message":    "Order accepted for processing",
-		"order_id":   order.OrderID,
-		"status":     order.Status,
-		"message_id": *result.MessageId,
+		"message":     "Order accepted for processing",
+		"order_id":    order.OrderID,
+		"status":      order.Status,
+		"message_id":  *result.MessageId,
+		"items":       order.Items,
+		"subtotal":    order.Subtotal,
+		"tax":         order.Tax,
+		"total":       order.Total,
+		"allocations": order.Allocations,
 	})
 }
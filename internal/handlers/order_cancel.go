@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CancelOrder handles POST /orders/{orderId}/cancel - cancels an order that
+// is still pending. Its stock goes back to the warehouses it was allocated
+// from, and the order processor drops the order when its message arrives.
+// Orders already processing or finished cannot be cancelled (409).
+func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	if !h.ordersRecorded(w) {
+		return
+	}
+
+	order, err := h.orders.Update(mux.Vars(r)["orderId"], func(o *models.Order) error {
+		if o.Status != models.StatusPending {
+			return &models.TransitionError{From: o.Status, To: models.StatusCancelled}
+		}
+		return o.Transition(models.StatusCancelled, time.Now(), "cancelled by customer")
+	})
+	if err != nil {
+		respondWithOrderError(w, err)
+		return
+	}
+
+	h.restock(order)
+	respondWithJSON(w, http.StatusOK, order)
+}
+
+// errRefundStarted aborts the update that starts a refund when the order is
+// already refunding or refunded
+var errRefundStarted = errors.New("refund already started")
+
+// RefundOrder handles POST /orders/{orderId}/refund - refunds the total of a
+// completed (or shipped) order through the payment gateway. The order is
+// marked refunding in the order store before the gateway is called, and only
+// a refunding order is refunded, so a retry after a crash or a gateway error
+// finishes the same refund and a refunded order is returned as it is. The
+// gateway refunds an order only once, so concurrent retries do not pay out
+// twice either. Refunded items are not restocked.
+func (h *OrderHandler) RefundOrder(w http.ResponseWriter, r *http.Request) {
+	if !h.ordersRecorded(w) {
+		return
+	}
+	orderID := mux.Vars(r)["orderId"]
+
+	order, err := h.orders.Update(orderID, func(o *models.Order) error {
+		if o.Status == models.StatusRefunding || o.Status == models.StatusRefunded {
+			return errRefundStarted
+		}
+		return o.Transition(models.StatusRefunding, time.Now(), "refund requested")
+	})
+	if err == errRefundStarted {
+		order, err = h.orders.Get(orderID)
+	}
+	if err != nil {
+		respondWithOrderError(w, err)
+		return
+	}
+	if order.Status == models.StatusRefunded {
+		respondWithJSON(w, http.StatusOK, order)
+		return
+	}
+
+	if err := h.payments.Refund(orderID, order.Total); err != nil {
+		respondWithError(w, http.StatusBadGateway, "REFUND_FAILED",
+			"Refund failed", err.Error())
+		return
+	}
+
+	refunded, err := h.orders.Update(orderID, func(o *models.Order) error {
+		if o.Status == models.StatusRefunded {
+			return nil // a concurrent retry finished first
+		}
+		return o.Transition(models.StatusRefunded, time.Now(), fmt.Sprintf("refunded %.2f", order.Total))
+	})
+	if err != nil {
+		log.Printf("Order %s was refunded %.2f but could not be marked refunded: %v", orderID, order.Total, err)
+		respondWithOrderError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, refunded)
+}
+
+// restock returns a cancelled order's units to stock
+func (h *OrderHandler) restock(order *models.Order) {
+	if h.inventory == nil || len(order.Allocations) == 0 {
+		return
+	}
+
+	err := h.inventory.Release(order.OrderID)
+	if err == store.ErrReservationUnknown {
+		// The units were sold, or the reservation did not survive a restart;
+		// either way the order keeps its allocations
+		err = h.inventory.Restock(order.Allocations)
+	}
+	if err != nil {
+		log.Printf("Failed to restock cancelled order %s: %v", order.OrderID, err)
+	}
+}
+
+// ordersRecorded writes a 404 response unless orders are kept in an order store
+func (h *OrderHandler) ordersRecorded(w http.ResponseWriter) bool {
+	if h.orders == nil {
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Order not found", "Orders are not recorded by this server")
+		return false
+	}
+	return true
+}
+
+// respondWithOrderError maps order store and state machine errors to HTTP responses
+func respondWithOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case err == store.ErrOrderNotFound:
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Order not found", "No order exists with the given ID")
+	case errors.Is(err, models.ErrInvalidTransition):
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Order status does not allow this", err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to update order", err.Error())
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SetPriceHistory makes price edits part of each product's price timeline and
+// makes GET /products/{id} serve scheduled prices as soon as they are due
+func (h *ProductHandler) SetPriceHistory(history *store.PriceHistory) {
+	h.prices = history
+}
+
+// recordPrice adds the saved product's price to its timeline when the write
+// set a new price. previous is the product before the write, nil if it is new.
+// The product is already saved, so a failure is only logged.
+func (h *ProductHandler) recordPrice(previous, saved *models.Product) {
+	if h.prices == nil || (previous != nil && previous.Price == saved.Price) {
+		return
+	}
+	if _, err := h.prices.Record(saved.ProductID, saved.Price, time.Now()); err != nil {
+		log.Printf("Failed to record price of product %d: %v", saved.ProductID, err)
+	}
+}
+
+// effectivePrice replaces the product's catalog price with a scheduled price
+// that is due but not applied yet, reporting whether it did
+func (h *ProductHandler) effectivePrice(product *models.Product) bool {
+	if h.prices == nil {
+		return false
+	}
+	price, ok := h.prices.Pending(product.ProductID, time.Now())
+	if ok {
+		product.Price = price
+	}
+	return ok
+}
+
+type PriceHandler struct {
+	history  *store.PriceHistory
+	products store.ProductRepository
+}
+
+// NewPriceHandler creates a handler for product price timelines
+func NewPriceHandler(history *store.PriceHistory, products store.ProductRepository) *PriceHandler {
+	return &PriceHandler{history: history, products: products}
+}
+
+// GetPrices handles GET /products/{productId}/prices - the price in effect now
+// and every recorded and scheduled price, oldest first
+func (h *PriceHandler) GetPrices(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseProductID(w, r)
+	if !ok {
+		return
+	}
+	product, ok := h.product(w, productID)
+	if !ok {
+		return
+	}
+
+	current := product.Price
+	if price, ok := h.history.Pending(productID, time.Now()); ok {
+		current = price
+	}
+	respondWithJSON(w, http.StatusOK, models.PriceTimelineResponse{
+		ProductID:    productID,
+		CurrentPrice: current,
+		Prices:       h.history.Timeline(productID),
+	})
+}
+
+// SchedulePrice handles POST /products/{productId}/prices - schedules the
+// product's price to change at effective_from, which must be in the future
+func (h *PriceHandler) SchedulePrice(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseProductID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PriceScheduleRequest
+	if !decodeStrict(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid price change", err.Error())
+		return
+	}
+
+	if _, ok := h.product(w, productID); !ok {
+		return
+	}
+
+	change, err := h.history.Schedule(productID, req.Price, req.EffectiveFrom, time.Now())
+	if err != nil {
+		respondWithPriceError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/products/%d/prices/%d", productID, change.ID))
+	respondWithJSON(w, http.StatusCreated, change)
+}
+
+// CancelPriceChange handles DELETE /products/{productId}/prices/{changeId} -
+// drops a price change that has not taken effect yet
+func (h *PriceHandler) CancelPriceChange(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseProductID(w, r)
+	if !ok {
+		return
+	}
+	changeID, err := strconv.ParseInt(mux.Vars(r)["changeId"], 10, 32)
+	if err != nil || changeID < 1 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid price change ID", "Price change ID must be a positive integer")
+		return
+	}
+
+	if err := h.history.Cancel(productID, int32(changeID), time.Now()); err != nil {
+		respondWithPriceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// product loads the product, writing a 404 (or 500) response if it cannot
+func (h *PriceHandler) product(w http.ResponseWriter, productID int32) (*models.Product, bool) {
+	product, err := h.products.Get(productID)
+	if err == store.ErrProductNotFound {
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Product not found", "No product exists with the given ID")
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Internal server error", err.Error())
+		return nil, false
+	}
+	return product, true
+}
+
+// respondWithPriceError maps price history errors to HTTP responses
+func respondWithPriceError(w http.ResponseWriter, err error) {
+	switch err {
+	case store.ErrPriceChangeNotFound:
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Price change not found", "The product has no price change with the given ID")
+	case store.ErrPriceChangeFinal:
+		respondWithError(w, http.StatusConflict, "CONFLICT",
+			"Price change already in effect", err.Error())
+	case store.ErrPriceNotFuture:
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid price change", err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to save price change", err.Error())
+	}
+}
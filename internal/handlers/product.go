@@ -4,21 +4,79 @@ import (
 	"CS6650_Online_Store/internal/models"
 	"CS6650_Online_Store/internal/store"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// Search page size limits
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
 type ProductHandler struct {
-	store *store.ProductStore
+	store store.ProductRepository
+
+	// Default search strategy when the request doesn't pick one
+	searchStrategy string
+
+	// Per-field relevance boosts applied to every search
+	searchBoosts store.SearchBoosts
+
+	// Whether updates of existing products must carry If-Match
+	requireIfMatch bool
+
+	// Canonical brand and manufacturer names; nil leaves names as sent
+	brands, manufacturers *store.BrandRegistry
+
+	// Price timelines; nil means prices are not tracked
+	prices *store.PriceHistory
+
+	// Categories products must belong to; nil accepts any category
+	categories models.CategoryResolver
 }
 
-// NewProductHandler creates a new product handler with circuit breaker protection
-func NewProductHandler(store *store.ProductStore) *ProductHandler {
-	return &ProductHandler{store: store}
+// NewProductHandler creates a new product handler backed by any ProductRepository
+func NewProductHandler(repo store.ProductRepository) *ProductHandler {
+	handler := &ProductHandler{store: repo, searchBoosts: store.DefaultSearchBoosts, requireIfMatch: true}
+
+	// SEARCH_STRATEGY lets load-test deployments default to the bounded scan
+	if strategy := os.Getenv("SEARCH_STRATEGY"); store.ValidSearchStrategy(strategy) {
+		handler.searchStrategy = strategy
+	}
+
+	// SEARCH_BOOSTS overrides field weights, e.g. "name=3,brand=2,category=1.5,description=1"
+	if spec := os.Getenv("SEARCH_BOOSTS"); spec != "" {
+		if boosts, err := store.ParseSearchBoosts(spec); err != nil {
+			log.Printf("Warning: ignoring SEARCH_BOOSTS: %v", err)
+		} else {
+			handler.searchBoosts = boosts
+		}
+	}
+
+	// REQUIRE_IF_MATCH=false lets legacy clients keep overwriting without an ETag
+	if value := os.Getenv("REQUIRE_IF_MATCH"); value != "" {
+		if require, err := strconv.ParseBool(value); err != nil {
+			log.Printf("Warning: ignoring REQUIRE_IF_MATCH: %v", err)
+		} else {
+			handler.requireIfMatch = require
+		}
+	}
+
+	return handler
 }
 
 // GetProduct handles GET /products/{productId}
@@ -36,7 +94,7 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get product from store
-	product, err := h.store.GetProduct(int32(productID))
+	product, err := h.store.Get(int32(productID))
 	if err != nil {
 		if err == store.ErrProductNotFound {
 			respondWithError(w, http.StatusNotFound, "NOT_FOUND",
@@ -48,11 +106,25 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A scheduled price that is due is served before the scheduler stores it.
+	// The stored version does not cover that price yet, so it is never a 304.
+	pendingPrice := h.effectivePrice(product)
+
+	// The version doubles as the ETag; a matching If-None-Match means the client's copy is current
+	etag := productETag(product.Version)
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && !pendingPrice && etagMatches(ifNoneMatch, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	// Return product
 	respondWithJSON(w, http.StatusOK, product)
 }
 
 // AddProductDetails handles POST /products/{productId}/details
+// Creates the product or replaces it. Replacing an existing product requires an
+// If-Match ETag (unless REQUIRE_IF_MATCH=false) and fails with 412 on a mismatch.
 func (h *ProductHandler) AddProductDetails(w http.ResponseWriter, r *http.Request) {
 	// Extract productId from URL
 	vars := mux.Vars(r)
@@ -91,39 +163,311 @@ func (h *ProductHandler) AddProductDetails(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Validate product data
-	if err := product.Validate(); err != nil {
+	if err := h.validate(&product); err != nil {
 		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
 			"Invalid product data", err.Error())
 		return
 	}
+	if !h.normalizeNames(w, &product) {
+		return
+	}
 
-	// Add or update product
-	if err := h.store.AddOrUpdateProduct(&product); err != nil {
+	// Updates of existing products are guarded by If-Match
+	var currentVersion int64
+	current, err := h.store.Get(product.ProductID)
+	if err == nil {
+		currentVersion = current.Version
+	} else if err != store.ErrProductNotFound {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Internal server error", err.Error())
+		return
+	}
+	if !h.checkIfMatch(w, r, currentVersion) {
+		return
+	}
+
+	// Add or update product. A create only lands while the product is still
+	// absent, and with If-Match an update only lands if nobody else got there first.
+	var saved *models.Product
+	if r.Header.Get("If-Match") != "" || currentVersion == 0 {
+		saved, err = h.store.CompareAndSwap(&product, currentVersion)
+		if err == store.ErrVersionConflict || err == store.ErrProductNotFound {
+			respondWithPreconditionFailed(w)
+			return
+		}
+	} else {
+		saved, err = h.overwriteProduct(&product, currentVersion)
+	}
+	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
 			"Failed to save product", err.Error())
 		return
 	}
+	w.Header().Set("ETag", productETag(saved.Version))
+	h.recordPrice(current, &product)
+	h.registerNames(saved)
 
 	// Return 204 No Content on success
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// SearchProducts handles GET /products/search?q={query}
-// This is the key endpoint for Homework 6 - searches exactly 100 products per request
-// Protected by circuit breaker to prevent cascade failures under high load
+// overwriteProduct stores product whatever version it has by then, starting
+// from version. Unlike Upsert it learns the version it wrote, for the ETag.
+func (h *ProductHandler) overwriteProduct(product *models.Product, version int64) (*models.Product, error) {
+	for {
+		saved, err := h.store.CompareAndSwap(product, version)
+		if err != store.ErrVersionConflict && err != store.ErrProductNotFound {
+			return saved, err
+		}
+
+		// Another write (or a delete) got in between; go again on top of it
+		current, err := h.store.Get(product.ProductID)
+		switch {
+		case err == nil:
+			version = current.Version
+		case err == store.ErrProductNotFound:
+			version = 0
+		default:
+			return nil, err
+		}
+	}
+}
+
+// CreateProduct handles POST /products - stores a new product under a server-assigned ID
+func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Failed to read request body", err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	var product models.Product
+	if err := json.Unmarshal(body, &product); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid JSON format", err.Error())
+		return
+	}
+
+	err = product.ValidateForCreate()
+	if err == nil {
+		err = h.validateCategory(&product)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid product data", err.Error())
+		return
+	}
+	if !h.normalizeNames(w, &product) {
+		return
+	}
+
+	created, err := h.store.Create(&product)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to save product", err.Error())
+		return
+	}
+	h.recordPrice(nil, created)
+	h.registerNames(created)
+
+	w.Header().Set("Location", fmt.Sprintf("/products/%d", created.ProductID))
+	w.Header().Set("ETag", productETag(created.Version))
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+// PatchProduct handles PATCH /products/{productId} with a JSON Merge Patch (RFC 7386).
+// The merged product is validated as a whole before it is saved. The save is a
+// compare-and-swap against the version the patch was applied to, so a
+// concurrent update is reported as 412 rather than silently overwritten.
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseProductID(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Failed to read request body", err.Error())
+		return
+	}
+	defer r.Body.Close()
+
+	current, err := h.store.Get(productID)
+	if err == store.ErrProductNotFound {
+		respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+			"Product not found", "No product exists with the given ID")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Internal server error", err.Error())
+		return
+	}
+
+	if !h.checkIfMatch(w, r, current.Version) {
+		return
+	}
+
+	merged, err := current.ApplyMergePatch(body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid merge patch", err.Error())
+		return
+	}
+
+	if merged.ProductID != productID {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Product ID mismatch", "product_id cannot be changed")
+		return
+	}
+
+	if err := h.validate(merged); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid product data", err.Error())
+		return
+	}
+	if !h.normalizeNames(w, merged) {
+		return
+	}
+
+	saved, err := h.store.CompareAndSwap(merged, current.Version)
+	if err == store.ErrVersionConflict || err == store.ErrProductNotFound {
+		respondWithPreconditionFailed(w)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to save product", err.Error())
+		return
+	}
+	h.recordPrice(current, saved)
+	h.registerNames(saved)
+
+	w.Header().Set("ETag", productETag(saved.Version))
+	respondWithJSON(w, http.StatusOK, saved)
+}
+
+// DeleteProduct handles DELETE /products/{productId} - retires a product from the catalog
+func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseProductID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Delete(productID); err != nil {
+		if err == store.ErrProductNotFound {
+			respondWithError(w, http.StatusNotFound, "NOT_FOUND",
+				"Product not found", "No product exists with the given ID")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to delete product", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchProducts handles GET /products/search?q={query}&strategy={index|scan}
+// The default index strategy searches the whole catalog through the inverted index
+// and supports limit, offset, cursor, sort={relevance|name|weight|product_id} and order={asc|desc}.
+// It also filters on category, brand, min_weight and max_weight (q is optional when
+// filtering) and returns facet counts per category and brand. Unknown query terms
+// are matched by prefix and edit distance unless fuzzy=false.
+// strategy=scan keeps the Homework 6 behavior - searches exactly 100 products per request
 func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
-	// Get query parameter
-	query := r.URL.Query().Get("q")
-	if query == "" {
+	params := r.URL.Query()
+
+	filter, err := parseSearchFilter(params)
+	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
-			"Missing query parameter", "Query parameter 'q' is required")
+			"Invalid filter", err.Error())
+		return
+	}
+	h.canonicalFilter(&filter)
+
+	strategy := params.Get("strategy")
+	if strategy == "" {
+		strategy = h.searchStrategy
+	}
+	if !store.ValidSearchStrategy(strategy) {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid search strategy", "Query parameter 'strategy' must be 'index' or 'scan'")
+		return
+	}
+
+	// Get query parameter; only filtered index searches may leave it out
+	query := params.Get("q")
+	if query == "" && (filter.IsEmpty() || strategy == store.SearchStrategyScan) {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Missing query parameter", "Query parameter 'q' is required unless a category, brand or weight filter is given")
+		return
+	}
+	if strategy == store.SearchStrategyScan && !filter.IsEmpty() {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid filter", "Filters are not supported with strategy=scan")
+		return
+	}
+
+	limit, err := parseIntParam(params, "limit", defaultSearchLimit)
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid limit", fmt.Sprintf("Query parameter 'limit' must be between 1 and %d", maxSearchLimit))
+		return
+	}
+
+	offset, err := parseIntParam(params, "offset", 0)
+	if err != nil || offset < 0 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid offset", "Query parameter 'offset' must be a non-negative integer")
+		return
+	}
+
+	fuzzy := true
+	if value := params.Get("fuzzy"); value != "" {
+		if fuzzy, err = strconv.ParseBool(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid fuzzy flag", "Query parameter 'fuzzy' must be 'true' or 'false'")
+			return
+		}
+	}
+
+	sortBy, order := params.Get("sort"), params.Get("order")
+	if !store.ValidSearchSort(sortBy) {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid sort", "Query parameter 'sort' must be one of relevance, name, weight, product_id")
+		return
+	}
+	if !store.ValidSearchOrder(order) {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid order", "Query parameter 'order' must be 'asc' or 'desc'")
 		return
 	}
 
 	// Record start time for performance measurement
 	startTime := time.Now()
 
-	searchResult, err := h.store.SearchProducts(query, 100, 20)
+	searchResult, err := h.store.Search(store.SearchRequest{
+		Query:      query,
+		Strategy:   strategy,
+		MaxCheck:   100,
+		MaxResults: limit,
+		Boosts:     h.searchBoosts,
+		Filter:     filter,
+		Fuzzy:      fuzzy,
+		Offset:     offset,
+		Cursor:     params.Get("cursor"),
+		Sort:       sortBy,
+		Order:      order,
+	})
+	if err == store.ErrInvalidCursor {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid cursor", "Cursor is malformed or was issued for a different sort order")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
 			"Search failed", err.Error())
@@ -142,6 +486,38 @@ func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request)
 	respondWithJSON(w, http.StatusOK, searchResult)
 }
 
+// SuggestProducts handles GET /products/suggest?prefix={prefix}&limit={n}
+// Returns the most common product name and brand completions of the prefix
+func (h *ProductHandler) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	prefix := strings.TrimSpace(params.Get("prefix"))
+	if prefix == "" {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Missing prefix parameter", "Query parameter 'prefix' is required")
+		return
+	}
+
+	limit, err := parseIntParam(params, "limit", defaultSuggestLimit)
+	if err != nil || limit < 1 || limit > maxSuggestLimit {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid limit", fmt.Sprintf("Query parameter 'limit' must be between 1 and %d", maxSuggestLimit))
+		return
+	}
+
+	suggestions, err := h.store.Suggest(prefix, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Suggest failed", err.Error())
+		return
+	}
+	if suggestions == nil {
+		suggestions = []models.Suggestion{}
+	}
+
+	respondWithJSON(w, http.StatusOK, models.SuggestResponse{Prefix: prefix, Suggestions: suggestions})
+}
+
 // HealthCheck handles GET /health - returns system health and circuit breaker status
 func (h *ProductHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	healthResponse := map[string]interface{}{
@@ -154,6 +530,122 @@ func (h *ProductHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 
 // Helper functions for responses
 
+// parseProductID reads the productId path variable, writing a 400 response if it is invalid
+func parseProductID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["productId"], 10, 32)
+	if err != nil || productID < 1 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid product ID", "Product ID must be a positive integer")
+		return 0, false
+	}
+	return int32(productID), true
+}
+
+// parseIntParam reads an optional integer query parameter
+func parseIntParam(params url.Values, name string, defaultValue int) (int, error) {
+	value := params.Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseSearchFilter reads the structured search filters. category, brand and
+// manufacturer may be repeated or comma-separated to match any of several values.
+func parseSearchFilter(params url.Values) (store.SearchFilter, error) {
+	filter := store.SearchFilter{
+		Categories:    listParam(params, "category"),
+		Brands:        listParam(params, "brand"),
+		Manufacturers: listParam(params, "manufacturer"),
+	}
+
+	for _, bound := range []struct {
+		name string
+		into **int32
+	}{
+		{"min_weight", &filter.MinWeight},
+		{"max_weight", &filter.MaxWeight},
+	} {
+		value := params.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		weight, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || weight < 0 {
+			return filter, fmt.Errorf("Query parameter '%s' must be a non-negative integer", bound.name)
+		}
+		w := int32(weight)
+		*bound.into = &w
+	}
+
+	if filter.MinWeight != nil && filter.MaxWeight != nil && *filter.MinWeight > *filter.MaxWeight {
+		return filter, fmt.Errorf("Query parameter 'min_weight' must not exceed 'max_weight'")
+	}
+	return filter, nil
+}
+
+// listParam collects a repeatable, comma-separated query parameter
+func listParam(params url.Values, name string) []string {
+	var values []string
+	for _, raw := range params[name] {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// LimitBody caps the request bodies next may read at maxBytes; reading past
+// the cap fails, and decodeStrict answers it with 413
+func LimitBody(maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next(w, r)
+	}
+}
+
+// decodeStrict parses a JSON body that may only hold known fields, writing a
+// 400 response if it is malformed (413 if it is over a LimitBody cap)
+func decodeStrict(w http.ResponseWriter, r *http.Request, into interface{}) bool {
+	defer r.Body.Close()
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(into); err != nil {
+		if !bodyTooLarge(w, err) {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid JSON format", err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// bodyTooLarge writes a 413 response if err comes from reading a body past its
+// LimitBody cap
+func bodyTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	respondWithError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE",
+		"Request body too large", fmt.Sprintf("The request body must be at most %d bytes", tooLarge.Limit))
+	return true
+}
+
+// respondWithValidationError writes a 400 response listing every invalid
+// field when err is a models.ValidationErrors
+func respondWithValidationError(w http.ResponseWriter, message string, err error) {
+	errorResponse := models.NewError("INVALID_INPUT", message, err.Error())
+	var fieldErrs models.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		errorResponse.Fields = fieldErrs
+	}
+	respondWithJSON(w, http.StatusBadRequest, errorResponse)
+}
+
 func respondWithJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
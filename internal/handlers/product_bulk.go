@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// importBatchSize is how many valid rows are written to the store at once
+	importBatchSize = 500
+
+	// exportFlushEvery flushes the export stream to the client this often
+	exportFlushEvery = 1000
+
+	// maxBatchGetSize caps the IDs one batch lookup may request
+	maxBatchGetSize = 200
+
+	// Listing page size limits
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// BatchGetProducts handles POST /products/batch-get with a body of {"ids": [...]}
+func (h *ProductHandler) BatchGetProducts(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	var req models.BatchGetRequest
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid JSON format", err.Error())
+		return
+	}
+
+	h.batchGet(w, req.IDs)
+}
+
+// ListProducts handles GET /products. With ids=1,2,3 it is the query string
+// form of batch-get; otherwise it browses the catalog in product_id order,
+// filtered by category, brand, manufacturer and weight range, a page at a
+// time: pass the response's next_after as after to continue.
+func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if params.Has("ids") {
+		h.batchGetFromQuery(w, listParam(params, "ids"))
+		return
+	}
+
+	req, err := parseListRequest(params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid listing parameters", err.Error())
+		return
+	}
+	h.canonicalFilter(&req.Filter)
+
+	response, err := h.store.List(req)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to list products", err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// parseListRequest reads the listing filters, limit and after cursor
+func parseListRequest(params url.Values) (store.ListRequest, error) {
+	filter, err := parseSearchFilter(params)
+	if err != nil {
+		return store.ListRequest{}, err
+	}
+
+	limit, err := parseIntParam(params, "limit", defaultListLimit)
+	if err != nil || limit < 1 || limit > maxListLimit {
+		return store.ListRequest{}, fmt.Errorf("Query parameter 'limit' must be between 1 and %d", maxListLimit)
+	}
+
+	var after int64
+	if value := params.Get("after"); value != "" {
+		if after, err = strconv.ParseInt(value, 10, 32); err != nil || after < 0 {
+			return store.ListRequest{}, errors.New("Query parameter 'after' must be a non-negative product ID")
+		}
+	}
+
+	return store.ListRequest{Filter: filter, After: int32(after), Limit: limit}, nil
+}
+
+// batchGetFromQuery parses the ids query parameter for batchGet
+func (h *ProductHandler) batchGetFromQuery(w http.ResponseWriter, values []string) {
+	ids := make([]int32, len(values))
+	for i, value := range values {
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid product ID", fmt.Sprintf("Product ID %q must be a positive integer", value))
+			return
+		}
+		ids[i] = int32(id)
+	}
+
+	h.batchGet(w, ids)
+}
+
+// batchGet looks up to maxBatchGetSize products at once. Repeated IDs are
+// answered once; unknown IDs are listed in missing rather than failing the
+// whole request.
+func (h *ProductHandler) batchGet(w http.ResponseWriter, ids []int32) {
+	if len(ids) == 0 {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Missing product IDs", "At least one product ID is required")
+		return
+	}
+
+	seen := make(map[int32]bool, len(ids))
+	unique := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		if err := models.ValidateProductID(id); err != nil {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid product ID", fmt.Sprintf("Product ID %d must be a positive integer", id))
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > maxBatchGetSize {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Too many product IDs", fmt.Sprintf("A batch may request at most %d products, got %d", maxBatchGetSize, len(unique)))
+		return
+	}
+
+	products, missing, err := h.store.GetMany(unique)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"Failed to look up products", err.Error())
+		return
+	}
+
+	response := models.BatchGetResponse{Products: make([]models.Product, len(products)), Missing: missing}
+	for i, product := range products {
+		response.Products[i] = *product
+	}
+	if response.Missing == nil {
+		response.Missing = []int32{}
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// ImportProducts handles POST /products/import?format={jsonl|csv}&dry_run={true|false}
+// The body is streamed one record at a time. Every row is validated with
+// Product.Validate; invalid rows are reported and skipped while the rest are
+// written in batches. With dry_run=true nothing is written and the report
+// lists what each row would change. Imports are bulk admin loads, so they
+// replace products without If-Match. Only the batch being filled is held in
+// memory; a dry run, whose report lists every row anyway, keeps every product
+// it has read so repeated IDs compare against the earlier row.
+func (h *ProductHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	format, err := bulkFormat(r.URL.Query().Get("format"), r.Header.Get("Content-Type"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid format", err.Error())
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+				"Invalid dry_run flag", "Query parameter 'dry_run' must be 'true' or 'false'")
+			return
+		}
+	}
+
+	reader, err := models.NewProductReader(format, r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid import file", err.Error())
+		return
+	}
+
+	report := &models.ImportReport{DryRun: dryRun, Rows: []models.ImportRow{}}
+	pending := make(map[int32]*models.Product) // unwritten rows win over the store for repeated IDs
+	var batch, batchPrevious []*models.Product
+	var batchRows []models.ImportRow
+	spellings := make(newSpellings)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := h.store.UpsertBatch(batch); err != nil {
+			for _, row := range batchRows {
+				row.Action, row.Error = models.ImportFailed, "save failed: "+err.Error()
+				report.Record(row)
+			}
+		} else {
+			for i, row := range batchRows {
+				report.Record(row)
+				h.recordPrice(batchPrevious[i], batch[i])
+			}
+			h.registerNames(batch...)
+		}
+		batch, batchPrevious, batchRows = batch[:0], batchPrevious[:0], batchRows[:0]
+		// The store and the registries are current for these rows again,
+		// whether or not the write failed
+		clear(pending)
+		clear(spellings)
+	}
+
+	for {
+		product, line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		report.Total++
+
+		if err != nil {
+			var recordErr *models.RecordError
+			if !errors.As(err, &recordErr) {
+				report.Record(models.ImportRow{Line: line, Action: models.ImportFailed, Error: err.Error()})
+				break // the stream itself is broken
+			}
+			report.Record(models.ImportRow{Line: line, Action: models.ImportFailed, Error: recordErr.Err.Error()})
+			continue
+		}
+
+		row := models.ImportRow{Line: line, ProductID: product.ProductID}
+		product.Version = 0 // assigned by the store
+		if err := h.validate(product); err != nil {
+			row.Action, row.Error = models.ImportFailed, err.Error()
+			report.Record(row)
+			continue
+		}
+		if err := h.canonicalNames(product, spellings); err != nil {
+			row.Action, row.Error = models.ImportFailed, err.Error()
+			report.Record(row)
+			continue
+		}
+
+		var previous *models.Product
+		row.Action, previous = h.importAction(product, pending)
+		if row.Action != models.ImportUnchanged {
+			pending[product.ProductID] = product
+		}
+		if dryRun || row.Action == models.ImportUnchanged {
+			report.Record(row)
+			continue
+		}
+
+		batch = append(batch, product)
+		batchPrevious = append(batchPrevious, previous)
+		batchRows = append(batchRows, row)
+		if len(batch) >= importBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// importAction decides whether a valid row creates, updates or leaves a
+// product unchanged, and returns the product the row replaces (nil for a new one)
+func (h *ProductHandler) importAction(product *models.Product, pending map[int32]*models.Product) (string, *models.Product) {
+	current, seen := pending[product.ProductID]
+	if !seen {
+		stored, err := h.store.Get(product.ProductID)
+		if err != nil {
+			return models.ImportCreated, nil
+		}
+		stored.Version = 0
+		current = stored
+	}
+
+	if *current == *product {
+		return models.ImportUnchanged, current
+	}
+	return models.ImportUpdated, current
+}
+
+// ExportProducts handles GET /products/export?format={jsonl|csv}
+// Products are streamed straight from the store as they are scanned, so the
+// catalog is never held in memory as a whole.
+func (h *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := bulkFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "INVALID_INPUT",
+			"Invalid format", err.Error())
+		return
+	}
+
+	contentType := "application/x-ndjson"
+	if format == models.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="products.`+format+`"`)
+
+	writer, err := models.NewProductWriter(format, w)
+	if err != nil {
+		log.Printf("Export failed to start: %v", err)
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+
+	written := 0
+	var writeErr error
+	scanErr := h.store.Scan(func(product *models.Product) bool {
+		if writeErr = writer.Write(product); writeErr != nil {
+			return false
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			if writeErr = writer.Flush(); writeErr != nil {
+				return false
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return true
+	})
+	if writeErr == nil {
+		writeErr = writer.Flush()
+	}
+
+	// The status line has already gone out, so failures can only be logged
+	if scanErr != nil || writeErr != nil {
+		log.Printf("Export aborted after %d products: scan=%v write=%v", written, scanErr, writeErr)
+	}
+}
+
+// bulkFormat picks jsonl or csv from an explicit format parameter, falling
+// back to a Content-Type or Accept header, and defaulting to jsonl
+func bulkFormat(param, header string) (string, error) {
+	switch strings.ToLower(param) {
+	case models.FormatJSONL, "ndjson":
+		return models.FormatJSONL, nil
+	case models.FormatCSV:
+		return models.FormatCSV, nil
+	case "":
+	default:
+		return "", errors.New("Query parameter 'format' must be 'jsonl' or 'csv'")
+	}
+
+	for _, part := range strings.Split(header, ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == "text/csv" {
+			return models.FormatCSV, nil
+		}
+	}
+	return models.FormatJSONL, nil
+}
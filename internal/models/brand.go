@@ -0,0 +1,44 @@
+package models
+
+import "strings"
+
+// Brand is a canonical brand or manufacturer name with the other spellings
+// that resolve to it
+type Brand struct {
+	ID      int32    `json:"id"`
+	Name    string   `json:"name"`    // canonical spelling stored on products
+	Aliases []string `json:"aliases"` // alternative names, matched case-insensitively
+}
+
+// BrandListResponse represents the response format for the brand and manufacturer lists
+type BrandListResponse struct {
+	Brands []Brand `json:"brands"` // Ordered by name
+}
+
+// BrandAliasRequest is the body of POST /brands/{id}/aliases
+type BrandAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+// BrandMergeRequest is the body of POST /brands/{id}/merge
+type BrandMergeRequest struct {
+	From []int32 `json:"from"` // brands folded into the target and removed
+}
+
+// BrandMergeResponse reports a merge
+type BrandMergeResponse struct {
+	Brand           Brand `json:"brand"`            // the target, with the merged names as aliases
+	ProductsUpdated int   `json:"products_updated"` // products moved to the canonical name
+}
+
+// CleanName trims a brand or manufacturer name and collapses runs of
+// whitespace, so "  Alpha   Corp " becomes "Alpha Corp"
+func CleanName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NameKey is the form brand and manufacturer names are compared in:
+// cleaned and lowercased, so "alpha", "Alpha " and "ALPHA" share a key
+func NameKey(name string) string {
+	return strings.ToLower(CleanName(name))
+}
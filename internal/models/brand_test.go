@@ -0,0 +1,20 @@
+package models
+
+import "testing"
+
+func TestNameKey(t *testing.T) {
+	tests := map[string]string{
+		"alpha":           "alpha",
+		"Alpha ":          "alpha",
+		"ALPHA":           "alpha",
+		"  Alpha   Corp ": "alpha corp",
+	}
+	for name, want := range tests {
+		if got := NameKey(name); got != want {
+			t.Errorf("NameKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := CleanName("  Alpha   Corp "); got != "Alpha Corp" {
+		t.Errorf("CleanName() = %q, want %q", got, "Alpha Corp")
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Category is a node in the product category tree
+type Category struct {
+	ID       int32  `json:"id"`
+	Name     string `json:"name"`                // unique, case-insensitive; products refer to it by name
+	Slug     string `json:"slug"`                // unique URL-friendly name, derived from Name if omitted
+	ParentID int32  `json:"parent_id,omitempty"` // 0 for a top-level category
+}
+
+// CategoryNode is a category with its subcategories, for the tree view
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+// CategoryListResponse represents the response format for the category list
+type CategoryListResponse struct {
+	Categories []Category `json:"categories"` // Ordered by ID
+}
+
+// CategoryTreeResponse represents the response format for the category tree
+type CategoryTreeResponse struct {
+	Categories []CategoryNode `json:"categories"` // Top-level categories
+}
+
+// DefaultCategories are the categories every catalog starts with. Their IDs
+// match the category_id of the generated products.
+var DefaultCategories = []Category{
+	{ID: 1, Name: "Electronics", Slug: "electronics"},
+	{ID: 2, Name: "Books", Slug: "books"},
+	{ID: 3, Name: "Home", Slug: "home"},
+	{ID: 4, Name: "Sports", Slug: "sports"},
+	{ID: 5, Name: "Clothing", Slug: "clothing"},
+	{ID: 6, Name: "Beauty", Slug: "beauty"},
+	{ID: 7, Name: "Toys", Slug: "toys"},
+	{ID: 8, Name: "Automotive", Slug: "automotive"},
+}
+
+var (
+	slugPattern    = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Slugify derives a slug from a category name, e.g. "Home & Garden" -> "home-garden"
+func Slugify(name string) string {
+	return strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// Validate checks the category's own fields. Uniqueness and the parent are
+// checked by the category store.
+func (c *Category) Validate() error {
+	if len(strings.TrimSpace(c.Name)) < 1 || len(c.Name) > 100 {
+		return errors.New("name must be between 1 and 100 characters")
+	}
+	if len(c.Slug) < 1 || len(c.Slug) > 100 {
+		return errors.New("slug must be between 1 and 100 characters")
+	}
+	if !slugPattern.MatchString(c.Slug) {
+		return errors.New("slug must be lowercase letters and digits separated by single hyphens")
+	}
+	if c.ParentID < 0 {
+		return errors.New("parent_id must be a positive integer")
+	}
+	if c.ID != 0 && c.ParentID == c.ID {
+		return errors.New("a category cannot be its own parent")
+	}
+	return nil
+}
+
+// CategoryResolver looks up categories by name, case-insensitively
+type CategoryResolver interface {
+	CategoryByName(name string) (*Category, bool)
+}
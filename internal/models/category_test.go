@@ -0,0 +1,72 @@
+package models
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Electronics":       "electronics",
+		"Home & Garden":     "home-garden",
+		"  Kids' Toys 2 ":   "kids-toys-2",
+		"Sports--Outdoors!": "sports-outdoors",
+	}
+	for name, want := range tests {
+		if got := Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCategory_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		category Category
+		wantErr  bool
+	}{
+		{"valid", Category{ID: 9, Name: "Garden", Slug: "garden", ParentID: 3}, false},
+		{"missing name", Category{Slug: "garden"}, true},
+		{"missing slug", Category{Name: "Garden"}, true},
+		{"bad slug", Category{Name: "Garden", Slug: "Garden Tools"}, true},
+		{"own parent", Category{ID: 9, Name: "Garden", Slug: "garden", ParentID: 9}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.category.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// staticCategories resolves the default categories
+type staticCategories struct{}
+
+func (staticCategories) CategoryByName(name string) (*Category, bool) {
+	for _, c := range DefaultCategories {
+		if c.Name == name {
+			return &c, true
+		}
+	}
+	return nil, false
+}
+
+func TestProduct_ValidateCategory(t *testing.T) {
+	product := NewProduct(1, "Novel", "Books", "Acme", "")
+	if product.CategoryID != 2 {
+		t.Fatalf("Expected NewProduct to look up the Books ID (2), got %d", product.CategoryID)
+	}
+	if err := product.ValidateCategory(staticCategories{}); err != nil {
+		t.Errorf("Expected a known category to be valid, got %v", err)
+	}
+
+	product.CategoryID = 1
+	if err := product.ValidateCategory(staticCategories{}); err == nil {
+		t.Error("Expected a category_id that doesn't match the category to be rejected")
+	}
+
+	unknown := NewProduct(1, "Rake", "Garden", "Acme", "")
+	if unknown.CategoryID != 0 {
+		t.Errorf("Expected an unknown category to get ID 0 instead of a default, got %d", unknown.CategoryID)
+	}
+	unknown.CategoryID = 1
+	if err := unknown.ValidateCategory(staticCategories{}); err == nil {
+		t.Error("Expected an unknown category to be rejected")
+	}
+}
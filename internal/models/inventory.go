@@ -0,0 +1,108 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// Inventory is the stock of one product across all warehouses. Reserved
+// units are held by orders that have not been paid for yet; Available is
+// what new orders can take.
+type Inventory struct {
+	ProductID  int32        `json:"product_id"`
+	Tracked    bool         `json:"tracked"` // false until stock is first set; untracked products never run out
+	OnHand     int          `json:"on_hand"`
+	Reserved   int          `json:"reserved"`
+	Available  int          `json:"available"`  // OnHand - Reserved
+	Warehouses []StockLevel `json:"warehouses"` // per-warehouse breakdown, ordered by warehouse ID
+}
+
+// StockLevel is the stock of one product in one warehouse
+type StockLevel struct {
+	ProductID   int32  `json:"product_id"`
+	WarehouseID string `json:"warehouse_id"`
+	OnHand      int    `json:"on_hand"`
+	Reserved    int    `json:"reserved"`
+	Available   int    `json:"available"`
+}
+
+// InventoryUpdate is the body of PUT /products/{id}/inventory
+type InventoryUpdate struct {
+	OnHand      int    `json:"on_hand"`
+	WarehouseID string `json:"warehouse_id,omitempty"` // defaults to the main warehouse
+}
+
+// Location is a point on the map, used to find the warehouse nearest an order
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// DistanceKm returns the great-circle distance between two locations
+func (l Location) DistanceKm(other Location) float64 {
+	lat1, lat2 := l.Latitude*math.Pi/180, other.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (other.Longitude - l.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Validate checks that the coordinates are on the map
+func (l Location) Validate() error {
+	if l.Latitude < -90 || l.Latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if l.Longitude < -180 || l.Longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// Warehouse is a place stock is shipped from
+type Warehouse struct {
+	ID       string   `json:"id"` // short code, e.g. "us-west"
+	Name     string   `json:"name"`
+	Location Location `json:"location"`
+}
+
+// WarehouseListResponse represents the response format for the warehouse list
+type WarehouseListResponse struct {
+	Warehouses []Warehouse `json:"warehouses"` // Ordered by ID
+}
+
+// WarehouseStockResponse lists the stock held in one warehouse
+type WarehouseStockResponse struct {
+	WarehouseID string       `json:"warehouse_id"`
+	Stock       []StockLevel `json:"stock"` // Ordered by product ID
+}
+
+// Validate checks the warehouse's fields
+func (w *Warehouse) Validate() error {
+	if len(w.ID) < 1 || len(w.ID) > 50 || !slugPattern.MatchString(w.ID) {
+		return errors.New("id must be 1-50 lowercase letters and digits separated by single hyphens")
+	}
+	if len(strings.TrimSpace(w.Name)) < 1 || len(w.Name) > 100 {
+		return errors.New("name must be between 1 and 100 characters")
+	}
+	return w.Location.Validate()
+}
+
+// Allocation is the part of an order line shipped from one warehouse
+type Allocation struct {
+	ProductID   int32  `json:"product_id"`
+	WarehouseID string `json:"warehouse_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+// TransferRequest is the body of POST /inventory/transfers
+type TransferRequest struct {
+	ProductID int32  `json:"product_id"`
+	From      string `json:"from"` // warehouse IDs
+	To        string `json:"to"`
+	Quantity  int    `json:"quantity"`
+}
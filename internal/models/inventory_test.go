@@ -0,0 +1,38 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLocation_DistanceKm(t *testing.T) {
+	newYork := Location{Latitude: 40.7128, Longitude: -74.0060}
+	london := Location{Latitude: 51.5074, Longitude: -0.1278}
+
+	if d := newYork.DistanceKm(london); math.Abs(d-5570) > 10 {
+		t.Errorf("New York to London = %.0f km, want about 5570", d)
+	}
+	if d := london.DistanceKm(london); d != 0 {
+		t.Errorf("Distance to itself = %v, want 0", d)
+	}
+}
+
+func TestWarehouse_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		warehouse Warehouse
+		wantErr   bool
+	}{
+		{"valid", Warehouse{ID: "us-west", Name: "US West", Location: Location{Latitude: 45, Longitude: -122}}, false},
+		{"bad id", Warehouse{ID: "US West", Name: "US West"}, true},
+		{"missing name", Warehouse{ID: "us-west"}, true},
+		{"latitude off the map", Warehouse{ID: "north", Name: "North", Location: Location{Latitude: 91}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.warehouse.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ApplyMergePatch applies an RFC 7386 JSON Merge Patch to the product and
+// returns the merged copy. Members set to null are reset to their zero value
+// and members the patch leaves out are kept. Unknown fields are rejected.
+// The result is not validated.
+func (p *Product) ApplyMergePatch(patch []byte) (*Product, error) {
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	if _, ok := patchDoc.(map[string]interface{}); !ok {
+		return nil, errors.New("merge patch must be a JSON object")
+	}
+
+	current, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var target interface{}
+	if err := json.Unmarshal(current, &target); err != nil {
+		return nil, err
+	}
+
+	merged, err := json.Marshal(MergePatch(target, patchDoc))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	var result Product
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return &result, nil
+}
+
+// MergePatch implements the RFC 7386 algorithm on decoded JSON values
+func MergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+		} else {
+			targetObj[name] = MergePatch(targetObj[name], value)
+		}
+	}
+	return targetObj
+}
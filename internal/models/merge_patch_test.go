@@ -0,0 +1,56 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProduct_ApplyMergePatch(t *testing.T) {
+	original := Product{
+		ProductID: 7, SKU: "ABC", Manufacturer: "Acme", CategoryID: 1, Weight: 100, SomeOtherID: 1,
+		Name: "Radio", Category: "Electronics", Description: "Portable", Brand: "Alpha",
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    func(p *Product)
+		wantErr bool
+	}{
+		{"changes only the given fields", `{"name": "Clock Radio", "weight": 250}`, func(p *Product) { p.Name = "Clock Radio"; p.Weight = 250 }, false},
+		{"null resets a field", `{"description": null}`, func(p *Product) { p.Description = "" }, false},
+		{"empty patch is a no-op", `{}`, func(p *Product) {}, false},
+		{"unknown field", `{"colour": "red"}`, nil, true},
+		{"wrong type", `{"weight": "heavy"}`, nil, true},
+		{"not an object", `["name"]`, nil, true},
+		{"malformed", `{"name":`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := original.ApplyMergePatch([]byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyMergePatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			want := original
+			tt.want(&want)
+			if *got != want {
+				t.Errorf("ApplyMergePatch() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestMergePatch_Nested(t *testing.T) {
+	target := map[string]interface{}{"a": "b", "c": map[string]interface{}{"d": "e", "f": "g"}}
+	patch := map[string]interface{}{"a": "z", "c": map[string]interface{}{"f": nil}}
+
+	want := map[string]interface{}{"a": "z", "c": map[string]interface{}{"d": "e"}}
+	if got := MergePatch(target, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("MergePatch() = %v, want %v", got, want)
+	}
+}
@@ -1,13 +1,24 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"time"
 )
 
+const (
+	// MaxOrderItems is the most items one order may hold
+	MaxOrderItems = 100
+
+	// MaxItemQuantity is the most units of one item an order may ask for
+	MaxItemQuantity = 1000
+)
+
 // Item represents an item in an order
 type Item struct {
-	ProductID int    `json:"product_id"`
-	Quantity  int    `json:"quantity"`
+	ProductID int     `json:"product_id"`
+	Quantity  int     `json:"quantity"`
 	Price     float64 `json:"price"`
 }
 
@@ -15,14 +26,136 @@ type Item struct {
 type Order struct {
 	OrderID    string    `json:"order_id"`
 	CustomerID int       `json:"customer_id"`
-	Status     string    `json:"status"` // pending, processing, completed
+	Status     string    `json:"status"` // changed only through Transition
 	Items      []Item    `json:"items"`
 	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"` // last status change
+
+	// Every status the order has been in, oldest first
+	History []StatusChange `json:"history,omitempty"`
+
+	// Warehouse allocation: the client may give a destination and a strategy
+	// (nearest, most_stock or split); the server fills in Allocations
+	ShipTo      *Location    `json:"ship_to,omitempty"`
+	Allocation  string       `json:"allocation,omitempty"`
+	Allocations []Allocation `json:"allocations,omitempty"`
+
+	// Totals in dollars, computed by the server from the catalog prices
+	Subtotal float64 `json:"subtotal"`
+	Tax      float64 `json:"tax"`
+	Total    float64 `json:"total"`
 }
 
-// OrderStatus constants
+// OrderStatus constants; see orderTransitions for the moves between them
 const (
 	StatusPending    = "pending"
 	StatusProcessing = "processing"
 	StatusCompleted  = "completed"
+	StatusFailed     = "failed" // the order could not be queued or paid for
+	StatusShipped    = "shipped"
+	StatusCancelled  = "cancelled"
+	StatusRefunding  = "refunding" // a refund was requested and may not have been paid out yet
+	StatusRefunded   = "refunded"
 )
+
+// Validate checks an item submitted with an order. Its price is replaced by
+// the catalog price, but a negative one is still rejected.
+func (i *Item) Validate() error {
+	var errs ValidationErrors
+
+	// product_id: minimum 1, maximum MaxInt32 (product IDs are int32)
+	if i.ProductID < 1 || i.ProductID > math.MaxInt32 {
+		errs.add("product_id", "must be between 1 and %d", math.MaxInt32)
+	}
+
+	// quantity: minimum 1, maximum MaxItemQuantity
+	if i.Quantity < 1 || i.Quantity > MaxItemQuantity {
+		errs.add("quantity", "must be between 1 and %d", MaxItemQuantity)
+	}
+
+	// price: minimum 0
+	if i.Price < 0 {
+		errs.add("price", "must be at least 0")
+	}
+
+	return errs.err()
+}
+
+// Validate checks an order submitted by a client. Every invalid field is
+// reported, as ValidationErrors, rather than only the first. Fields the
+// server fills in (status, timestamps, history, allocations and totals) must
+// be left out.
+func (o *Order) Validate() error {
+	var errs ValidationErrors
+
+	// order_id: optional, maxLength 100
+	if len(o.OrderID) > 100 {
+		errs.add("order_id", "must be at most 100 characters")
+	}
+
+	// customer_id: minimum 1
+	if o.CustomerID < 1 {
+		errs.add("customer_id", "must be at least 1")
+	}
+
+	// items: minItems 1, maxItems MaxOrderItems
+	if len(o.Items) < 1 || len(o.Items) > MaxOrderItems {
+		errs.add("items", "must hold between 1 and %d items", MaxOrderItems)
+	}
+	for i := range o.Items {
+		var itemErrs ValidationErrors
+		if errors.As(o.Items[i].Validate(), &itemErrs) {
+			errs.nest(fmt.Sprintf("items[%d]", i), itemErrs)
+		}
+	}
+
+	if o.ShipTo != nil {
+		if err := o.ShipTo.Validate(); err != nil {
+			errs.add("ship_to", "%v", err)
+		}
+	}
+
+	serverSet := []struct {
+		field string
+		set   bool
+	}{
+		{"status", o.Status != ""},
+		{"created_at", !o.CreatedAt.IsZero()},
+		{"updated_at", !o.UpdatedAt.IsZero()},
+		{"history", o.History != nil},
+		{"allocations", o.Allocations != nil},
+		{"subtotal", o.Subtotal != 0},
+		{"tax", o.Tax != 0},
+		{"total", o.Total != 0},
+	}
+	for _, f := range serverSet {
+		if f.set {
+			errs.add(f.field, "is set by the server and must be omitted")
+		}
+	}
+
+	return errs.err()
+}
+
+// ApplyPrices replaces every item's price with the catalog price and
+// computes the totals. prices must hold every item's product.
+func (o *Order) ApplyPrices(prices map[int32]float64, taxRate float64) {
+	for i := range o.Items {
+		o.Items[i].Price = prices[int32(o.Items[i].ProductID)]
+	}
+	o.ComputeTotals(taxRate)
+}
+
+// ComputeTotals sets Subtotal, Tax and Total from the item prices. Amounts
+// are summed in whole cents and tax is rounded to the nearest cent.
+func (o *Order) ComputeTotals(taxRate float64) {
+	var subtotal int64
+	for _, item := range o.Items {
+		subtotal += ToCents(item.Price) * int64(item.Quantity)
+	}
+	tax := int64(math.Round(float64(subtotal) * taxRate))
+
+	o.Subtotal = FromCents(subtotal)
+	o.Tax = FromCents(tax)
+	o.Total = FromCents(subtotal + tax)
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidTransition is matched (with errors.Is) by every TransitionError
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// orderTransitions lists the statuses each status may move to. Statuses
+// without an entry are final.
+var orderTransitions = map[string][]string{
+	StatusPending:    {StatusProcessing, StatusCancelled, StatusFailed},
+	StatusProcessing: {StatusCompleted, StatusCancelled, StatusFailed},
+	StatusCompleted:  {StatusShipped, StatusRefunding},
+	StatusShipped:    {StatusRefunding},
+	StatusRefunding:  {StatusRefunded},
+}
+
+// StatusChange is one entry in an order's status history
+type StatusChange struct {
+	From   string    `json:"from,omitempty"` // empty for the status the order was created in
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// TransitionError reports a status change the state machine does not allow
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("order cannot move from %s to %s", e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// ValidOrderStatus reports whether s is an order status
+func ValidOrderStatus(s string) bool {
+	switch s {
+	case StatusPending, StatusProcessing, StatusCompleted, StatusFailed,
+		StatusShipped, StatusCancelled, StatusRefunding, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Begin starts a new order in the pending status
+func (o *Order) Begin(at time.Time) {
+	o.Status = StatusPending
+	o.CreatedAt = at
+	o.UpdatedAt = at
+	o.History = []StatusChange{{To: StatusPending, At: at}}
+}
+
+// Transition moves the order to a new status and records the change in its
+// history. Changes the state machine does not allow return a TransitionError
+// and leave the order as it was.
+func (o *Order) Transition(to string, at time.Time, reason string) error {
+	if !CanTransition(o.Status, to) {
+		return &TransitionError{From: o.Status, To: to}
+	}
+	o.History = append(o.History, StatusChange{From: o.Status, To: to, At: at, Reason: reason})
+	o.Status = to
+	o.UpdatedAt = at
+	return nil
+}
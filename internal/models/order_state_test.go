@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusProcessing, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusPending, StatusCompleted, false},
+		{StatusProcessing, StatusCompleted, true},
+		{StatusProcessing, StatusFailed, true},
+		{StatusCompleted, StatusShipped, true},
+		{StatusCompleted, StatusRefunding, true},
+		{StatusCompleted, StatusRefunded, false},
+		{StatusCompleted, StatusPending, false},
+		{StatusCompleted, StatusCancelled, false},
+		{StatusShipped, StatusRefunding, true},
+		{StatusRefunding, StatusRefunded, true},
+		{StatusRefunding, StatusCompleted, false},
+		{StatusFailed, StatusProcessing, false},
+		{StatusCancelled, StatusPending, false},
+		{StatusRefunded, StatusCompleted, false},
+		{StatusPending, StatusPending, false},
+		{"", StatusPending, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestOrder_Transition(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var order Order
+	order.Begin(start)
+
+	if order.Status != StatusPending || !order.CreatedAt.Equal(start) || len(order.History) != 1 {
+		t.Fatalf("Expected a pending order with one history entry, got %+v", order)
+	}
+
+	paid := start.Add(time.Minute)
+	if err := order.Transition(StatusProcessing, paid, ""); err != nil {
+		t.Fatalf("Transition(processing) error = %v", err)
+	}
+	if err := order.Transition(StatusCompleted, paid, "payment verified"); err != nil {
+		t.Fatalf("Transition(completed) error = %v", err)
+	}
+
+	err := order.Transition(StatusPending, paid, "")
+	var transitionErr *TransitionError
+	if !errors.Is(err, ErrInvalidTransition) || !errors.As(err, &transitionErr) || transitionErr.From != StatusCompleted {
+		t.Errorf("Expected a TransitionError from completed, got %v", err)
+	}
+
+	want := []StatusChange{
+		{To: StatusPending, At: start},
+		{From: StatusPending, To: StatusProcessing, At: paid},
+		{From: StatusProcessing, To: StatusCompleted, At: paid, Reason: "payment verified"},
+	}
+	if len(order.History) != len(want) {
+		t.Fatalf("Expected %d history entries, got %+v", len(want), order.History)
+	}
+	for i := range want {
+		if order.History[i] != want[i] {
+			t.Errorf("History[%d] = %+v, want %+v", i, order.History[i], want[i])
+		}
+	}
+	if order.Status != StatusCompleted || !order.UpdatedAt.Equal(paid) {
+		t.Errorf("Expected a completed order updated at %v, got %s at %v", paid, order.Status, order.UpdatedAt)
+	}
+}
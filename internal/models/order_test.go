@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func validOrder() Order {
+	return Order{
+		CustomerID: 42,
+		Items:      []Item{{ProductID: 1, Quantity: 2, Price: 19.99}, {ProductID: 5, Quantity: 1}},
+	}
+}
+
+func TestItem_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    Item
+		wantErr bool
+	}{
+		{"valid item", Item{ProductID: 1, Quantity: 3, Price: 9.99}, false},
+		{"price may be omitted", Item{ProductID: 1, Quantity: 1}, false},
+		{"invalid product_id (zero)", Item{ProductID: 0, Quantity: 1}, true},
+		{"invalid product_id (above int32)", Item{ProductID: 1 << 32, Quantity: 1}, true},
+		{"invalid quantity (zero)", Item{ProductID: 1, Quantity: 0}, true},
+		{"invalid quantity (negative)", Item{ProductID: 1, Quantity: -2}, true},
+		{"invalid quantity (too many)", Item{ProductID: 1, Quantity: MaxItemQuantity + 1}, true},
+		{"invalid price (negative)", Item{ProductID: 1, Quantity: 1, Price: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.item.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrder_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *Order)
+		wantErr bool
+	}{
+		{"valid order", func(o *Order) {}, false},
+		{"client order_id and ship_to", func(o *Order) {
+			o.OrderID = "order-1"
+			o.ShipTo = &Location{Latitude: 47.6, Longitude: -122.3}
+		}, false},
+		{"invalid customer_id (zero)", func(o *Order) { o.CustomerID = 0 }, true},
+		{"no items", func(o *Order) { o.Items = nil }, true},
+		{"too many items", func(o *Order) {
+			o.Items = make([]Item, MaxOrderItems+1)
+			for i := range o.Items {
+				o.Items[i] = Item{ProductID: i + 1, Quantity: 1}
+			}
+		}, true},
+		{"invalid item", func(o *Order) { o.Items[1].Quantity = 0 }, true},
+		{"invalid ship_to", func(o *Order) { o.ShipTo = &Location{Latitude: 91} }, true},
+		{"order_id too long", func(o *Order) { o.OrderID = string(make([]byte, 101)) }, true},
+		{"client status", func(o *Order) { o.Status = StatusCompleted }, true},
+		{"client created_at", func(o *Order) { o.CreatedAt = time.Now() }, true},
+		{"client total", func(o *Order) { o.Total = 0.01 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := validOrder()
+			tt.modify(&order)
+			err := order.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrder_ValidateListsEveryViolation(t *testing.T) {
+	order := Order{
+		Status: StatusCompleted,
+		Items:  []Item{{ProductID: 1, Quantity: 1}, {ProductID: 0, Quantity: -1, Price: -5}},
+	}
+
+	var errs ValidationErrors
+	if !errors.As(order.Validate(), &errs) {
+		t.Fatalf("Expected ValidationErrors, got %v", order.Validate())
+	}
+	var fields []string
+	for _, fieldErr := range errs {
+		fields = append(fields, fieldErr.Field)
+	}
+	want := []string{"customer_id", "items[1].product_id", "items[1].quantity", "items[1].price", "status"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Expected errors for %v, got %v", want, fields)
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// MaxPrice is the highest price a product may have
+const MaxPrice = 1000000.00
+
+// ValidatePrice checks that a price is a whole number of cents between 0 and MaxPrice
+func ValidatePrice(price float64) error {
+	if math.IsNaN(price) || price < 0 || price > MaxPrice {
+		return errors.New("price must be between 0 and 1000000")
+	}
+	if math.Abs(price*100-math.Round(price*100)) > 1e-6 {
+		return errors.New("price must not have fractions of a cent")
+	}
+	return nil
+}
+
+// ToCents converts a dollar amount to whole cents. Order totals are summed in
+// cents so they never pick up floating-point drift.
+func ToCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromCents converts whole cents back to dollars
+func FromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// Price change statuses
+const (
+	// PriceScheduled changes have not taken effect yet
+	PriceScheduled = "scheduled"
+
+	// PriceApplied changes are (or were) the product's catalog price
+	PriceApplied = "applied"
+
+	// PriceSuperseded changes were overtaken by a later price before the
+	// catalog was updated to them, so they never took effect
+	PriceSuperseded = "superseded"
+)
+
+// PriceChange is one entry in a product's price timeline: the price the
+// product costs from EffectiveFrom until the next applied change
+type PriceChange struct {
+	ID            int32     `json:"id"`
+	ProductID     int32     `json:"product_id"`
+	Price         float64   `json:"price"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PriceScheduleRequest is the body of POST /products/{id}/prices
+type PriceScheduleRequest struct {
+	Price         float64   `json:"price"`
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// Validate checks the price and that a start time was given
+func (r PriceScheduleRequest) Validate() error {
+	if err := ValidatePrice(r.Price); err != nil {
+		return err
+	}
+	if r.EffectiveFrom.IsZero() {
+		return errors.New("effective_from is required")
+	}
+	return nil
+}
+
+// PriceTimelineResponse is the response of GET /products/{id}/prices
+type PriceTimelineResponse struct {
+	ProductID    int32         `json:"product_id"`
+	CurrentPrice float64       `json:"current_price"`
+	Prices       []PriceChange `json:"prices"` // oldest first, including scheduled changes
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestValidatePrice(t *testing.T) {
+	tests := []struct {
+		price   float64
+		wantErr bool
+	}{
+		{0, false},
+		{0.01, false},
+		{19.99, false},
+		{MaxPrice, false},
+		{-0.01, true},
+		{MaxPrice + 0.01, true},
+		{1.999, true},
+		{math.NaN(), true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidatePrice(tt.price); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePrice(%v) error = %v, wantErr %v", tt.price, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOrder_ApplyPrices(t *testing.T) {
+	order := Order{Items: []Item{
+		{ProductID: 1, Quantity: 3, Price: 1000}, // client prices are ignored
+		{ProductID: 2, Quantity: 1},
+	}}
+	order.ApplyPrices(map[int32]float64{1: 0.10, 2: 0.20}, 0.0825)
+
+	if order.Items[0].Price != 0.10 || order.Items[1].Price != 0.20 {
+		t.Errorf("Expected catalog prices on the items, got %+v", order.Items)
+	}
+	// 3 x 0.10 + 0.20 is exactly 0.50 in cents, with 4.125 cents of tax rounded down
+	if order.Subtotal != 0.50 || order.Tax != 0.04 || order.Total != 0.54 {
+		t.Errorf("Expected totals 0.50 + 0.04 = 0.54, got %v + %v = %v", order.Subtotal, order.Tax, order.Total)
+	}
+}
+
+func TestOrder_ComputeTotalsWithoutTax(t *testing.T) {
+	order := Order{Items: []Item{{ProductID: 1, Quantity: 7, Price: 19.99}}}
+	order.ComputeTotals(0)
+
+	if order.Subtotal != 139.93 || order.Tax != 0 || order.Total != 139.93 {
+		t.Errorf("Expected totals 139.93 + 0 = 139.93, got %v + %v = %v", order.Subtotal, order.Tax, order.Total)
+	}
+}
+
+func TestPriceScheduleRequest_Validate(t *testing.T) {
+	start := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+	if err := (PriceScheduleRequest{Price: 9.99, EffectiveFrom: start}).Validate(); err != nil {
+		t.Errorf("Expected a valid request, got %v", err)
+	}
+	if err := (PriceScheduleRequest{Price: 9.99}).Validate(); err == nil {
+		t.Error("Expected an error without effective_from")
+	}
+	if err := (PriceScheduleRequest{Price: -1, EffectiveFrom: start}).Validate(); err == nil {
+		t.Error("Expected an error for a negative price")
+	}
+}
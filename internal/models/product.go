@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // Product represents a product in the e-commerce system
@@ -21,20 +22,81 @@ type Product struct {
 	Category    string `json:"category"`
 	Description string `json:"description"`
 	Brand       string `json:"brand"`
+
+	// Price is the current selling price in dollars. Orders are charged this
+	// price, never one sent by the client; 0 means the product is unpriced and
+	// cannot be ordered, as are products stored before prices were added.
+	Price float64 `json:"price"`
+
+	// Version is assigned by the store: 1 on creation, incremented on every
+	// update. It is served as the ETag for optimistic concurrency.
+	Version int64 `json:"version,omitempty"`
 }
 
 // Error represents an API error response
 type Error struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Error   string       `json:"error"`
+	Message string       `json:"message"`
+	Details string       `json:"details,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"` // every invalid field of a rejected request
+}
+
+// SearchResult is a product returned by search along with its relevance score
+type SearchResult struct {
+	Product
+	Score float64 `json:"score"` // Higher is more relevant; 0 for unranked searches
+}
+
+// FacetCount is the number of matching products sharing a field value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets summarizes search matches per category and brand
+type SearchFacets struct {
+	Categories []FacetCount `json:"categories"`
+	Brands     []FacetCount `json:"brands"`
 }
 
 // SearchResponse represents the response format for product search
 type SearchResponse struct {
-	Products   []Product `json:"products"`              // Max 20 results
-	TotalFound int       `json:"total_found"`           // Total matches found
-	SearchTime string    `json:"search_time,omitempty"` // Optional search time
+	Products   []SearchResult `json:"products"`              // Max 20 results, best match first
+	TotalFound int            `json:"total_found"`           // Total matches found
+	SearchTime string         `json:"search_time,omitempty"` // Optional search time
+	NextCursor string         `json:"next_cursor,omitempty"` // Pass as cursor to fetch the next page
+	Facets     *SearchFacets  `json:"facets,omitempty"`      // Counts per category and brand
+}
+
+// Suggestion is an autocomplete completion for a typed prefix
+type Suggestion struct {
+	Text  string `json:"text"`  // Product name or brand as stored
+	Field string `json:"field"` // "name" or "brand"
+	Count int    `json:"count"` // Products with this name or brand
+}
+
+// SuggestResponse represents the response format for autocomplete
+type SuggestResponse struct {
+	Prefix      string       `json:"prefix"`
+	Suggestions []Suggestion `json:"suggestions"` // Most common first
+}
+
+// ProductListResponse is a page of products in product_id order
+type ProductListResponse struct {
+	Products  []Product `json:"products"`
+	NextAfter int32     `json:"next_after,omitempty"` // Pass as after to fetch the next page
+}
+
+// BatchGetRequest is the body of POST /products/batch-get
+type BatchGetRequest struct {
+	IDs []int32 `json:"ids"`
+}
+
+// BatchGetResponse holds the products found by a batch lookup, in the order
+// their IDs were requested, and the requested IDs that do not exist
+type BatchGetResponse struct {
+	Products []Product `json:"products"`
+	Missing  []int32   `json:"missing"`
 }
 
 // Validate checks if the product data is valid according to OpenAPI spec
@@ -44,6 +106,21 @@ func (p *Product) Validate() error {
 		return errors.New("product_id must be at least 1")
 	}
 
+	return p.validateFields()
+}
+
+// ValidateForCreate checks a product submitted for creation. The server
+// assigns its ID, so product_id must be left out.
+func (p *Product) ValidateForCreate() error {
+	if p.ProductID != 0 {
+		return errors.New("product_id is assigned by the server and must be omitted")
+	}
+
+	return p.validateFields()
+}
+
+// validateFields checks everything except product_id
+func (p *Product) validateFields() error {
 	// sku: minLength 1, maxLength 100
 	if len(p.SKU) < 1 || len(p.SKU) > 100 {
 		return errors.New("sku must be between 1 and 100 characters")
@@ -90,6 +167,24 @@ func (p *Product) Validate() error {
 		return errors.New("brand must be between 1 and 100 characters")
 	}
 
+	// price: whole cents, from 0 (unpriced) up to MaxPrice
+	if err := ValidatePrice(p.Price); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateCategory checks that the product's category is one of categories,
+// referenced by its own ID
+func (p *Product) ValidateCategory(categories CategoryResolver) error {
+	category, ok := categories.CategoryByName(p.Category)
+	if !ok {
+		return fmt.Errorf("category %q does not exist", p.Category)
+	}
+	if p.CategoryID != category.ID {
+		return fmt.Errorf("category_id must be %d for category %q", category.ID, category.Name)
+	}
 	return nil
 }
 
@@ -132,21 +227,13 @@ func NewProduct(id int32, name, category, brand, description string) *Product {
 	}
 }
 
-// getCategoryID maps category names to IDs for consistency
+// getCategoryID maps a default category name to its ID. Unknown categories
+// get 0, which Validate rejects, instead of silently becoming Electronics.
 func getCategoryID(category string) int32 {
-	categoryMap := map[string]int32{
-		"Electronics": 1,
-		"Books":       2,
-		"Home":        3,
-		"Sports":      4,
-		"Clothing":    5,
-		"Beauty":      6,
-		"Toys":        7,
-		"Automotive":  8,
-	}
-
-	if id, exists := categoryMap[category]; exists {
-		return id
+	for _, c := range DefaultCategories {
+		if strings.EqualFold(c.Name, category) {
+			return c.ID
+		}
 	}
-	return 1 // Default to Electronics
+	return 0
 }
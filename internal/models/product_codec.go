@@ -0,0 +1,325 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Bulk formats for product import and export
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// maxJSONLLine bounds a single JSONL record
+const maxJSONLLine = 1 << 20
+
+// ProductCSVColumns is the CSV header written on export, named after the
+// Product JSON fields. Imports may list the columns in any order; version is
+// ignored on import because the store assigns it.
+var ProductCSVColumns = []string{
+	"product_id", "sku", "manufacturer", "category_id", "weight",
+	"some_other_id", "name", "category", "description", "brand", "price", "version",
+}
+
+// RecordError reports a malformed record. Reading can continue past it.
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// ProductReader decodes products one record at a time
+type ProductReader interface {
+	// Read returns the next product and the line it started on. At the end of
+	// input it returns io.EOF; for a malformed record it returns a *RecordError.
+	Read() (*Product, int, error)
+}
+
+// ProductWriter encodes products one record at a time
+type ProductWriter interface {
+	Write(product *Product) error
+
+	// Flush writes any buffered records to the underlying writer
+	Flush() error
+}
+
+// NewProductReader returns a reader for the given format
+func NewProductReader(format string, r io.Reader) (ProductReader, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLProductReader(r), nil
+	case FormatCSV:
+		return newCSVProductReader(r)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// NewProductWriter returns a writer for the given format
+func NewProductWriter(format string, w io.Writer) (ProductWriter, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLProductWriter(w), nil
+	case FormatCSV:
+		return newCSVProductWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// jsonlProductReader reads one JSON object per line, skipping blank lines
+type jsonlProductReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLProductReader(r io.Reader) *jsonlProductReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLLine)
+	return &jsonlProductReader{scanner: scanner}
+}
+
+func (r *jsonlProductReader) Read() (*Product, int, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		var product Product
+		if err := decoder.Decode(&product); err != nil {
+			return nil, r.line, &RecordError{Line: r.line, Err: err}
+		}
+		if decoder.More() {
+			return nil, r.line, &RecordError{Line: r.line, Err: errors.New("unexpected data after JSON object")}
+		}
+		return &product, r.line, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		// A line over the limit cannot be skipped, so this ends the import
+		return nil, r.line + 1, err
+	}
+	return nil, r.line, io.EOF
+}
+
+// csvProductReader maps CSV columns onto Product fields by header name
+type csvProductReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func newCSVProductReader(r io.Reader) (*csvProductReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // checked per record so one bad row doesn't stop the import
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv input is empty: expected a header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+
+	known := make(map[string]bool, len(ProductCSVColumns))
+	for _, column := range ProductCSVColumns {
+		known[column] = true
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate csv column %q", name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	if !seen["product_id"] {
+		return nil, errors.New("csv header must include product_id")
+	}
+
+	return &csvProductReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvProductReader) Read() (*Product, int, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	line, _ := r.reader.FieldPos(0)
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, parseErr.StartLine, &RecordError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return nil, line, err
+	}
+	if len(record) != len(r.columns) {
+		return nil, line, &RecordError{Line: line, Err: fmt.Errorf("expected %d fields, got %d", len(r.columns), len(record))}
+	}
+
+	var product Product
+	for i, value := range record {
+		if err := setProductField(&product, r.columns[i], value); err != nil {
+			return nil, line, &RecordError{Line: line, Err: err}
+		}
+	}
+	return &product, line, nil
+}
+
+// setProductField parses one CSV value into the named field
+func setProductField(p *Product, column, value string) error {
+	var number *int32
+	switch column {
+	case "product_id":
+		number = &p.ProductID
+	case "category_id":
+		number = &p.CategoryID
+	case "weight":
+		number = &p.Weight
+	case "some_other_id":
+		number = &p.SomeOtherID
+	case "sku":
+		p.SKU = value
+	case "manufacturer":
+		p.Manufacturer = value
+	case "name":
+		p.Name = value
+	case "category":
+		p.Category = value
+	case "description":
+		p.Description = value
+	case "brand":
+		p.Brand = value
+	case "price":
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("price must be a number, got %q", value)
+		}
+		p.Price = price
+	case "version":
+		// Assigned by the store
+	}
+
+	if number != nil {
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", column, value)
+		}
+		*number = int32(n)
+	}
+	return nil
+}
+
+// jsonlProductWriter writes one JSON object per line
+type jsonlProductWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newJSONLProductWriter(w io.Writer) *jsonlProductWriter {
+	writer := bufio.NewWriter(w)
+	return &jsonlProductWriter{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+func (w *jsonlProductWriter) Write(product *Product) error {
+	return w.encoder.Encode(product)
+}
+
+func (w *jsonlProductWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+// csvProductWriter writes ProductCSVColumns, header first
+type csvProductWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVProductWriter(w io.Writer) (*csvProductWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ProductCSVColumns); err != nil {
+		return nil, err
+	}
+	return &csvProductWriter{writer: writer, record: make([]string, len(ProductCSVColumns))}, nil
+}
+
+func (w *csvProductWriter) Write(p *Product) error {
+	w.record = append(w.record[:0],
+		strconv.Itoa(int(p.ProductID)), p.SKU, p.Manufacturer, strconv.Itoa(int(p.CategoryID)), strconv.Itoa(int(p.Weight)),
+		strconv.Itoa(int(p.SomeOtherID)), p.Name, p.Category, p.Description, p.Brand,
+		strconv.FormatFloat(p.Price, 'f', 2, 64), strconv.FormatInt(p.Version, 10))
+	return w.writer.Write(w.record)
+}
+
+func (w *csvProductWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// Import row outcomes
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportFailed    = "failed"
+)
+
+// ImportRow is the outcome of importing one record
+type ImportRow struct {
+	Line      int    `json:"line"`
+	ProductID int32  `json:"product_id,omitempty"`
+	Action    string `json:"action"` // created, updated, unchanged or failed
+	Error     string `json:"error,omitempty"`
+}
+
+// ImportReport summarizes a bulk import. Rows lists every failed record, and
+// in a dry run every record with the change it would make.
+type ImportReport struct {
+	DryRun    bool        `json:"dry_run"`
+	Total     int         `json:"total"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Failed    int         `json:"failed"`
+	Rows      []ImportRow `json:"rows"`
+}
+
+// Record counts a row outcome, keeping it in Rows if it failed or this is a dry run
+func (r *ImportReport) Record(row ImportRow) {
+	switch row.Action {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportUnchanged:
+		r.Unchanged++
+	case ImportFailed:
+		r.Failed++
+	}
+	if r.DryRun || row.Action == ImportFailed {
+		r.Rows = append(r.Rows, row)
+	}
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProductCodec_RoundTrip(t *testing.T) {
+	products := []*Product{
+		{ProductID: 1, SKU: "A-1", Manufacturer: "Acme", CategoryID: 1, Weight: 100, SomeOtherID: 1,
+			Name: "Radio, portable", Category: "Electronics", Description: "Says \"hello\"\non two lines", Brand: "Alpha", Price: 19.99, Version: 3},
+		{ProductID: 2, SKU: "B-2", Manufacturer: "Acme", CategoryID: 2, Weight: 0, SomeOtherID: 2,
+			Name: "Book", Category: "Books", Brand: "Beta", Version: 1},
+	}
+
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewProductWriter(format, &buf)
+			if err != nil {
+				t.Fatalf("NewProductWriter() error = %v", err)
+			}
+			for _, p := range products {
+				if err := writer.Write(p); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			reader, err := NewProductReader(format, &buf)
+			if err != nil {
+				t.Fatalf("NewProductReader() error = %v", err)
+			}
+			for _, want := range products {
+				got, _, err := reader.Read()
+				if err != nil {
+					t.Fatalf("Read() error = %v", err)
+				}
+				want := *want
+				if format == FormatCSV {
+					want.Version = 0 // not imported from CSV
+				}
+				if *got != want {
+					t.Errorf("Expected %+v, got %+v", want, *got)
+				}
+			}
+			if _, _, err := reader.Read(); err != io.EOF {
+				t.Errorf("Expected io.EOF after the last record, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProductReader_BadRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		input     string
+		wantLines []int // lines of rows that fail, in order
+		wantOK    int
+	}{
+		{"jsonl", FormatJSONL,
+			"{\"product_id\": 1}\n\n{\"product_id\": \"x\"}\n{\"colour\": \"red\"}\n{\"product_id\": 4}\n",
+			[]int{3, 4}, 2},
+		{"csv", FormatCSV,
+			"Product_ID,name\n1,Radio\nx,Clock\n3\n4,\"Lamp\n",
+			[]int{3, 4, 5}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewProductReader(tt.format, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("NewProductReader() error = %v", err)
+			}
+
+			var failed []int
+			ok := 0
+			for {
+				_, line, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				var recordErr *RecordError
+				switch {
+				case errors.As(err, &recordErr):
+					failed = append(failed, line)
+				case err != nil:
+					t.Fatalf("Read() unexpected error = %v", err)
+				default:
+					ok++
+				}
+			}
+
+			if ok != tt.wantOK || len(failed) != len(tt.wantLines) {
+				t.Fatalf("Expected %d good rows and failures on %v, got %d and %v", tt.wantOK, tt.wantLines, ok, failed)
+			}
+			for i := range failed {
+				if failed[i] != tt.wantLines[i] {
+					t.Errorf("Expected failures on lines %v, got %v", tt.wantLines, failed)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestProductReader_CSVHeader(t *testing.T) {
+	for _, header := range []string{"", "product_id,colour", "product_id,name,name", "name,brand"} {
+		if _, err := NewProductReader(FormatCSV, strings.NewReader(header)); err == nil {
+			t.Errorf("Expected header %q to be rejected", header)
+		}
+	}
+
+	if _, err := NewProductReader("xml", strings.NewReader("")); err == nil {
+		t.Error("Expected an unsupported format to be rejected")
+	}
+}
@@ -188,3 +188,24 @@ func TestValidateProductID(t *testing.T) {
 		})
 	}
 }
+
+func TestProduct_ValidateForCreate(t *testing.T) {
+	product := Product{
+		SKU: "ABC123", Manufacturer: "Test Manufacturer", CategoryID: 1, Weight: 100, SomeOtherID: 1,
+		Name: "Test Product", Category: "Electronics", Brand: "TestBrand",
+	}
+	if err := product.ValidateForCreate(); err != nil {
+		t.Errorf("Expected a product without ID to be valid for create, got %v", err)
+	}
+
+	product.ProductID = 5
+	if err := product.ValidateForCreate(); err == nil {
+		t.Error("Expected an error when the client picks the product_id")
+	}
+
+	product.ProductID = 0
+	product.SKU = ""
+	if err := product.ValidateForCreate(); err == nil {
+		t.Error("Expected other fields to still be validated")
+	}
+}
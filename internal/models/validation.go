@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with one field of a request
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. "items[2].quantity"
+	Message string `json:"message"`
+}
+
+// ValidationErrors lists every invalid field of a request, so a client can fix
+// them all at once
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add records a problem with a field
+func (e *ValidationErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// nest records the problems of a nested value under its field
+func (e *ValidationErrors) nest(field string, nested ValidationErrors) {
+	for _, fieldErr := range nested {
+		e.add(field+"."+fieldErr.Field, "%s", fieldErr.Message)
+	}
+}
+
+// err returns nil when no problem was recorded, so callers never get a
+// non-nil error holding an empty list
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
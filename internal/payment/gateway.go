@@ -0,0 +1,107 @@
+package payment
+
+import (
+	"errors"
+	"time"
+)
+
+// chargeRetention is how long a charge or refund is remembered so a retried
+// charge or refund of the same order is not made twice, like a processor's
+// idempotency window
+const chargeRetention = 24 * time.Hour
+
+// ErrInvalidAmount is returned for charges and refunds of a negative amount
+var ErrInvalidAmount = errors.New("payment amount must not be negative")
+
+// Gateway is the payment processor orders are charged and refunded through
+type Gateway interface {
+	// Charge takes payment for an order, blocking until the processor answers.
+	// Charging an order that was already charged succeeds without taking
+	// payment again, so a redelivered or retried order is never charged twice.
+	Charge(orderID string, amount float64) error
+
+	// Refund returns the payment for an order to the customer. Refunding an
+	// order that was already refunded succeeds without paying out again.
+	Refund(orderID string, amount float64) error
+}
+
+// SimulatedGateway stands in for a real payment processor: it handles one
+// request at a time and each takes a fixed delay, which makes it the
+// bottleneck of order processing
+type SimulatedGateway struct {
+	slot  chan struct{}
+	delay time.Duration
+
+	// When each order was charged and refunded; only touched while holding slot
+	charged   map[string]time.Time
+	refunded  map[string]time.Time
+	lastSweep time.Time
+}
+
+var _ Gateway = (*SimulatedGateway)(nil)
+
+// NewSimulatedGateway creates a gateway that takes delay per request
+func NewSimulatedGateway(delay time.Duration) *SimulatedGateway {
+	return &SimulatedGateway{
+		// Buffer size of 1 means only 1 payment can process at a time
+		slot:     make(chan struct{}, 1),
+		delay:    delay,
+		charged:  make(map[string]time.Time),
+		refunded: make(map[string]time.Time),
+	}
+}
+
+// Charge implements Gateway
+func (g *SimulatedGateway) Charge(orderID string, amount float64) error {
+	return g.process(amount, func() bool { return g.once(g.charged, orderID) })
+}
+
+// Refund implements Gateway
+func (g *SimulatedGateway) Refund(orderID string, amount float64) error {
+	return g.process(amount, func() bool { return g.once(g.refunded, orderID) })
+}
+
+// once records orderID in done, reporting whether it was not there yet.
+// Callers hold slot.
+func (g *SimulatedGateway) once(done map[string]time.Time, orderID string) bool {
+	now := time.Now()
+	g.sweep(now)
+	if _, ok := done[orderID]; ok {
+		return false
+	}
+	done[orderID] = now
+	return true
+}
+
+// process waits for the processor to be free and then for the request to
+// complete. take reports whether the request does any work; it runs while
+// the processor is held.
+func (g *SimulatedGateway) process(amount float64, take func() bool) error {
+	if amount < 0 {
+		return ErrInvalidAmount
+	}
+
+	g.slot <- struct{}{} // blocks while another request is in progress
+	defer func() { <-g.slot }()
+
+	if take() {
+		time.Sleep(g.delay)
+	}
+	return nil
+}
+
+// sweep forgets charges and refunds older than chargeRetention, at most
+// once per retention period. Callers hold slot.
+func (g *SimulatedGateway) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < chargeRetention {
+		return
+	}
+	g.lastSweep = now
+	for _, done := range []map[string]time.Time{g.charged, g.refunded} {
+		for orderID, at := range done {
+			if now.Sub(at) >= chargeRetention {
+				delete(done, orderID)
+			}
+		}
+	}
+}
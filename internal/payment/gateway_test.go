@@ -0,0 +1,72 @@
+package payment
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSimulatedGateway_ChargesAnOrderOnce(t *testing.T) {
+	g := NewSimulatedGateway(10 * time.Millisecond)
+
+	// Redeliveries of the same order race each other
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := g.Charge("order-1", 25); err != nil {
+				t.Errorf("Charge() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(g.charged) != 1 {
+		t.Errorf("Expected one charge to be recorded, got %d", len(g.charged))
+	}
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("Expected repeated charges to be skipped, took %v", elapsed)
+	}
+
+	if err := g.Charge("order-2", 10); err != nil {
+		t.Fatalf("Charge() error = %v", err)
+	}
+	if len(g.charged) != 2 {
+		t.Errorf("Expected a different order to be charged, got %d charges", len(g.charged))
+	}
+	if err := g.Charge("order-3", -1); err != ErrInvalidAmount {
+		t.Errorf("Expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestSimulatedGateway_ForgetsOldCharges(t *testing.T) {
+	g := NewSimulatedGateway(0)
+	g.charged["old"] = time.Now().Add(-chargeRetention)
+	g.lastSweep = time.Now().Add(-chargeRetention)
+
+	if err := g.Charge("new", 10); err != nil {
+		t.Fatalf("Charge() error = %v", err)
+	}
+	if _, ok := g.charged["old"]; ok || len(g.charged) != 1 {
+		t.Errorf("Expected charges past the retention window to be swept, got %v", g.charged)
+	}
+}
+
+func TestSimulatedGateway_RefundsAnOrderOnce(t *testing.T) {
+	g := NewSimulatedGateway(10 * time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := g.Refund("order-1", 25); err != nil {
+			t.Fatalf("Refund() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+		t.Errorf("Expected repeated refunds to be skipped, took %v", elapsed)
+	}
+	if len(g.refunded) != 1 || len(g.charged) != 0 {
+		t.Errorf("Expected one refund and no charge recorded, got %v and %v", g.refunded, g.charged)
+	}
+}
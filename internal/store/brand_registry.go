@@ -0,0 +1,440 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// brandNormalizeBatch is how many products are read at a time when names are normalized
+const brandNormalizeBatch = 1000
+
+var (
+	ErrBrandNotFound = errors.New("brand not found")
+	ErrBrandExists   = errors.New("name is already a brand or alias")
+	ErrBrandName     = errors.New("name must be between 1 and 100 characters")
+	ErrBrandAlias    = errors.New("alias not found")
+	ErrBrandMerge    = errors.New("a brand cannot be merged into itself")
+)
+
+// BrandRegistry maps every spelling of a brand (or manufacturer) to one
+// canonical name. Names are matched by models.NameKey, so case and stray
+// whitespace never create a new brand. Unknown names are registered on first
+// use. When opened with a file path, every change rewrites that file, so bulk
+// writers register their new names together with Register.
+type BrandRegistry struct {
+	mu    sync.RWMutex
+	byID  map[int32]*models.Brand
+	byKey map[string]int32 // NameKey of each canonical name and alias -> ID
+	maxID int32
+
+	path string // JSON file backing the registry, or "" for memory only
+}
+
+// NewBrandRegistry creates an empty in-memory registry
+func NewBrandRegistry() *BrandRegistry {
+	return &BrandRegistry{byID: make(map[int32]*models.Brand), byKey: make(map[string]int32)}
+}
+
+// OpenBrandRegistry loads a registry from path, starting empty if the file
+// does not exist yet, and saves every change back to it
+func OpenBrandRegistry(path string) (*BrandRegistry, error) {
+	r := NewBrandRegistry()
+	r.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read brands: %w", err)
+	}
+
+	var brands []models.Brand
+	if err := json.Unmarshal(data, &brands); err != nil {
+		return nil, fmt.Errorf("decode brands %s: %w", path, err)
+	}
+	for _, b := range brands {
+		r.putLocked(b)
+	}
+	return r, nil
+}
+
+// putLocked stores a brand and indexes its names. Callers hold r.mu.
+func (r *BrandRegistry) putLocked(b models.Brand) {
+	if b.Aliases == nil {
+		b.Aliases = []string{}
+	}
+	r.byID[b.ID] = &b
+	r.byKey[models.NameKey(b.Name)] = b.ID
+	for _, alias := range b.Aliases {
+		r.byKey[models.NameKey(alias)] = b.ID
+	}
+	r.maxID = max(r.maxID, b.ID)
+}
+
+// deleteLocked removes a brand and all of its names. Callers hold r.mu.
+func (r *BrandRegistry) deleteLocked(b *models.Brand) {
+	delete(r.byID, b.ID)
+	delete(r.byKey, models.NameKey(b.Name))
+	for _, alias := range b.Aliases {
+		delete(r.byKey, models.NameKey(alias))
+	}
+}
+
+func (r *BrandRegistry) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+	return writeJSONFile(r.path, r.listLocked())
+}
+
+// Canonical returns the canonical spelling of name, or the cleaned name if
+// it is not registered. It never registers anything.
+func (r *BrandRegistry) Canonical(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id, ok := r.byKey[models.NameKey(name)]; ok {
+		return r.byID[id].Name
+	}
+	return models.CleanName(name)
+}
+
+// Resolve returns the canonical spelling of name, registering the cleaned
+// name as a new brand if no brand or alias matches it
+func (r *BrandRegistry) Resolve(name string) (string, error) {
+	if canonical, ok := r.Lookup(name); ok {
+		return canonical, nil
+	}
+
+	created, err := r.Create(models.Brand{Name: name})
+	if err == ErrBrandExists {
+		// Registered concurrently under the same key
+		canonical, _ := r.Lookup(name)
+		return canonical, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return created.Name, nil
+}
+
+// Register adds every name that no brand or alias matches yet as a new
+// brand, in order, so the first spelling of a name wins, and saves the
+// registry once for all of them. An invalid name fails the whole call before
+// anything is registered.
+func (r *BrandRegistry) Register(names ...string) error {
+	cleaned := make([]string, 0, len(names))
+	for _, name := range names {
+		name = models.CleanName(name)
+		if len(name) < 1 || len(name) > 100 {
+			return fmt.Errorf("%q: %w", name, ErrBrandName)
+		}
+		cleaned = append(cleaned, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	maxID := r.maxID
+	var added []models.Brand
+	for _, name := range cleaned {
+		if _, taken := r.byKey[models.NameKey(name)]; taken {
+			continue
+		}
+		b := models.Brand{ID: r.maxID + 1, Name: name}
+		r.putLocked(b)
+		added = append(added, b)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if err := r.saveLocked(); err != nil {
+		for i := range added {
+			r.deleteLocked(&added[i])
+		}
+		r.maxID = maxID
+		return err
+	}
+	return nil
+}
+
+// Lookup returns the canonical spelling of name and whether any brand or
+// alias matches it. It never registers anything.
+func (r *BrandRegistry) Lookup(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byKey[models.NameKey(name)]
+	if !ok {
+		return "", false
+	}
+	return r.byID[id].Name, true
+}
+
+// Get returns a copy of the brand or ErrBrandNotFound
+func (r *BrandRegistry) Get(id int32) (*models.Brand, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	b, ok := r.byID[id]
+	if !ok {
+		return nil, ErrBrandNotFound
+	}
+	return copyBrand(b), nil
+}
+
+// List returns every brand ordered by canonical name
+func (r *BrandRegistry) List() []models.Brand {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.listLocked()
+}
+
+func (r *BrandRegistry) listLocked() []models.Brand {
+	brands := make([]models.Brand, 0, len(r.byID))
+	for _, b := range r.byID {
+		brands = append(brands, *copyBrand(b))
+	}
+	sort.Slice(brands, func(i, j int) bool {
+		if ki, kj := models.NameKey(brands[i].Name), models.NameKey(brands[j].Name); ki != kj {
+			return ki < kj
+		}
+		return brands[i].ID < brands[j].ID
+	})
+	return brands
+}
+
+// Create registers a brand under the next unused ID. Neither its name nor any
+// alias may already belong to another brand.
+func (r *BrandRegistry) Create(b models.Brand) (*models.Brand, error) {
+	b.Name = models.CleanName(b.Name)
+	if len(b.Name) < 1 || len(b.Name) > 100 {
+		return nil, ErrBrandName
+	}
+
+	names := []string{b.Name}
+	aliases := make([]string, 0, len(b.Aliases))
+	for _, alias := range b.Aliases {
+		alias = models.CleanName(alias)
+		if len(alias) < 1 || len(alias) > 100 {
+			return nil, ErrBrandName
+		}
+		if !containsKey(names, alias) {
+			names = append(names, alias)
+			aliases = append(aliases, alias)
+		}
+	}
+	b.Aliases = aliases
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		if _, taken := r.byKey[models.NameKey(name)]; taken {
+			return nil, ErrBrandExists
+		}
+	}
+
+	b.ID = r.maxID + 1
+	r.putLocked(b)
+	if err := r.saveLocked(); err != nil {
+		r.deleteLocked(&b)
+		return nil, err
+	}
+	return copyBrand(&b), nil
+}
+
+// AddAlias makes alias resolve to the brand
+func (r *BrandRegistry) AddAlias(id int32, alias string) (*models.Brand, error) {
+	alias = models.CleanName(alias)
+	if len(alias) < 1 || len(alias) > 100 {
+		return nil, ErrBrandName
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.byID[id]
+	if !ok {
+		return nil, ErrBrandNotFound
+	}
+	if owner, taken := r.byKey[models.NameKey(alias)]; taken {
+		if owner == id {
+			return copyBrand(b), nil
+		}
+		return nil, ErrBrandExists
+	}
+
+	updated := *copyBrand(b)
+	updated.Aliases = append(updated.Aliases, alias)
+	r.putLocked(updated)
+	if err := r.saveLocked(); err != nil {
+		delete(r.byKey, models.NameKey(alias))
+		r.putLocked(*b)
+		return nil, err
+	}
+	return copyBrand(&updated), nil
+}
+
+// RemoveAlias stops alias resolving to the brand
+func (r *BrandRegistry) RemoveAlias(id int32, alias string) (*models.Brand, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.byID[id]
+	if !ok {
+		return nil, ErrBrandNotFound
+	}
+
+	key := models.NameKey(alias)
+	updated := *copyBrand(b)
+	updated.Aliases = updated.Aliases[:0]
+	for _, existing := range b.Aliases {
+		if models.NameKey(existing) != key {
+			updated.Aliases = append(updated.Aliases, existing)
+		}
+	}
+	if len(updated.Aliases) == len(b.Aliases) {
+		return nil, ErrBrandAlias
+	}
+
+	delete(r.byKey, key)
+	r.putLocked(updated)
+	if err := r.saveLocked(); err != nil {
+		r.putLocked(*b)
+		return nil, err
+	}
+	return copyBrand(&updated), nil
+}
+
+// Merge folds the sources into the target brand: their canonical names and
+// aliases become aliases of the target, and the sources are removed. It
+// returns the updated target and the removed brands, whose names products
+// may still carry.
+func (r *BrandRegistry) Merge(targetID int32, sourceIDs []int32) (*models.Brand, []models.Brand, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	target, ok := r.byID[targetID]
+	if !ok {
+		return nil, nil, ErrBrandNotFound
+	}
+
+	var sources []*models.Brand
+	for _, id := range sourceIDs {
+		if id == targetID {
+			return nil, nil, ErrBrandMerge
+		}
+		source, ok := r.byID[id]
+		if !ok {
+			return nil, nil, ErrBrandNotFound
+		}
+		if !containsBrand(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+
+	merged := *copyBrand(target)
+	removed := make([]models.Brand, 0, len(sources))
+	for _, source := range sources {
+		merged.Aliases = append(merged.Aliases, source.Name)
+		merged.Aliases = append(merged.Aliases, source.Aliases...)
+		removed = append(removed, *copyBrand(source))
+	}
+
+	for _, source := range sources {
+		r.deleteLocked(source)
+	}
+	r.putLocked(merged)
+	if err := r.saveLocked(); err != nil {
+		// Put everything back as it was
+		r.deleteLocked(&merged)
+		r.putLocked(*target)
+		for _, source := range removed {
+			r.putLocked(source)
+		}
+		return nil, nil, err
+	}
+	return copyBrand(&merged), removed, nil
+}
+
+func copyBrand(b *models.Brand) *models.Brand {
+	brandCopy := *b
+	brandCopy.Aliases = append([]string{}, b.Aliases...)
+	return &brandCopy
+}
+
+func containsKey(names []string, name string) bool {
+	for _, existing := range names {
+		if models.NameKey(existing) == models.NameKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsBrand(brands []*models.Brand, b *models.Brand) bool {
+	for _, existing := range brands {
+		if existing.ID == b.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeProductNames registers the brand and manufacturer of every stored
+// product and rewrites the products whose names are not canonical, so search
+// and facets group each brand under one name. Products are visited in ID
+// order, so the first spelling of a new name becomes its canonical name. It
+// returns how many products were rewritten.
+func NormalizeProductNames(products ProductRepository, brands, manufacturers *BrandRegistry) (int, error) {
+	var stale []*models.Product
+	req := ListRequest{Limit: brandNormalizeBatch}
+	for {
+		page, err := products.List(req)
+		if err != nil {
+			return 0, err
+		}
+
+		// Register the page's new names with one save per registry
+		brandNames := make([]string, len(page.Products))
+		manufacturerNames := make([]string, len(page.Products))
+		for i, product := range page.Products {
+			brandNames[i], manufacturerNames[i] = product.Brand, product.Manufacturer
+		}
+		if err := brands.Register(brandNames...); err != nil {
+			return 0, fmt.Errorf("register brands: %w", err)
+		}
+		if err := manufacturers.Register(manufacturerNames...); err != nil {
+			return 0, fmt.Errorf("register manufacturers: %w", err)
+		}
+
+		for i := range page.Products {
+			product := &page.Products[i]
+			brand, manufacturer := brands.Canonical(product.Brand), manufacturers.Canonical(product.Manufacturer)
+			if brand != product.Brand || manufacturer != product.Manufacturer {
+				product.Brand, product.Manufacturer = brand, manufacturer
+				stale = append(stale, product)
+			}
+		}
+
+		if page.NextAfter == 0 {
+			break
+		}
+		req.After = page.NextAfter
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	if err := products.UpsertBatch(stale); err != nil {
+		return 0, err
+	}
+	return len(stale), nil
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestBrandRegistry_Resolve(t *testing.T) {
+	r := NewBrandRegistry()
+	for _, name := range []string{"Alpha ", "alpha", "ALPHA", "  alpha"} {
+		canonical, err := r.Resolve(name)
+		if err != nil {
+			t.Fatalf("Resolve(%q) error = %v", name, err)
+		}
+		if canonical != "Alpha" {
+			t.Errorf("Resolve(%q) = %q, want the first spelling seen", name, canonical)
+		}
+	}
+	if brands := r.List(); len(brands) != 1 {
+		t.Errorf("Expected one brand, got %+v", brands)
+	}
+
+	if got := r.Canonical("beta"); got != "beta" || len(r.List()) != 1 {
+		t.Errorf("Expected Canonical to leave unknown names unregistered, got %q", got)
+	}
+	if _, err := r.Resolve("   "); err != ErrBrandName {
+		t.Errorf("Expected a blank name to be rejected, got %v", err)
+	}
+}
+
+func TestBrandRegistry_Register(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brands.json")
+	r, err := OpenBrandRegistry(path)
+	if err != nil {
+		t.Fatalf("OpenBrandRegistry() error = %v", err)
+	}
+	r.Create(models.Brand{Name: "Alpha"})
+
+	if err := r.Register("alpha", "Beta ", "BETA", "Gamma"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	reopened, err := OpenBrandRegistry(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	brands := reopened.List()
+	if len(brands) != 3 || brands[1].Name != "Beta" || brands[1].ID != 2 || brands[2].ID != 3 {
+		t.Errorf("Expected Beta and Gamma saved under the next IDs, got %+v", brands)
+	}
+
+	if err := r.Register("Delta", "  "); !errors.Is(err, ErrBrandName) || len(r.List()) != 3 {
+		t.Errorf("Expected a blank name to fail the whole call, got %v", err)
+	}
+
+	// A failed save registers nothing and gives no IDs away
+	r.path = filepath.Join(t.TempDir(), "missing", "brands.json")
+	if err := r.Register("Delta", "Epsilon"); err == nil || len(r.List()) != 3 {
+		t.Errorf("Expected the failed save to be undone, got %v and %+v", err, r.List())
+	}
+	r.path = path
+	if created, _ := r.Create(models.Brand{Name: "Delta"}); created.ID != 4 {
+		t.Errorf("Expected numbering to continue at 4, got %+v", created)
+	}
+}
+
+func TestBrandRegistry_AliasesAndMerge(t *testing.T) {
+	r := NewBrandRegistry()
+	alpha, _ := r.Create(models.Brand{Name: "Alpha", Aliases: []string{"Alpha Inc"}})
+	beta, _ := r.Create(models.Brand{Name: "Beta"})
+
+	if _, err := r.Create(models.Brand{Name: "alpha inc"}); err != ErrBrandExists {
+		t.Errorf("Expected an alias to block a new brand, got %v", err)
+	}
+	if _, err := r.AddAlias(beta.ID, "ALPHA"); err != ErrBrandExists {
+		t.Errorf("Expected another brand's name to be refused as an alias, got %v", err)
+	}
+	if _, err := r.AddAlias(beta.ID, "Beta Co"); err != nil {
+		t.Fatalf("AddAlias() error = %v", err)
+	}
+	if got := r.Canonical("beta co"); got != "Beta" {
+		t.Errorf("Expected the alias to resolve to Beta, got %q", got)
+	}
+
+	if _, _, err := r.Merge(alpha.ID, []int32{alpha.ID}); err != ErrBrandMerge {
+		t.Errorf("Expected a self-merge to be rejected, got %v", err)
+	}
+	merged, removed, err := r.Merge(alpha.ID, []int32{beta.ID})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if len(removed) != 1 || removed[0].Name != "Beta" {
+		t.Errorf("Expected Beta to be removed, got %+v", removed)
+	}
+	if len(merged.Aliases) != 3 {
+		t.Errorf("Expected Beta and its alias to join Alpha's aliases, got %+v", merged.Aliases)
+	}
+	for _, name := range []string{"beta", "Beta Co", "Alpha Inc"} {
+		if got := r.Canonical(name); got != "Alpha" {
+			t.Errorf("Canonical(%q) = %q after merge, want Alpha", name, got)
+		}
+	}
+	if _, err := r.Get(beta.ID); err != ErrBrandNotFound {
+		t.Errorf("Expected the merged brand to be gone, got %v", err)
+	}
+
+	if _, err := r.RemoveAlias(alpha.ID, "BETA"); err != nil {
+		t.Fatalf("RemoveAlias() error = %v", err)
+	}
+	if got := r.Canonical("Beta"); got != "Beta" {
+		t.Errorf("Expected a removed alias to stop resolving, got %q", got)
+	}
+	if _, err := r.RemoveAlias(alpha.ID, "Gamma"); err != ErrBrandAlias {
+		t.Errorf("Expected ErrBrandAlias for an unknown alias, got %v", err)
+	}
+}
+
+func TestBrandRegistry_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brands.json")
+	r, err := OpenBrandRegistry(path)
+	if err != nil {
+		t.Fatalf("OpenBrandRegistry() error = %v", err)
+	}
+	r.Create(models.Brand{Name: "Alpha", Aliases: []string{"Alpha Inc"}})
+
+	reopened, err := OpenBrandRegistry(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if got := reopened.Canonical("alpha inc"); got != "Alpha" {
+		t.Errorf("Expected the alias to survive reopening, got %q", got)
+	}
+	if created, _ := reopened.Create(models.Brand{Name: "Beta"}); created.ID != 2 {
+		t.Errorf("Expected numbering to continue at 2, got %d", created.ID)
+	}
+}
+
+func TestNormalizeProductNames(t *testing.T) {
+	products := NewEmptyProductStore()
+	for i, brand := range []string{"Alpha", "alpha ", "ALPHA", "Beta"} {
+		p := testProduct(int32(i+1), "SKU")
+		p.Brand, p.Manufacturer = brand, brand
+		products.AddOrUpdateProduct(p)
+	}
+
+	brands, manufacturers := NewBrandRegistry(), NewBrandRegistry()
+	normalized, err := NormalizeProductNames(products, brands, manufacturers)
+	if err != nil {
+		t.Fatalf("NormalizeProductNames() error = %v", err)
+	}
+	if normalized != 2 {
+		t.Errorf("Expected the two misspelled products to be rewritten, got %d", normalized)
+	}
+	if len(brands.List()) != 2 || len(manufacturers.List()) != 2 {
+		t.Errorf("Expected Alpha and Beta in both registries, got %+v and %+v", brands.List(), manufacturers.List())
+	}
+
+	page, err := products.List(ListRequest{Filter: SearchFilter{Brands: []string{"Alpha"}}, Limit: 10})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Products) != 3 {
+		t.Errorf("Expected three Alpha products after normalizing, got %+v", page.Products)
+	}
+	for _, p := range page.Products {
+		if p.Brand != "Alpha" || p.Manufacturer != "Alpha" {
+			t.Errorf("Expected canonical names, got brand %q manufacturer %q", p.Brand, p.Manufacturer)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// categoryRegisterBatch is how many products are read at a time when their
+// categories are registered
+const categoryRegisterBatch = 1000
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("a category with this name or slug already exists")
+	ErrCategoryParent   = errors.New("parent category does not exist or would create a cycle")
+	ErrCategoryInUse    = errors.New("category has subcategories or products")
+)
+
+// CategoryStore holds the category tree in memory. When opened with a file
+// path, every change rewrites that file so the tree survives restarts.
+type CategoryStore struct {
+	mu     sync.RWMutex
+	byID   map[int32]*models.Category
+	byName map[string]int32 // lowercased name -> ID
+	bySlug map[string]int32
+	maxID  int32
+
+	path string // JSON file backing the store, or "" for memory only
+}
+
+// Compile-time check that categories can back product validation
+var _ models.CategoryResolver = (*CategoryStore)(nil)
+
+// NewCategoryStore creates an in-memory store holding models.DefaultCategories
+func NewCategoryStore() *CategoryStore {
+	s := newEmptyCategoryStore()
+	for _, c := range models.DefaultCategories {
+		s.putLocked(c)
+	}
+	return s
+}
+
+func newEmptyCategoryStore() *CategoryStore {
+	return &CategoryStore{
+		byID:   make(map[int32]*models.Category),
+		byName: make(map[string]int32),
+		bySlug: make(map[string]int32),
+	}
+}
+
+// OpenCategoryStore loads categories from path, or starts from the defaults
+// if the file does not exist yet, and saves every change back to it
+func OpenCategoryStore(path string) (*CategoryStore, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s := NewCategoryStore()
+		s.path = path
+		return s, s.saveLocked()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read categories: %w", err)
+	}
+
+	var categories []models.Category
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, fmt.Errorf("decode categories %s: %w", path, err)
+	}
+	s := newEmptyCategoryStore()
+	s.path = path
+	for _, c := range categories {
+		s.putLocked(c)
+	}
+	return s, nil
+}
+
+// putLocked stores a category and its lookup keys. Callers hold s.mu.
+func (s *CategoryStore) putLocked(c models.Category) {
+	if previous, ok := s.byID[c.ID]; ok {
+		delete(s.byName, strings.ToLower(previous.Name))
+		delete(s.bySlug, previous.Slug)
+	}
+	s.byID[c.ID] = &c
+	s.byName[strings.ToLower(c.Name)] = c.ID
+	s.bySlug[c.Slug] = c.ID
+	s.maxID = max(s.maxID, c.ID)
+}
+
+// removeLocked drops a category and its lookup keys. Callers hold s.mu.
+func (s *CategoryStore) removeLocked(c *models.Category) {
+	delete(s.byID, c.ID)
+	delete(s.byName, strings.ToLower(c.Name))
+	delete(s.bySlug, c.Slug)
+}
+
+// addLocked stores a new category and saves the tree, taking the category
+// back out if the save fails. Callers hold s.mu.
+func (s *CategoryStore) addLocked(c models.Category) error {
+	maxID := s.maxID
+	s.putLocked(c)
+	if err := s.saveLocked(); err != nil {
+		s.removeLocked(&c)
+		s.maxID = maxID
+		return err
+	}
+	return nil
+}
+
+// saveLocked atomically rewrites the backing file, if there is one. Callers hold s.mu.
+func (s *CategoryStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	return writeJSONFile(s.path, s.listLocked())
+}
+
+// Get returns a copy of the category or ErrCategoryNotFound
+func (s *CategoryStore) Get(id int32) (*models.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.byID[id]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	categoryCopy := *c
+	return &categoryCopy, nil
+}
+
+// CategoryByName implements models.CategoryResolver
+func (s *CategoryStore) CategoryByName(name string) (*models.Category, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.byName[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	categoryCopy := *s.byID[id]
+	return &categoryCopy, true
+}
+
+// List returns every category ordered by ID
+func (s *CategoryStore) List() []models.Category {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listLocked()
+}
+
+func (s *CategoryStore) listLocked() []models.Category {
+	categories := make([]models.Category, 0, len(s.byID))
+	for _, c := range s.byID {
+		categories = append(categories, *c)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories
+}
+
+// Tree returns the top-level categories with their subcategories nested
+func (s *CategoryStore) Tree() []models.CategoryNode {
+	categories := s.List()
+
+	children := make(map[int32][]models.Category)
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+	var build func(parentID int32) []models.CategoryNode
+	build = func(parentID int32) []models.CategoryNode {
+		nodes := make([]models.CategoryNode, 0, len(children[parentID]))
+		for _, c := range children[parentID] {
+			nodes = append(nodes, models.CategoryNode{Category: c, Children: build(c.ID)})
+		}
+		return nodes
+	}
+	return build(0)
+}
+
+// Descendants returns the category and every category below it
+func (s *CategoryStore) Descendants(id int32) ([]models.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	root, ok := s.byID[id]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+
+	children := make(map[int32][]*models.Category)
+	for _, c := range s.byID {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+	result := []models.Category{*root}
+	for i := 0; i < len(result); i++ {
+		for _, child := range children[result[i].ID] {
+			result = append(result, *child)
+		}
+	}
+	return result, nil
+}
+
+// Create stores a new category under the next unused ID. An empty slug is
+// derived from the name.
+func (s *CategoryStore) Create(c models.Category) (*models.Category, error) {
+	if c.Slug == "" {
+		c.Slug = models.Slugify(c.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.ID = s.maxID + 1
+	if err := s.checkLocked(c); err != nil {
+		return nil, err
+	}
+
+	if err := s.addLocked(c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Update replaces a category and returns the previous version, so callers
+// can follow a rename through the products that use it
+func (s *CategoryStore) Update(c models.Category) (updated, previous *models.Category, err error) {
+	if c.Slug == "" {
+		c.Slug = models.Slugify(c.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.byID[c.ID]
+	if !ok {
+		return nil, nil, ErrCategoryNotFound
+	}
+	if err := s.checkLocked(c); err != nil {
+		return nil, nil, err
+	}
+
+	old := *current
+	s.putLocked(c)
+	if err := s.saveLocked(); err != nil {
+		s.putLocked(old)
+		return nil, nil, err
+	}
+	return &c, &old, nil
+}
+
+// Delete removes a category that has no subcategories and that no product in
+// products refers to; products would otherwise fail validation on every
+// later update
+func (s *CategoryStore) Delete(id int32, products ProductRepository) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.byID[id]
+	if !ok {
+		return ErrCategoryNotFound
+	}
+	for _, other := range s.byID {
+		if other.ParentID == id {
+			return ErrCategoryInUse
+		}
+	}
+
+	// The category index answers this without walking the catalog
+	page, err := products.List(ListRequest{Filter: SearchFilter{Categories: []string{c.Name}}, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(page.Products) > 0 {
+		return ErrCategoryInUse
+	}
+
+	s.removeLocked(c)
+	if err := s.saveLocked(); err != nil {
+		s.putLocked(*c)
+		return err
+	}
+	return nil
+}
+
+// Register makes sure a category named name exists, creating it with the
+// given ID if possible (or the next free one). It is used at startup to adopt
+// categories that products already refer to.
+func (s *CategoryStore) Register(name string, id int32) (*models.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.byName[strings.ToLower(name)]; ok {
+		categoryCopy := *s.byID[existing]
+		return &categoryCopy, nil
+	}
+
+	c := models.Category{ID: id, Name: name, Slug: models.Slugify(name)}
+	if _, taken := s.byID[id]; taken || id < 1 {
+		c.ID = s.maxID + 1
+	}
+	if _, taken := s.bySlug[c.Slug]; taken {
+		c.Slug = fmt.Sprintf("%s-%d", c.Slug, c.ID)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("register category %q: %w", name, err)
+	}
+
+	if err := s.addLocked(c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// checkLocked validates c against the rest of the tree. Callers hold s.mu.
+func (s *CategoryStore) checkLocked(c models.Category) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if id, ok := s.byName[strings.ToLower(c.Name)]; ok && id != c.ID {
+		return ErrCategoryExists
+	}
+	if id, ok := s.bySlug[c.Slug]; ok && id != c.ID {
+		return ErrCategoryExists
+	}
+
+	// Walk up from the new parent; reaching c itself means a cycle
+	for parent := c.ParentID; parent != 0; {
+		if parent == c.ID {
+			return ErrCategoryParent
+		}
+		p, ok := s.byID[parent]
+		if !ok {
+			return ErrCategoryParent
+		}
+		parent = p.ParentID
+	}
+	return nil
+}
+
+// RegisterProductCategories registers every category name the stored
+// products use, keeping their category_id where it is free, and returns how
+// many categories were added. Products are read in ID order, so the spelling
+// and ID a category gets do not depend on how the store orders them.
+func (s *CategoryStore) RegisterProductCategories(products ProductRepository) (int, error) {
+	type productCategory struct {
+		name string
+		id   int32
+	}
+
+	// Each category once, as spelled by the product with the lowest ID
+	var found []productCategory
+	seen := make(map[string]bool)
+	req := ListRequest{Limit: categoryRegisterBatch}
+	for {
+		page, err := products.List(req)
+		if err != nil {
+			return 0, err
+		}
+		for _, product := range page.Products {
+			key := strings.ToLower(product.Category)
+			if !seen[key] {
+				seen[key] = true
+				found = append(found, productCategory{name: product.Category, id: product.CategoryID})
+			}
+		}
+		if page.NextAfter == 0 {
+			break
+		}
+		req.After = page.NextAfter
+	}
+
+	// Categories whose ID is free keep it before the clashing ones are
+	// numbered, so a new ID never takes one a later product refers to
+	before := len(s.List())
+	claimed := make(map[int32]bool)
+	var clashing []productCategory
+	for _, c := range found {
+		if _, err := s.Get(c.id); err == nil || claimed[c.id] || c.id < 1 {
+			clashing = append(clashing, c)
+			continue
+		}
+		claimed[c.id] = true
+		if _, err := s.Register(c.name, c.id); err != nil {
+			return len(s.List()) - before, err
+		}
+	}
+	for _, c := range clashing {
+		if _, err := s.Register(c.name, 0); err != nil {
+			return len(s.List()) - before, err
+		}
+	}
+	return len(s.List()) - before, nil
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCategoryStore_CRUD(t *testing.T) {
+	s := NewCategoryStore()
+	if len(s.List()) != len(models.DefaultCategories) {
+		t.Fatalf("Expected the default categories, got %+v", s.List())
+	}
+
+	garden, err := s.Create(models.Category{Name: "Garden", ParentID: 3})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if garden.ID != 9 || garden.Slug != "garden" {
+		t.Errorf("Expected ID 9 with a derived slug, got %+v", garden)
+	}
+
+	if _, err := s.Create(models.Category{Name: "garden"}); err != ErrCategoryExists {
+		t.Errorf("Expected a duplicate name to be rejected, got %v", err)
+	}
+	if _, err := s.Create(models.Category{Name: "Orphan", ParentID: 99}); err != ErrCategoryParent {
+		t.Errorf("Expected an unknown parent to be rejected, got %v", err)
+	}
+
+	// Moving Home under Garden would make Home its own ancestor
+	if _, _, err := s.Update(models.Category{ID: 3, Name: "Home", Slug: "home", ParentID: 9}); err != ErrCategoryParent {
+		t.Errorf("Expected a cycle to be rejected, got %v", err)
+	}
+
+	updated, previous, err := s.Update(models.Category{ID: 9, Name: "Garden & Patio", ParentID: 3})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if previous.Name != "Garden" || updated.Slug != "garden-patio" {
+		t.Errorf("Expected a rename from Garden with a new slug, got %+v from %+v", updated, previous)
+	}
+	if _, ok := s.CategoryByName("garden"); ok {
+		t.Error("Expected the old name to be released")
+	}
+	if c, ok := s.CategoryByName("GARDEN & PATIO"); !ok || c.ID != 9 {
+		t.Errorf("Expected case-insensitive lookup of the new name, got %+v", c)
+	}
+
+	products := NewEmptyProductStore()
+	if err := s.Delete(3, products); err != ErrCategoryInUse {
+		t.Errorf("Expected a category with subcategories to be kept, got %v", err)
+	}
+	if err := s.Delete(9, products); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(9); err != ErrCategoryNotFound {
+		t.Errorf("Expected ErrCategoryNotFound after delete, got %v", err)
+	}
+}
+
+func TestCategoryStore_DeleteKeepsCategoriesWithProducts(t *testing.T) {
+	s := NewCategoryStore()
+	products := NewEmptyProductStore()
+	p := testProduct(1, "SKU")
+	p.Category, p.CategoryID = "Beauty", 6
+	products.AddOrUpdateProduct(p)
+
+	if err := s.Delete(6, products); err != ErrCategoryInUse {
+		t.Errorf("Expected a category with products to be kept, got %v", err)
+	}
+	if _, err := s.Get(6); err != nil {
+		t.Errorf("Expected Beauty to still exist, got %v", err)
+	}
+
+	products.Delete(1)
+	if err := s.Delete(6, products); err != nil {
+		t.Errorf("Expected an unused category to be deleted, got %v", err)
+	}
+}
+
+func TestCategoryStore_Descendants(t *testing.T) {
+	s := NewCategoryStore()
+	garden, _ := s.Create(models.Category{Name: "Garden", ParentID: 3})
+	tools, _ := s.Create(models.Category{Name: "Garden Tools", ParentID: garden.ID})
+	s.Create(models.Category{Name: "Kitchen", ParentID: 3})
+
+	descendants, err := s.Descendants(garden.ID)
+	if err != nil {
+		t.Fatalf("Descendants() error = %v", err)
+	}
+	var names []string
+	for _, c := range descendants {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "Garden" || names[1] != tools.Name {
+		t.Errorf("Expected Garden and Garden Tools, got %v", names)
+	}
+
+	tree := s.Tree()
+	if len(tree) != len(models.DefaultCategories) || len(tree[2].Children) != 2 || len(tree[2].Children[0].Children) != 1 {
+		t.Errorf("Expected Home to hold Garden (with Garden Tools) and Kitchen, got %+v", tree[2])
+	}
+}
+
+func TestCategoryStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories.json")
+	s, err := OpenCategoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenCategoryStore() error = %v", err)
+	}
+	s.Create(models.Category{Name: "Garden", ParentID: 3})
+
+	reopened, err := OpenCategoryStore(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if c, ok := reopened.CategoryByName("Garden"); !ok || c.ParentID != 3 {
+		t.Errorf("Expected Garden under Home after reopening, got %+v", c)
+	}
+	if created, _ := reopened.Create(models.Category{Name: "Pets"}); created.ID != 10 {
+		t.Errorf("Expected numbering to continue at 10, got %d", created.ID)
+	}
+}
+
+// A change that cannot be saved must not stay in memory either
+func TestCategoryStore_FailedSavesAreUndone(t *testing.T) {
+	s := NewCategoryStore()
+	s.path = filepath.Join(t.TempDir(), "missing", "categories.json")
+	before := s.List()
+
+	if _, err := s.Create(models.Category{Name: "Garden"}); err == nil {
+		t.Error("Expected Create to fail")
+	}
+	if _, err := s.Register("Pets", 0); err == nil {
+		t.Error("Expected Register to fail")
+	}
+	if _, _, err := s.Update(models.Category{ID: 1, Name: "Gadgets"}); err == nil {
+		t.Error("Expected Update to fail")
+	}
+	if err := s.Delete(1, NewEmptyProductStore()); err == nil {
+		t.Error("Expected Delete to fail")
+	}
+
+	if after := s.List(); !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected the categories to be unchanged, got %+v", after)
+	}
+	for _, name := range []string{"Garden", "Pets", "Gadgets"} {
+		if _, ok := s.CategoryByName(name); ok {
+			t.Errorf("Expected %s not to be resolvable", name)
+		}
+	}
+	if s.maxID != int32(len(before)) {
+		t.Errorf("Expected the next ID to stay %d, got %d", len(before)+1, s.maxID+1)
+	}
+}
+
+func TestCategoryStore_RegisterProductCategories(t *testing.T) {
+	products := NewEmptyProductStore()
+	for i, category := range []string{"Books", "Garden", "garden", "Pets"} {
+		p := testProduct(int32(i+1), "SKU")
+		p.Category = category
+		p.CategoryID = int32(20 + i)
+		products.AddOrUpdateProduct(p)
+	}
+	clash := testProduct(9, "SKU")
+	clash.Category, clash.CategoryID = "Tools", 2 // ID already used by Books
+	products.AddOrUpdateProduct(clash)
+
+	// Games clashes and comes first, but must not take the ID Music refers to
+	games, music := testProduct(5, "SKU"), testProduct(6, "SKU")
+	games.Category, games.CategoryID = "Games", 2
+	music.Category, music.CategoryID = "Music", 24
+	products.AddOrUpdateProduct(games)
+	products.AddOrUpdateProduct(music)
+
+	s := NewCategoryStore()
+	added, err := s.RegisterProductCategories(products)
+	if err != nil {
+		t.Fatalf("RegisterProductCategories() error = %v", err)
+	}
+	if added != 5 {
+		t.Errorf("Expected Garden, Pets, Games, Music and Tools to be added, got %d", added)
+	}
+	if c, ok := s.CategoryByName("Pets"); !ok || c.ID != 23 {
+		t.Errorf("Expected Pets to keep its product category_id 23, got %+v", c)
+	}
+	if c, ok := s.CategoryByName("Music"); !ok || c.ID != 24 {
+		t.Errorf("Expected Music to keep its product category_id 24, got %+v", c)
+	}
+	if c, ok := s.CategoryByName("garden"); !ok || c.Name != "Garden" {
+		t.Errorf("Expected the lowest product ID's spelling Garden, got %+v", c)
+	}
+	if c, ok := s.CategoryByName("Tools"); !ok || c.ID == 2 {
+		t.Errorf("Expected Tools to get a fresh ID, got %+v", c)
+	}
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key comes back with
+// a different request than the one it was first used for
+var ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+
+// StoredResponse is the response recorded for an idempotency key, replayed
+// for every duplicate of the request
+type StoredResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// idempotencyEntry is one key: in flight until response is set, and done is
+// closed once the first request has finished either way
+type idempotencyEntry struct {
+	fingerprint string
+	response    *StoredResponse
+	expires     time.Time
+	done        chan struct{}
+}
+
+// IdempotencyStore remembers the response to each idempotency key for a TTL
+// after the request finished. Keys are held in memory, so duplicates are only
+// recognized by the server instance that saw the first request.
+type IdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+// NewIdempotencyStore creates a store that keeps responses for ttl
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		entries:   make(map[string]*idempotencyEntry),
+		ttl:       ttl,
+		lastSweep: time.Now(),
+	}
+}
+
+// Begin claims key for a request with the given fingerprint. It returns nil
+// when the caller is first and must handle the request, then report the
+// outcome with Complete or Abandon. A duplicate gets the recorded response,
+// waiting for it while the first request is still in flight (or until ctx is
+// done, returning ctx.Err()), and a key reused with another fingerprint fails
+// with ErrIdempotencyKeyReused.
+func (s *IdempotencyStore) Begin(ctx context.Context, key, fingerprint string) (*StoredResponse, error) {
+	for {
+		s.mu.Lock()
+		now := time.Now()
+		s.sweepLocked(now)
+
+		entry, ok := s.entries[key]
+		if ok && entry.response != nil && !now.Before(entry.expires) {
+			delete(s.entries, key)
+			ok = false
+		}
+		if !ok {
+			s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, done: make(chan struct{})}
+			s.mu.Unlock()
+			return nil, nil
+		}
+		if entry.fingerprint != fingerprint {
+			s.mu.Unlock()
+			return nil, ErrIdempotencyKeyReused
+		}
+		if entry.response != nil {
+			s.mu.Unlock()
+			return entry.response, nil
+		}
+		s.mu.Unlock()
+
+		// Wait for the first request; if it was abandoned, claim the key again
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Complete records the response for a key claimed with Begin and hands it to
+// any duplicates waiting for it
+func (s *IdempotencyStore) Complete(key string, response *StoredResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || entry.response != nil {
+		return
+	}
+	entry.response = response
+	entry.expires = time.Now().Add(s.ttl)
+	close(entry.done)
+}
+
+// Abandon forgets a key claimed with Begin without recording a response, so
+// the request can be retried with it
+func (s *IdempotencyStore) Abandon(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || entry.response != nil {
+		return
+	}
+	delete(s.entries, key)
+	close(entry.done)
+}
+
+// Len returns the number of keys held, including those in flight
+func (s *IdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweepLocked drops expired responses, at most once per TTL so a busy server
+// does not scan every key on every request. Callers hold s.mu.
+func (s *IdempotencyStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if entry.response != nil && !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
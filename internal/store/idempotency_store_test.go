@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStore_ReplaysAndRejectsReuse(t *testing.T) {
+	ctx := context.Background()
+	s := NewIdempotencyStore(time.Hour)
+
+	if replay, err := s.Begin(ctx, "key-1", "order-a"); replay != nil || err != nil {
+		t.Fatalf("Expected the first request to claim the key, got %v, %v", replay, err)
+	}
+	s.Complete("key-1", &StoredResponse{Status: 200, Body: []byte(`{"order_id":"1"}`)})
+
+	replay, err := s.Begin(ctx, "key-1", "order-a")
+	if err != nil || replay == nil || replay.Status != 200 || string(replay.Body) != `{"order_id":"1"}` {
+		t.Errorf("Expected the recorded response, got %+v, %v", replay, err)
+	}
+	if _, err := s.Begin(ctx, "key-1", "order-b"); err != ErrIdempotencyKeyReused {
+		t.Errorf("Expected ErrIdempotencyKeyReused for a different request, got %v", err)
+	}
+
+	// An abandoned key can be claimed again
+	s.Begin(ctx, "key-2", "order-a")
+	s.Abandon("key-2")
+	if replay, err := s.Begin(ctx, "key-2", "order-b"); replay != nil || err != nil {
+		t.Errorf("Expected an abandoned key to be claimable, got %v, %v", replay, err)
+	}
+}
+
+func TestIdempotencyStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	s := NewIdempotencyStore(20 * time.Millisecond)
+	s.Begin(ctx, "key", "order-a")
+	s.Complete("key", &StoredResponse{Status: 202})
+	s.Begin(ctx, "other", "order-a")
+	s.Complete("other", &StoredResponse{Status: 202})
+
+	time.Sleep(30 * time.Millisecond)
+	if replay, err := s.Begin(ctx, "key", "order-b"); replay != nil || err != nil {
+		t.Errorf("Expected an expired key to be claimable, got %v, %v", replay, err)
+	}
+	if s.Len() != 1 {
+		t.Errorf("Expected expired keys to be swept, %d held", s.Len())
+	}
+}
+
+func TestIdempotencyStore_ConcurrentDuplicates(t *testing.T) {
+	ctx := context.Background()
+	s := NewIdempotencyStore(time.Hour)
+
+	var handled atomic.Int32
+	var wg sync.WaitGroup
+	responses := make([]*StoredResponse, 20)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			replay, err := s.Begin(ctx, "key", "order-a")
+			if err != nil {
+				t.Errorf("Begin() error = %v", err)
+				return
+			}
+			if replay == nil {
+				handled.Add(1)
+				time.Sleep(10 * time.Millisecond) // duplicates arrive while this is in flight
+				replay = &StoredResponse{Status: 200, Body: []byte("paid")}
+				s.Complete("key", replay)
+			}
+			responses[i] = replay
+		}(i)
+	}
+	wg.Wait()
+
+	if handled.Load() != 1 {
+		t.Errorf("Expected exactly one request to be handled, got %d", handled.Load())
+	}
+	for i, response := range responses {
+		if response == nil || string(response.Body) != "paid" {
+			t.Errorf("Expected request %d to get the recorded response, got %+v", i, response)
+		}
+	}
+}
+
+func TestIdempotencyStore_WaitEndsWithContext(t *testing.T) {
+	s := NewIdempotencyStore(time.Hour)
+	s.Begin(context.Background(), "key", "order-a") // never finishes
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if replay, err := s.Begin(ctx, "key", "order-a"); replay != nil || err != context.DeadlineExceeded {
+		t.Errorf("Expected the duplicate to stop waiting with its context, got %v, %v", replay, err)
+	}
+}
@@ -0,0 +1,598 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrStockBelowReserved = errors.New("on_hand cannot be lower than the units already reserved")
+	ErrInvalidStock       = errors.New("on_hand must not be negative")
+	ErrInvalidQuantity    = errors.New("quantity must be positive")
+	ErrReservationExists  = errors.New("a reservation with this ID already exists")
+	ErrReservationUnknown = errors.New("reservation not found")
+	ErrWarehouseNotFound  = errors.New("warehouse not found")
+	ErrWarehouseExists    = errors.New("a warehouse with this ID already exists")
+	ErrInvalidAllocation  = errors.New("unknown allocation strategy (expected nearest, most_stock or split)")
+	ErrInvalidTransfer    = errors.New("a transfer needs two different warehouses")
+)
+
+// DefaultWarehouseID is the warehouse every inventory starts with. Stock set
+// without naming a warehouse is kept here.
+const DefaultWarehouseID = "main"
+
+// Allocation strategies decide which warehouses an order line ships from
+const (
+	// AllocateNearest ships each line from the single warehouse closest to the
+	// order's ship_to location that can cover it (by warehouse ID without one)
+	AllocateNearest = "nearest"
+
+	// AllocateMostStock ships each line from the warehouse with the most
+	// available units
+	AllocateMostStock = "most_stock"
+
+	// AllocateSplit takes each line from as many warehouses as it needs,
+	// nearest first (most stock first without a ship_to location), so an
+	// order is only refused when the units do not exist anywhere
+	AllocateSplit = "split"
+)
+
+// ValidAllocationStrategy reports whether s names an allocation strategy
+func ValidAllocationStrategy(s string) bool {
+	return s == AllocateNearest || s == AllocateMostStock || s == AllocateSplit
+}
+
+// InsufficientStockError reports the first product a reservation could not
+// be satisfied for. It matches ErrInsufficientStock with errors.Is.
+type InsufficientStockError struct {
+	ProductID int32
+	Requested int
+	Available int // units the strategy could have used
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("product %d: requested %d, available %d", e.ProductID, e.Requested, e.Available)
+}
+
+func (e *InsufficientStockError) Is(target error) bool {
+	return target == ErrInsufficientStock
+}
+
+// ReservationLine asks for units of one product
+type ReservationLine struct {
+	ProductID int32
+	Quantity  int
+}
+
+// ReserveOptions controls how a reservation is spread over warehouses
+type ReserveOptions struct {
+	Strategy string           // allocation strategy; "" means AllocateSplit
+	ShipTo   *models.Location // destination for the nearest-first strategies, if known
+}
+
+// reservation is a set of units held for one order until it is committed or
+// released
+type reservation struct {
+	allocations []models.Allocation
+}
+
+type stockLevel struct {
+	onHand   int
+	reserved int
+}
+
+func (l *stockLevel) available() int { return l.onHand - l.reserved }
+
+// InventoryStore tracks on-hand and reserved units per product and
+// warehouse. Orders move stock through reserve -> commit (the units are sold)
+// or reserve -> release (the units go back); sold units return with Restock.
+// Only pending reservations are held, so memory does not grow with orders.
+// Products without any stock level are untracked: they can always be
+// ordered, so catalogs that do not manage stock keep working.
+//
+// When opened with a file path, warehouses and on-hand counts are saved
+// there whenever they change. Reservations live in memory only.
+type InventoryStore struct {
+	mu           sync.Mutex
+	warehouses   map[string]*models.Warehouse
+	levels       map[int32]map[string]*stockLevel // product ID -> warehouse ID -> stock
+	reservations map[string]*reservation
+
+	path string // JSON file backing warehouses and on-hand counts, or "" for memory only
+}
+
+// inventoryFile is the on-disk form of an InventoryStore
+type inventoryFile struct {
+	Warehouses []models.Warehouse  `json:"warehouses"`
+	Stock      []models.StockLevel `json:"stock"` // only on_hand is kept
+}
+
+// NewInventoryStore creates an in-memory inventory holding only the main
+// warehouse and no stock
+func NewInventoryStore() *InventoryStore {
+	return &InventoryStore{
+		warehouses: map[string]*models.Warehouse{
+			DefaultWarehouseID: {ID: DefaultWarehouseID, Name: "Main warehouse"},
+		},
+		levels:       make(map[int32]map[string]*stockLevel),
+		reservations: make(map[string]*reservation),
+	}
+}
+
+// OpenInventoryStore loads warehouses and on-hand counts from path, starting
+// empty if the file does not exist yet, and saves every change back to it
+func OpenInventoryStore(path string) (*InventoryStore, error) {
+	s := NewInventoryStore()
+	s.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read inventory: %w", err)
+	}
+
+	var file inventoryFile
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		// Files from before warehouses list one on-hand count per product
+		var counts []models.Inventory
+		if err := json.Unmarshal(data, &counts); err != nil {
+			return nil, fmt.Errorf("decode inventory %s: %w", path, err)
+		}
+		for _, count := range counts {
+			file.Stock = append(file.Stock, models.StockLevel{ProductID: count.ProductID, WarehouseID: DefaultWarehouseID, OnHand: count.OnHand})
+		}
+	} else if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode inventory %s: %w", path, err)
+	}
+
+	for _, w := range file.Warehouses {
+		warehouse := w
+		s.warehouses[w.ID] = &warehouse
+	}
+	for _, level := range file.Stock {
+		if _, ok := s.warehouses[level.WarehouseID]; !ok {
+			return nil, fmt.Errorf("decode inventory %s: stock of product %d in unknown warehouse %q", path, level.ProductID, level.WarehouseID)
+		}
+		s.levelLocked(level.ProductID, level.WarehouseID).onHand = level.OnHand
+	}
+	return s, nil
+}
+
+// saveLocked rewrites the backing file, if there is one. Callers hold s.mu.
+func (s *InventoryStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	file := inventoryFile{Warehouses: s.warehousesLocked(), Stock: []models.StockLevel{}}
+	for productID, levels := range s.levels {
+		for warehouseID, level := range levels {
+			file.Stock = append(file.Stock, models.StockLevel{ProductID: productID, WarehouseID: warehouseID, OnHand: level.onHand})
+		}
+	}
+	sort.Slice(file.Stock, func(i, j int) bool {
+		if file.Stock[i].ProductID != file.Stock[j].ProductID {
+			return file.Stock[i].ProductID < file.Stock[j].ProductID
+		}
+		return file.Stock[i].WarehouseID < file.Stock[j].WarehouseID
+	})
+	return writeJSONFile(s.path, file)
+}
+
+// levelLocked returns the product's stock in the warehouse, creating an empty
+// level (which starts tracking the product) if needed. Callers hold s.mu.
+func (s *InventoryStore) levelLocked(productID int32, warehouseID string) *stockLevel {
+	levels, ok := s.levels[productID]
+	if !ok {
+		levels = make(map[string]*stockLevel)
+		s.levels[productID] = levels
+	}
+	level, ok := levels[warehouseID]
+	if !ok {
+		level = &stockLevel{}
+		levels[warehouseID] = level
+	}
+	return level
+}
+
+// Warehouses returns every warehouse ordered by ID
+func (s *InventoryStore) Warehouses() []models.Warehouse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.warehousesLocked()
+}
+
+func (s *InventoryStore) warehousesLocked() []models.Warehouse {
+	warehouses := make([]models.Warehouse, 0, len(s.warehouses))
+	for _, w := range s.warehouses {
+		warehouses = append(warehouses, *w)
+	}
+	sort.Slice(warehouses, func(i, j int) bool { return warehouses[i].ID < warehouses[j].ID })
+	return warehouses
+}
+
+// Warehouse returns a copy of the warehouse or ErrWarehouseNotFound
+func (s *InventoryStore) Warehouse(id string) (*models.Warehouse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.warehouses[id]
+	if !ok {
+		return nil, ErrWarehouseNotFound
+	}
+	warehouseCopy := *w
+	return &warehouseCopy, nil
+}
+
+// CreateWarehouse adds a warehouse with no stock
+func (s *InventoryStore) CreateWarehouse(w models.Warehouse) (*models.Warehouse, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.warehouses[w.ID]; exists {
+		return nil, ErrWarehouseExists
+	}
+	s.warehouses[w.ID] = &w
+	if err := s.saveLocked(); err != nil {
+		delete(s.warehouses, w.ID)
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Get returns the product's stock in total and per warehouse, or an untracked
+// record if stock has never been set
+func (s *InventoryStore) Get(productID int32) models.Inventory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inventoryLocked(productID)
+}
+
+func (s *InventoryStore) inventoryLocked(productID int32) models.Inventory {
+	inventory := models.Inventory{ProductID: productID, Warehouses: []models.StockLevel{}}
+	levels, tracked := s.levels[productID]
+	if !tracked {
+		return inventory
+	}
+
+	inventory.Tracked = true
+	for warehouseID, level := range levels {
+		inventory.OnHand += level.onHand
+		inventory.Reserved += level.reserved
+		inventory.Warehouses = append(inventory.Warehouses, models.StockLevel{
+			ProductID:   productID,
+			WarehouseID: warehouseID,
+			OnHand:      level.onHand,
+			Reserved:    level.reserved,
+			Available:   level.available(),
+		})
+	}
+	inventory.Available = inventory.OnHand - inventory.Reserved
+	sort.Slice(inventory.Warehouses, func(i, j int) bool {
+		return inventory.Warehouses[i].WarehouseID < inventory.Warehouses[j].WarehouseID
+	})
+	return inventory
+}
+
+// WarehouseStock returns the stock of every product held in the warehouse,
+// ordered by product ID
+func (s *InventoryStore) WarehouseStock(warehouseID string) ([]models.StockLevel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.warehouses[warehouseID]; !ok {
+		return nil, ErrWarehouseNotFound
+	}
+
+	stock := []models.StockLevel{}
+	for productID, levels := range s.levels {
+		if level, ok := levels[warehouseID]; ok {
+			stock = append(stock, models.StockLevel{
+				ProductID:   productID,
+				WarehouseID: warehouseID,
+				OnHand:      level.onHand,
+				Reserved:    level.reserved,
+				Available:   level.available(),
+			})
+		}
+	}
+	sort.Slice(stock, func(i, j int) bool { return stock[i].ProductID < stock[j].ProductID })
+	return stock, nil
+}
+
+// SetOnHand sets the units in stock at one warehouse, starting to track the
+// product if it was untracked. It cannot drop below the units reserved there
+// by pending orders.
+func (s *InventoryStore) SetOnHand(productID int32, warehouseID string, onHand int) (models.Inventory, error) {
+	if onHand < 0 {
+		return models.Inventory{}, ErrInvalidStock
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.warehouses[warehouseID]; !ok {
+		return models.Inventory{}, ErrWarehouseNotFound
+	}
+
+	_, tracked := s.levels[productID]
+	_, stocked := s.levels[productID][warehouseID]
+	level := s.levelLocked(productID, warehouseID)
+	if onHand < level.reserved {
+		return models.Inventory{}, ErrStockBelowReserved
+	}
+
+	previous := level.onHand
+	level.onHand = onHand
+	if err := s.saveLocked(); err != nil {
+		switch {
+		case !tracked:
+			delete(s.levels, productID)
+		case !stocked:
+			delete(s.levels[productID], warehouseID)
+		default:
+			level.onHand = previous
+		}
+		return models.Inventory{}, err
+	}
+	return s.inventoryLocked(productID), nil
+}
+
+// Transfer moves available units of a product from one warehouse to another
+func (s *InventoryStore) Transfer(productID int32, from, to string, quantity int) (models.Inventory, error) {
+	if quantity < 1 {
+		return models.Inventory{}, ErrInvalidQuantity
+	}
+	if from == to {
+		return models.Inventory{}, ErrInvalidTransfer
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range []string{from, to} {
+		if _, ok := s.warehouses[id]; !ok {
+			return models.Inventory{}, ErrWarehouseNotFound
+		}
+	}
+
+	source, ok := s.levels[productID][from]
+	if !ok || source.available() < quantity {
+		available := 0
+		if ok {
+			available = source.available()
+		}
+		return models.Inventory{}, &InsufficientStockError{ProductID: productID, Requested: quantity, Available: available}
+	}
+
+	_, stocked := s.levels[productID][to]
+	destination := s.levelLocked(productID, to)
+	source.onHand -= quantity
+	destination.onHand += quantity
+	if err := s.saveLocked(); err != nil {
+		source.onHand += quantity
+		destination.onHand -= quantity
+		if !stocked {
+			delete(s.levels[productID], to)
+		}
+		return models.Inventory{}, err
+	}
+	return s.inventoryLocked(productID), nil
+}
+
+// Reserve holds stock for every line under reservationID, or for none of
+// them: if any tracked product is short, nothing is reserved and an
+// *InsufficientStockError is returned. Untracked products are not held, and
+// an order of untracked products only leaves no reservation behind.
+// It returns which warehouses the units were taken from.
+func (s *InventoryStore) Reserve(reservationID string, lines []ReservationLine, opts ReserveOptions) ([]models.Allocation, error) {
+	if opts.Strategy == "" {
+		opts.Strategy = AllocateSplit
+	}
+	if !ValidAllocationStrategy(opts.Strategy) {
+		return nil, ErrInvalidAllocation
+	}
+
+	wanted := make(map[int32]int)
+	var productIDs []int32
+	for _, line := range lines {
+		if line.Quantity < 1 {
+			return nil, ErrInvalidQuantity
+		}
+		if _, seen := wanted[line.ProductID]; !seen {
+			productIDs = append(productIDs, line.ProductID)
+		}
+		wanted[line.ProductID] += line.Quantity
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.reservations[reservationID]; exists {
+		return nil, ErrReservationExists
+	}
+
+	allocations := []models.Allocation{}
+	for _, productID := range productIDs {
+		levels, tracked := s.levels[productID]
+		if !tracked {
+			continue
+		}
+		allocated, err := s.allocateLocked(productID, wanted[productID], levels, opts)
+		if err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, allocated...)
+	}
+
+	if len(allocations) == 0 {
+		return allocations, nil
+	}
+	for _, a := range allocations {
+		s.levels[a.ProductID][a.WarehouseID].reserved += a.Quantity
+	}
+	s.reservations[reservationID] = &reservation{allocations: allocations}
+	return allocations, nil
+}
+
+// allocateLocked picks the warehouses one product's units come from. Callers hold s.mu.
+func (s *InventoryStore) allocateLocked(productID int32, quantity int, levels map[string]*stockLevel, opts ReserveOptions) ([]models.Allocation, error) {
+	candidates := make([]string, 0, len(levels))
+	for warehouseID, level := range levels {
+		if level.available() > 0 {
+			candidates = append(candidates, warehouseID)
+		}
+	}
+
+	switch {
+	case opts.Strategy == AllocateMostStock || (opts.Strategy == AllocateSplit && opts.ShipTo == nil):
+		sort.Slice(candidates, func(i, j int) bool {
+			ai, aj := levels[candidates[i]].available(), levels[candidates[j]].available()
+			if ai != aj {
+				return ai > aj
+			}
+			return candidates[i] < candidates[j]
+		})
+	case opts.ShipTo != nil:
+		sort.Slice(candidates, func(i, j int) bool {
+			di := opts.ShipTo.DistanceKm(s.warehouses[candidates[i]].Location)
+			dj := opts.ShipTo.DistanceKm(s.warehouses[candidates[j]].Location)
+			if di != dj {
+				return di < dj
+			}
+			return candidates[i] < candidates[j]
+		})
+	default:
+		sort.Strings(candidates)
+	}
+
+	if opts.Strategy == AllocateSplit {
+		var allocations []models.Allocation
+		remaining := quantity
+		for _, warehouseID := range candidates {
+			take := min(remaining, levels[warehouseID].available())
+			allocations = append(allocations, models.Allocation{ProductID: productID, WarehouseID: warehouseID, Quantity: take})
+			if remaining -= take; remaining == 0 {
+				return allocations, nil
+			}
+		}
+		return nil, &InsufficientStockError{ProductID: productID, Requested: quantity, Available: quantity - remaining}
+	}
+
+	// The other strategies ship the line from the first warehouse that covers it
+	largest := 0
+	for _, warehouseID := range candidates {
+		available := levels[warehouseID].available()
+		if available >= quantity {
+			return []models.Allocation{{ProductID: productID, WarehouseID: warehouseID, Quantity: quantity}}, nil
+		}
+		largest = max(largest, available)
+	}
+	return nil, &InsufficientStockError{ProductID: productID, Requested: quantity, Available: largest}
+}
+
+// Commit turns a reservation into a sale: its units leave on-hand stock and
+// the reservation is forgotten
+func (s *InventoryStore) Commit(reservationID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reservations[reservationID]
+	if !ok {
+		return ErrReservationUnknown
+	}
+
+	s.moveLocked(r, func(level *stockLevel, quantity int) {
+		level.reserved -= quantity
+		level.onHand -= quantity
+	})
+	if err := s.saveLocked(); err != nil {
+		s.moveLocked(r, func(level *stockLevel, quantity int) {
+			level.reserved += quantity
+			level.onHand += quantity
+		})
+		return err
+	}
+	delete(s.reservations, reservationID)
+	return nil
+}
+
+// Release gives a pending reservation's units back, making them available
+// again, and forgets the reservation
+func (s *InventoryStore) Release(reservationID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reservations[reservationID]
+	if !ok {
+		return ErrReservationUnknown
+	}
+	delete(s.reservations, reservationID)
+	s.moveLocked(r, func(level *stockLevel, quantity int) { level.reserved -= quantity })
+	return nil
+}
+
+// Reservations returns the IDs of the pending reservations, in order
+func (s *InventoryStore) Reservations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.reservations))
+	for id := range s.reservations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Restock puts sold units back on hand where they came from, e.g. for a
+// cancelled order. Committed reservations are not kept, so the order's
+// allocations say where that is.
+func (s *InventoryStore) Restock(allocations []models.Allocation) error {
+	if len(allocations) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range allocations {
+		if a.Quantity <= 0 {
+			return ErrInvalidQuantity
+		}
+		if _, ok := s.warehouses[a.WarehouseID]; !ok {
+			return ErrWarehouseNotFound
+		}
+	}
+	for _, a := range allocations {
+		s.levelLocked(a.ProductID, a.WarehouseID).onHand += a.Quantity
+	}
+	if err := s.saveLocked(); err != nil {
+		for _, a := range allocations {
+			s.levelLocked(a.ProductID, a.WarehouseID).onHand -= a.Quantity
+		}
+		return err
+	}
+	return nil
+}
+
+// moveLocked applies fn to the stock level behind every allocation of the
+// reservation. Callers hold s.mu.
+func (s *InventoryStore) moveLocked(r *reservation, fn func(level *stockLevel, quantity int)) {
+	for _, a := range r.allocations {
+		fn(s.levelLocked(a.ProductID, a.WarehouseID), a.Quantity)
+	}
+}
@@ -0,0 +1,345 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestInventoryStore_ReserveCommitRelease(t *testing.T) {
+	s := NewInventoryStore()
+	if inv := s.Get(42); inv.Tracked {
+		t.Fatalf("Expected product 42 to start untracked, got %+v", inv)
+	}
+	if _, err := s.SetOnHand(42, DefaultWarehouseID, 5); err != nil {
+		t.Fatalf("SetOnHand() error = %v", err)
+	}
+
+	// Product 7 is untracked, so it never limits an order
+	if _, err := s.Reserve("order-1", []ReservationLine{{ProductID: 42, Quantity: 2}, {ProductID: 7, Quantity: 100}}, ReserveOptions{}); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if inv := s.Get(42); inv.OnHand != 5 || inv.Reserved != 2 || inv.Available != 3 {
+		t.Errorf("Expected 2 of 5 reserved, got %+v", inv)
+	}
+
+	// Repeated lines add up: 2 + 2 is more than the 3 still available
+	_, err := s.Reserve("order-2", []ReservationLine{{ProductID: 42, Quantity: 2}, {ProductID: 42, Quantity: 2}}, ReserveOptions{})
+	var shortage *InsufficientStockError
+	if !errors.Is(err, ErrInsufficientStock) || !errors.As(err, &shortage) || shortage.Requested != 4 || shortage.Available != 3 {
+		t.Errorf("Expected a shortage of 4 requested, 3 available, got %v", err)
+	}
+	if _, err := s.Reserve("order-1", []ReservationLine{{ProductID: 42, Quantity: 1}}, ReserveOptions{}); err != ErrReservationExists {
+		t.Errorf("Expected a reused reservation ID to be rejected, got %v", err)
+	}
+	if _, err := s.Reserve("order-3", []ReservationLine{{ProductID: 42, Quantity: 0}}, ReserveOptions{}); err != ErrInvalidQuantity {
+		t.Errorf("Expected a zero quantity to be rejected, got %v", err)
+	}
+
+	if _, err := s.SetOnHand(42, DefaultWarehouseID, 1); err != ErrStockBelowReserved {
+		t.Errorf("Expected on-hand below the reserved units to be rejected, got %v", err)
+	}
+
+	if err := s.Commit("order-1"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if inv := s.Get(42); inv.OnHand != 3 || inv.Reserved != 0 {
+		t.Errorf("Expected the committed units to leave stock, got %+v", inv)
+	}
+
+	if _, err := s.Reserve("order-4", []ReservationLine{{ProductID: 42, Quantity: 3}}, ReserveOptions{}); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := s.Release("order-4"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if inv := s.Get(42); inv.Available != 3 {
+		t.Errorf("Expected released units to be available again, got %+v", inv)
+	}
+
+	// A committed reservation is forgotten; its units come back with Restock
+	if err := s.Release("order-1"); err != ErrReservationUnknown {
+		t.Errorf("Expected a committed reservation to be gone, got %v", err)
+	}
+	if err := s.Release("order-4"); err != ErrReservationUnknown {
+		t.Errorf("Expected a second release to fail, got %v", err)
+	}
+}
+
+func TestInventoryStore_ReservationsAreNotKept(t *testing.T) {
+	s := NewInventoryStore()
+	s.SetOnHand(42, DefaultWarehouseID, 100)
+
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("order-%d", i)
+		if _, err := s.Reserve(id, []ReservationLine{{ProductID: 42, Quantity: 1}}, ReserveOptions{}); err != nil {
+			t.Fatalf("Reserve() error = %v", err)
+		}
+		if err := s.Commit(id); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+	}
+	// Untracked products allocate nothing, so nothing is held for them
+	allocations, err := s.Reserve("untracked", []ReservationLine{{ProductID: 7, Quantity: 3}}, ReserveOptions{})
+	if err != nil || len(allocations) != 0 {
+		t.Fatalf("Expected an untracked order to allocate nothing, got %v, %v", allocations, err)
+	}
+
+	if len(s.reservations) != 0 {
+		t.Errorf("Expected no reservations to be held after reserve + commit, got %d", len(s.reservations))
+	}
+	if inv := s.Get(42); inv.OnHand != 50 || inv.Reserved != 0 {
+		t.Errorf("Expected 50 units sold, got %+v", inv)
+	}
+
+	// A reused order ID is not refused once its reservation is settled
+	if _, err := s.Reserve("order-0", []ReservationLine{{ProductID: 42, Quantity: 1}}, ReserveOptions{}); err != nil {
+		t.Errorf("Expected a settled reservation ID to be reusable, got %v", err)
+	}
+}
+
+func TestInventoryStore_ConcurrentReservationsNeverOversell(t *testing.T) {
+	s := NewInventoryStore()
+	s.SetOnHand(1, DefaultWarehouseID, 10)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := s.Reserve(string(rune('A'+i)), []ReservationLine{{ProductID: 1, Quantity: 1}}, ReserveOptions{}); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if accepted != 10 {
+		t.Errorf("Expected exactly 10 reservations to succeed, got %d", accepted)
+	}
+	if inv := s.Get(1); inv.Available != 0 {
+		t.Errorf("Expected no stock left, got %+v", inv)
+	}
+}
+
+func TestInventoryStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	s, err := OpenInventoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenInventoryStore() error = %v", err)
+	}
+	s.SetOnHand(42, DefaultWarehouseID, 5)
+	s.Reserve("order-1", []ReservationLine{{ProductID: 42, Quantity: 2}}, ReserveOptions{})
+	s.Commit("order-1")
+
+	reopened, err := OpenInventoryStore(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if inv := reopened.Get(42); !inv.Tracked || inv.OnHand != 3 {
+		t.Errorf("Expected 3 units on hand after reopening, got %+v", inv)
+	}
+}
+
+// newWarehouseInventory stocks product 1 in three warehouses: main (no
+// location) with 2 units, east (New York) with 5 and west (Seattle) with 3
+func newWarehouseInventory(t *testing.T) *InventoryStore {
+	t.Helper()
+	s := NewInventoryStore()
+	for _, w := range []models.Warehouse{
+		{ID: "east", Name: "East", Location: models.Location{Latitude: 40.7, Longitude: -74.0}},
+		{ID: "west", Name: "West", Location: models.Location{Latitude: 47.6, Longitude: -122.3}},
+	} {
+		if _, err := s.CreateWarehouse(w); err != nil {
+			t.Fatalf("CreateWarehouse() error = %v", err)
+		}
+	}
+	for warehouseID, units := range map[string]int{DefaultWarehouseID: 2, "east": 5, "west": 3} {
+		if _, err := s.SetOnHand(1, warehouseID, units); err != nil {
+			t.Fatalf("SetOnHand() error = %v", err)
+		}
+	}
+	return s
+}
+
+func TestInventoryStore_AllocationStrategies(t *testing.T) {
+	portland := &models.Location{Latitude: 45.5, Longitude: -122.7}
+	tests := []struct {
+		name     string
+		opts     ReserveOptions
+		quantity int
+		want     []models.Allocation
+		short    bool
+	}{
+		{"nearest ships from the closest warehouse", ReserveOptions{Strategy: AllocateNearest, ShipTo: portland}, 3,
+			[]models.Allocation{{ProductID: 1, WarehouseID: "west", Quantity: 3}}, false},
+		{"nearest skips warehouses that cannot cover the line", ReserveOptions{Strategy: AllocateNearest, ShipTo: portland}, 4,
+			[]models.Allocation{{ProductID: 1, WarehouseID: "east", Quantity: 4}}, false},
+		{"nearest without a destination goes by ID", ReserveOptions{Strategy: AllocateNearest}, 1,
+			[]models.Allocation{{ProductID: 1, WarehouseID: "east", Quantity: 1}}, false},
+		{"most stock", ReserveOptions{Strategy: AllocateMostStock, ShipTo: portland}, 2,
+			[]models.Allocation{{ProductID: 1, WarehouseID: "east", Quantity: 2}}, false},
+		{"single warehouse strategies refuse lines no warehouse covers", ReserveOptions{Strategy: AllocateMostStock}, 6, nil, true},
+		{"split takes the nearest units first", ReserveOptions{Strategy: AllocateSplit, ShipTo: portland}, 9,
+			[]models.Allocation{
+				{ProductID: 1, WarehouseID: "west", Quantity: 3},
+				{ProductID: 1, WarehouseID: "east", Quantity: 5},
+				{ProductID: 1, WarehouseID: DefaultWarehouseID, Quantity: 1},
+			}, false},
+		{"split without a destination takes the biggest stock first", ReserveOptions{}, 6,
+			[]models.Allocation{
+				{ProductID: 1, WarehouseID: "east", Quantity: 5},
+				{ProductID: 1, WarehouseID: "west", Quantity: 1},
+			}, false},
+		{"split refuses more than exists", ReserveOptions{Strategy: AllocateSplit}, 11, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newWarehouseInventory(t)
+			got, err := s.Reserve("order", []ReservationLine{{ProductID: 1, Quantity: tt.quantity}}, tt.opts)
+			if tt.short {
+				if !errors.Is(err, ErrInsufficientStock) {
+					t.Errorf("Expected ErrInsufficientStock, got %v with %+v", err, got)
+				}
+				if inv := s.Get(1); inv.Reserved != 0 {
+					t.Errorf("Expected nothing reserved after a refusal, got %+v", inv)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reserve() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reserve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	s := newWarehouseInventory(t)
+	if _, err := s.Reserve("order", []ReservationLine{{ProductID: 1, Quantity: 1}}, ReserveOptions{Strategy: "cheapest"}); err != ErrInvalidAllocation {
+		t.Errorf("Expected an unknown strategy to be rejected, got %v", err)
+	}
+}
+
+func TestInventoryStore_SplitReservationReleasesEveryWarehouse(t *testing.T) {
+	s := newWarehouseInventory(t)
+	if _, err := s.Reserve("order", []ReservationLine{{ProductID: 1, Quantity: 9}}, ReserveOptions{}); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if inv := s.Get(1); inv.Available != 1 {
+		t.Errorf("Expected 1 unit left after reserving 9, got %+v", inv)
+	}
+
+	if err := s.Release("order"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	inv := s.Get(1)
+	want := map[string]int{DefaultWarehouseID: 2, "east": 5, "west": 3}
+	for _, level := range inv.Warehouses {
+		if level.Available != want[level.WarehouseID] || level.Reserved != 0 {
+			t.Errorf("Expected %d units available again in %s, got %+v", want[level.WarehouseID], level.WarehouseID, level)
+		}
+	}
+}
+
+func TestInventoryStore_Restock(t *testing.T) {
+	s := newWarehouseInventory(t)
+	allocations, err := s.Reserve("order", []ReservationLine{{ProductID: 1, Quantity: 9}}, ReserveOptions{})
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := s.Commit("order"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	// The allocations alone say where the sold units go back to
+	if err := s.Restock(allocations); err != nil {
+		t.Fatalf("Restock() error = %v", err)
+	}
+	inv := s.Get(1)
+	want := map[string]int{DefaultWarehouseID: 2, "east": 5, "west": 3}
+	for _, level := range inv.Warehouses {
+		if level.OnHand != want[level.WarehouseID] {
+			t.Errorf("Expected %d units back in %s, got %+v", want[level.WarehouseID], level.WarehouseID, level)
+		}
+	}
+
+	bad := []models.Allocation{{ProductID: 1, WarehouseID: "north", Quantity: 1}}
+	if err := s.Restock(bad); err != ErrWarehouseNotFound {
+		t.Errorf("Expected an unknown warehouse to be rejected, got %v", err)
+	}
+	bad = []models.Allocation{{ProductID: 1, WarehouseID: DefaultWarehouseID, Quantity: 0}}
+	if err := s.Restock(bad); err != ErrInvalidQuantity {
+		t.Errorf("Expected a zero quantity to be rejected, got %v", err)
+	}
+}
+
+func TestInventoryStore_Transfer(t *testing.T) {
+	s := newWarehouseInventory(t)
+	s.Reserve("order", []ReservationLine{{ProductID: 1, Quantity: 2}}, ReserveOptions{Strategy: AllocateNearest, ShipTo: &models.Location{Latitude: 47.6, Longitude: -122.3}})
+
+	// West holds 3 units but 2 are reserved
+	if _, err := s.Transfer(1, "west", "east", 2); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("Expected reserved units to stay put, got %v", err)
+	}
+	if _, err := s.Transfer(1, "west", "west", 1); err != ErrInvalidTransfer {
+		t.Errorf("Expected a transfer to the same warehouse to be rejected, got %v", err)
+	}
+	if _, err := s.Transfer(1, "west", "north", 1); err != ErrWarehouseNotFound {
+		t.Errorf("Expected an unknown warehouse to be rejected, got %v", err)
+	}
+
+	inv, err := s.Transfer(1, "east", "west", 4)
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	if inv.OnHand != 10 || inv.Reserved != 2 {
+		t.Errorf("Expected totals to be unchanged by a transfer, got %+v", inv)
+	}
+	stock, _ := s.WarehouseStock("west")
+	if len(stock) != 1 || stock[0].OnHand != 7 || stock[0].Available != 5 {
+		t.Errorf("Expected 7 units in west with 5 available, got %+v", stock)
+	}
+}
+
+func TestInventoryStore_WarehousePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	s, err := OpenInventoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenInventoryStore() error = %v", err)
+	}
+	s.CreateWarehouse(models.Warehouse{ID: "east", Name: "East", Location: models.Location{Latitude: 40.7, Longitude: -74.0}})
+	s.SetOnHand(1, "east", 4)
+
+	reopened, err := OpenInventoryStore(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if w, err := reopened.Warehouse("east"); err != nil || w.Location.Latitude != 40.7 {
+		t.Errorf("Expected the east warehouse after reopening, got %+v, %v", w, err)
+	}
+	if stock, _ := reopened.WarehouseStock("east"); len(stock) != 1 || stock[0].OnHand != 4 {
+		t.Errorf("Expected 4 units in east after reopening, got %+v", stock)
+	}
+
+	// Files written before warehouses existed hold counts for the main warehouse
+	legacy := filepath.Join(t.TempDir(), "inventory.json")
+	os.WriteFile(legacy, []byte(`[{"product_id": 42, "tracked": true, "on_hand": 3}]`), 0o644)
+	upgraded, err := OpenInventoryStore(legacy)
+	if err != nil {
+		t.Fatalf("OpenInventoryStore() legacy error = %v", err)
+	}
+	if inv := upgraded.Get(42); inv.OnHand != 3 || inv.Warehouses[0].WarehouseID != DefaultWarehouseID {
+		t.Errorf("Expected legacy stock in the main warehouse, got %+v", inv)
+	}
+}
@@ -0,0 +1,283 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderExists   = errors.New("an order with this ID already exists")
+)
+
+// OrderStore records orders and their status. The HTTP handlers create orders
+// and the order processor moves them along, so with separate processes both
+// must open the same durable store.
+type OrderStore interface {
+	// Create stores a new order, returning ErrOrderExists if its ID is taken.
+	// UpdatedAt defaults to CreatedAt.
+	Create(order *models.Order) error
+
+	// Get returns a copy of the order or ErrOrderNotFound
+	Get(orderID string) (*models.Order, error)
+
+	// Update applies fn to the stored order and saves the result, stamping
+	// UpdatedAt. An error from fn aborts the update and is returned as is, and
+	// a status change the order state machine forbids is rejected with a
+	// models.TransitionError. Concurrent updates of one order are applied one
+	// after the other.
+	Update(orderID string, fn func(order *models.Order) error) (*models.Order, error)
+}
+
+// MemoryOrderStore keeps orders in memory; they are lost on restart and are
+// only visible to the process that holds them
+type MemoryOrderStore struct {
+	mu     sync.RWMutex
+	orders map[string]*models.Order
+}
+
+var _ OrderStore = (*MemoryOrderStore)(nil)
+
+// NewMemoryOrderStore creates an empty in-memory order store
+func NewMemoryOrderStore() *MemoryOrderStore {
+	return &MemoryOrderStore{orders: make(map[string]*models.Order)}
+}
+
+// Create implements OrderStore
+func (s *MemoryOrderStore) Create(order *models.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.orders[order.OrderID]; exists {
+		return ErrOrderExists
+	}
+	stored := cloneOrder(order)
+	if stored.UpdatedAt.IsZero() {
+		stored.UpdatedAt = stored.CreatedAt
+	}
+	s.orders[order.OrderID] = stored
+	return nil
+}
+
+// Get implements OrderStore
+func (s *MemoryOrderStore) Get(orderID string) (*models.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order, ok := s.orders[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return cloneOrder(order), nil
+}
+
+// Update implements OrderStore
+func (s *MemoryOrderStore) Update(orderID string, fn func(order *models.Order) error) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.orders[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+
+	order := cloneOrder(stored)
+	if err := fn(order); err != nil {
+		return nil, err
+	}
+	if err := checkTransition(stored.Status, order.Status); err != nil {
+		return nil, err
+	}
+	order.OrderID = orderID
+	order.UpdatedAt = time.Now()
+	s.orders[orderID] = order
+	return cloneOrder(order), nil
+}
+
+// checkTransition rejects an update that moves an order between statuses the
+// state machine does not connect
+func checkTransition(from, to string) error {
+	if from != to && !models.CanTransition(from, to) {
+		return &models.TransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// cloneOrder copies an order, including its slices
+func cloneOrder(order *models.Order) *models.Order {
+	c := *order
+	c.Items = append([]models.Item(nil), order.Items...)
+	c.History = append([]models.StatusChange(nil), order.History...)
+	c.Allocations = append([]models.Allocation(nil), order.Allocations...)
+	if order.ShipTo != nil {
+		shipTo := *order.ShipTo
+		c.ShipTo = &shipTo
+	}
+	return &c
+}
+
+// orderMigrations are applied in order; the index of each entry + 1 is its
+// schema version. Never edit an existing entry - append a new one instead.
+var orderMigrations = []string{
+	// data holds the whole order as JSON; the other columns are kept for queries
+	`CREATE TABLE orders (
+		order_id    VARCHAR(100) PRIMARY KEY,
+		customer_id INTEGER      NOT NULL,
+		status      VARCHAR(20)  NOT NULL,
+		created_at  TIMESTAMP    NOT NULL,
+		updated_at  TIMESTAMP    NOT NULL,
+		data        TEXT         NOT NULL
+	)`,
+	`CREATE INDEX idx_orders_customer ON orders (customer_id, created_at)`,
+}
+
+// SQLOrderStore persists orders in SQLite or Postgres. The server and the
+// order processor can share it.
+type SQLOrderStore struct {
+	db     *sql.DB
+	driver string
+}
+
+var _ OrderStore = (*SQLOrderStore)(nil)
+
+// OpenSQLOrderStore connects to the database and applies pending migrations.
+// Its migrations are versioned separately, so it may share the products database.
+func OpenSQLOrderStore(driver, dsn string) (*SQLOrderStore, error) {
+	db, err := openSQL(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SQLOrderStore{db: db, driver: driver}
+	if err := migrate(s.db, s.rebind, "order_schema_migrations", orderMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close releases the database connection pool
+func (s *SQLOrderStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLOrderStore) rebind(query string) string {
+	return rebindQuery(s.driver, query)
+}
+
+// Create implements OrderStore
+func (s *SQLOrderStore) Create(order *models.Order) error {
+	stored := cloneOrder(order)
+	if stored.UpdatedAt.IsZero() {
+		stored.UpdatedAt = stored.CreatedAt
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(s.rebind(`INSERT INTO orders (order_id, customer_id, status, created_at, updated_at, data)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (order_id) DO NOTHING`),
+		stored.OrderID, stored.CustomerID, stored.Status, stored.CreatedAt.UTC(), stored.UpdatedAt.UTC(), string(data))
+	if err != nil {
+		return err
+	}
+	if inserted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if inserted == 0 {
+		return ErrOrderExists
+	}
+	return nil
+}
+
+// Get implements OrderStore
+func (s *SQLOrderStore) Get(orderID string) (*models.Order, error) {
+	return getOrder(s.db.QueryRow(s.rebind(`SELECT data FROM orders WHERE order_id = ?`), orderID))
+}
+
+// Update implements OrderStore. The row is locked for the transaction on
+// Postgres; SQLite runs one connection, so transactions never overlap.
+func (s *SQLOrderStore) Update(orderID string, fn func(order *models.Order) error) (*models.Order, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT data FROM orders WHERE order_id = ?`
+	if s.driver == DriverPostgres {
+		query += ` FOR UPDATE`
+	}
+	order, err := getOrder(tx.QueryRow(s.rebind(query), orderID))
+	if err != nil {
+		return nil, err
+	}
+
+	from := order.Status
+	if err := fn(order); err != nil {
+		return nil, err
+	}
+	if err := checkTransition(from, order.Status); err != nil {
+		return nil, err
+	}
+	order.OrderID = orderID
+	order.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(s.rebind(`UPDATE orders SET status = ?, updated_at = ?, data = ? WHERE order_id = ?`),
+		order.Status, order.UpdatedAt.UTC(), string(data), orderID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// getOrder decodes the order held in a data column
+func getOrder(row rowScanner) (*models.Order, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	var order models.Order
+	if err := json.Unmarshal([]byte(data), &order); err != nil {
+		return nil, fmt.Errorf("decode order: %w", err)
+	}
+	return &order, nil
+}
+
+// OpenOrderStore opens the order store for a backend: memory, sqlite or
+// postgres. The SQLite file defaults to orders.db; Postgres needs a dsn.
+// The returned function closes the store.
+func OpenOrderStore(backend, dsn string) (OrderStore, func() error, error) {
+	switch backend {
+	case "", "memory":
+		return NewMemoryOrderStore(), func() error { return nil }, nil
+	case DriverSQLite, DriverPostgres:
+		if dsn == "" {
+			if backend == DriverPostgres {
+				return nil, nil, errors.New("the postgres order store needs a DSN")
+			}
+			dsn = "orders.db"
+		}
+		s, err := OpenSQLOrderStore(backend, dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unknown order store %q (expected memory, sqlite or postgres)", backend)
+}
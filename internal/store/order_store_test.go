@@ -0,0 +1,138 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testOrder(id string) *models.Order {
+	order := &models.Order{
+		OrderID:    id,
+		CustomerID: 7,
+		Items:      []models.Item{{ProductID: 1, Quantity: 2, Price: 9.99}},
+		Subtotal:   19.98,
+		Total:      19.98,
+	}
+	order.Begin(time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC))
+	return order
+}
+
+// testOrderStore checks the OrderStore contract against any implementation
+func testOrderStore(t *testing.T, s OrderStore) {
+	t.Helper()
+
+	if _, err := s.Get("missing"); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+
+	order := testOrder("order-1")
+	if err := s.Create(order); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := s.Create(testOrder("order-1")); err != ErrOrderExists {
+		t.Errorf("Expected ErrOrderExists for a duplicate ID, got %v", err)
+	}
+
+	stored, err := s.Get("order-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Status != models.StatusPending || len(stored.Items) != 1 || stored.Items[0].Price != 9.99 || stored.Total != 19.98 {
+		t.Errorf("Expected the order as created, got %+v", stored)
+	}
+	if !stored.UpdatedAt.Equal(order.CreatedAt) {
+		t.Errorf("Expected updated_at to default to created_at, got %v", stored.UpdatedAt)
+	}
+
+	updated, err := s.Update("order-1", func(o *models.Order) error {
+		return o.Transition(models.StatusProcessing, time.Now(), "")
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Status != models.StatusProcessing || !updated.UpdatedAt.After(order.CreatedAt) {
+		t.Errorf("Expected a processing order with a new updated_at, got %+v", updated)
+	}
+	s.Update("order-1", func(o *models.Order) error {
+		return o.Transition(models.StatusCompleted, time.Now(), "paid")
+	})
+	stored, _ = s.Get("order-1")
+	if stored.Status != models.StatusCompleted || !stored.CreatedAt.Equal(order.CreatedAt) || len(stored.History) != 3 {
+		t.Errorf("Expected the updates and their history to be stored, got %+v", stored)
+	}
+
+	// The state machine is enforced even when a caller sets the status directly
+	_, err = s.Update("order-1", func(o *models.Order) error {
+		o.Status = models.StatusPending
+		return nil
+	})
+	if !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("Expected completed -> pending to be rejected, got %v", err)
+	}
+
+	abort := errors.New("abort")
+	if _, err := s.Update("order-1", func(o *models.Order) error {
+		o.Status = models.StatusFailed
+		return abort
+	}); err != abort {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+	if stored, _ := s.Get("order-1"); stored.Status != models.StatusCompleted {
+		t.Errorf("Expected an aborted update to change nothing, got %s", stored.Status)
+	}
+	if _, err := s.Update("missing", func(*models.Order) error { return nil }); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+
+	// Updates of one order never lose each other's changes
+	s.Create(testOrder("order-2"))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Update("order-2", func(o *models.Order) error {
+				o.Items[0].Quantity++
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if stored, _ := s.Get("order-2"); stored.Items[0].Quantity != 22 {
+		t.Errorf("Expected quantity 22 after 20 concurrent updates, got %d", stored.Items[0].Quantity)
+	}
+}
+
+func TestMemoryOrderStore(t *testing.T) {
+	testOrderStore(t, NewMemoryOrderStore())
+}
+
+func TestSQLOrderStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.db")
+	s, err := OpenSQLOrderStore(DriverSQLite, path)
+	if err != nil {
+		t.Fatalf("OpenSQLOrderStore() error = %v", err)
+	}
+	testOrderStore(t, s)
+	s.Close()
+
+	// Orders survive reopening, and the products schema can share the file
+	reopened, err := OpenSQLOrderStore(DriverSQLite, path)
+	if err != nil {
+		t.Fatalf("OpenSQLOrderStore() reopen error = %v", err)
+	}
+	defer reopened.Close()
+	if stored, err := reopened.Get("order-1"); err != nil || stored.Status != models.StatusCompleted {
+		t.Errorf("Expected the completed order after reopening, got %+v, %v", stored, err)
+	}
+
+	products, err := OpenSQLProductStore(DriverSQLite, path, nil)
+	if err != nil {
+		t.Fatalf("OpenSQLProductStore() on the orders database error = %v", err)
+	}
+	products.Close()
+}
@@ -0,0 +1,544 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	walFileName      = "products.wal"
+	snapshotFileName = "products.snapshot"
+
+	walOpUpsert = "upsert"
+	walOpDelete = "delete"
+)
+
+// PersistenceOptions configures the file-backed product store
+type PersistenceOptions struct {
+	// Dir holds the write-ahead log and snapshot files
+	Dir string
+
+	// SnapshotInterval takes a compacted snapshot on this period (0 disables)
+	SnapshotInterval time.Duration
+
+	// SnapshotEvery takes a snapshot once the WAL holds this many records (0 disables)
+	SnapshotEvery int
+
+	// Seed is loaded as the initial catalog when Dir holds no data yet (nil disables)
+	Seed *SeedConfig
+}
+
+// walRecord is one line of the write-ahead log
+type walRecord struct {
+	Seq       uint64          `json:"seq"`
+	Op        string          `json:"op"`
+	ProductID int32           `json:"product_id"`
+	Product   *models.Product `json:"product,omitempty"`
+}
+
+// snapshotHeader is the first line of a snapshot file
+type snapshotHeader struct {
+	Seq   uint64 `json:"seq"`
+	Count int32  `json:"count"`
+}
+
+// PersistentProductStore keeps products in an in-memory ProductStore and makes
+// every mutation durable by appending it to a write-ahead log (fsynced before
+// the call returns). The log is periodically compacted into a snapshot.
+// On startup the latest snapshot is loaded and the log is replayed on top.
+type PersistentProductStore struct {
+	mem  *ProductStore
+	opts PersistenceOptions
+
+	mu         sync.Mutex // serializes log appends, in-memory applies and snapshots
+	wal        *os.File
+	seq        uint64 // sequence number of the last logged mutation
+	walRecords int    // records appended since the last snapshot
+	walSize    int64  // bytes of whole records in the WAL
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ ProductRepository = (*PersistentProductStore)(nil)
+
+// OpenPersistentProductStore loads (or creates) a durable product store in opts.Dir
+func OpenPersistentProductStore(opts PersistenceOptions) (*PersistentProductStore, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("persistence directory is required")
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+
+	s := &PersistentProductStore{
+		mem:  NewEmptyProductStore(),
+		opts: opts,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	hasSnapshot, err := s.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, err := s.replayWAL()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Recovered %d products (snapshot=%v, wal records=%d)", s.mem.Count(), hasSnapshot, replayed)
+
+	wal, err := os.OpenFile(s.path(walFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open wal: %w", err)
+	}
+	s.wal = wal
+	s.walRecords = replayed
+
+	// First boot: seed the catalog and persist it as the initial snapshot
+	if !hasSnapshot && replayed == 0 && opts.Seed != nil && opts.Seed.Mode != SeedNone {
+		log.Printf("No persisted data found, seeding %s...", opts.Seed.Describe())
+		if err := s.mem.seed(*opts.Seed); err != nil {
+			wal.Close()
+			return nil, fmt.Errorf("seed products: %w", err)
+		}
+		if err := s.Snapshot(); err != nil {
+			wal.Close()
+			return nil, err
+		}
+	}
+
+	go s.snapshotLoop()
+	return s, nil
+}
+
+func (s *PersistentProductStore) path(name string) string {
+	return filepath.Join(s.opts.Dir, name)
+}
+
+// loadSnapshot reads the snapshot file into memory, if one exists
+func (s *PersistentProductStore) loadSnapshot() (bool, error) {
+	f, err := os.Open(s.path(snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(f))
+
+	var header snapshotHeader
+	if err := decoder.Decode(&header); err != nil {
+		return false, fmt.Errorf("read snapshot header: %w", err)
+	}
+
+	for {
+		var product models.Product
+		if err := decoder.Decode(&product); err == io.EOF {
+			break
+		} else if err != nil {
+			return false, fmt.Errorf("read snapshot: %w", err)
+		}
+		s.mem.restoreProduct(&product)
+	}
+
+	if s.mem.Count() != header.Count {
+		return false, fmt.Errorf("snapshot is incomplete: header says %d products, read %d", header.Count, s.mem.Count())
+	}
+
+	s.seq = header.Seq
+	return true, nil
+}
+
+// replayWAL applies every logged mutation newer than the snapshot.
+// The log ends at the first torn record (the process died mid-append), which
+// is truncated away along with anything after it before new records are
+// appended; such a write was never acknowledged to the caller.
+func (s *PersistentProductStore) replayWAL() (int, error) {
+	f, err := os.OpenFile(s.path(walFileName), os.O_RDWR, 0o644)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("open wal: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	replayed := 0
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return 0, fmt.Errorf("read wal: %w", readErr)
+		}
+		if len(line) == 0 {
+			break
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil || line[len(line)-1] != '\n' {
+			log.Printf("Truncating torn WAL record at offset %d", offset)
+			if err := f.Truncate(offset); err != nil {
+				return 0, fmt.Errorf("truncate wal: %w", err)
+			}
+			if err := f.Sync(); err != nil {
+				return 0, fmt.Errorf("sync wal: %w", err)
+			}
+			break
+		}
+		offset += int64(len(line))
+
+		// Records already folded into the snapshot are skipped
+		if record.Seq > s.seq {
+			if err := s.apply(&record); err != nil {
+				return 0, fmt.Errorf("replay wal record %d: %w", record.Seq, err)
+			}
+			s.seq = record.Seq
+			replayed++
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	s.walSize = offset
+	return replayed, nil
+}
+
+// apply performs a logged mutation against the in-memory store
+func (s *PersistentProductStore) apply(record *walRecord) error {
+	switch record.Op {
+	case walOpUpsert:
+		if record.Product == nil {
+			return errors.New("upsert record without a product")
+		}
+		s.mem.restoreProduct(record.Product)
+		return nil
+	case walOpDelete:
+		return s.mem.DeleteProduct(record.ProductID)
+	}
+	return fmt.Errorf("unknown wal op %q", record.Op)
+}
+
+// appendLocked writes records to the log and fsyncs them once. A failed append
+// is cut off again, so later records never land behind a torn one.
+// Callers hold s.mu.
+func (s *PersistentProductStore) appendLocked(records ...*walRecord) error {
+	var data []byte
+	for i, record := range records {
+		record.Seq = s.seq + uint64(i) + 1
+
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	_, err := s.wal.Write(data)
+	if err != nil {
+		err = fmt.Errorf("append wal: %w", err)
+	} else if err = s.wal.Sync(); err != nil {
+		err = fmt.Errorf("sync wal: %w", err)
+	}
+	if err != nil {
+		if truncErr := s.wal.Truncate(s.walSize); truncErr != nil {
+			log.Printf("Failed to cut off a failed WAL append: %v", truncErr)
+		}
+		return err
+	}
+
+	s.seq += uint64(len(records))
+	s.walRecords += len(records)
+	s.walSize += int64(len(data))
+	return nil
+}
+
+// Snapshot writes all products to a new snapshot file and truncates the WAL
+func (s *PersistentProductStore) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+func (s *PersistentProductStore) snapshotLocked() error {
+	start := time.Now()
+	tmpPath := s.path(snapshotFileName + ".tmp")
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+
+	writer := bufio.NewWriter(f)
+	encoder := json.NewEncoder(writer)
+
+	err = encoder.Encode(snapshotHeader{Seq: s.seq, Count: s.mem.Count()})
+	if err == nil {
+		s.mem.Scan(func(product *models.Product) bool {
+			err = encoder.Encode(product)
+			return err == nil
+		})
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	// Atomically replace the previous snapshot, then drop the WAL records it covers
+	if err := os.Rename(tmpPath, s.path(snapshotFileName)); err != nil {
+		return fmt.Errorf("install snapshot: %w", err)
+	}
+	syncDir(s.opts.Dir)
+
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("truncate wal: %w", err)
+	}
+	s.walRecords = 0
+	s.walSize = 0
+
+	log.Printf("Snapshot of %d products written in %v (seq=%d)", s.mem.Count(), time.Since(start), s.seq)
+	return nil
+}
+
+// maybeSnapshotLocked compacts the WAL once it grows past SnapshotEvery records
+func (s *PersistentProductStore) maybeSnapshotLocked() {
+	if s.opts.SnapshotEvery > 0 && s.walRecords >= s.opts.SnapshotEvery {
+		if err := s.snapshotLocked(); err != nil {
+			log.Printf("Snapshot failed: %v", err)
+		}
+	}
+}
+
+// snapshotLoop takes periodic snapshots until Close is called
+func (s *PersistentProductStore) snapshotLoop() {
+	defer close(s.done)
+
+	if s.opts.SnapshotInterval <= 0 {
+		<-s.stop
+		return
+	}
+
+	ticker := time.NewTicker(s.opts.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.walRecords > 0 {
+				if err := s.snapshotLocked(); err != nil {
+					log.Printf("Periodic snapshot failed: %v", err)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the snapshot loop, writes a final snapshot and closes the WAL
+func (s *PersistentProductStore) Close() error {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.snapshotLocked()
+	if closeErr := s.wal.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// syncDir fsyncs a directory so a rename inside it is durable
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Get implements ProductRepository
+func (s *PersistentProductStore) Get(productID int32) (*models.Product, error) {
+	return s.mem.GetProduct(productID)
+}
+
+// GetMany implements ProductRepository
+func (s *PersistentProductStore) GetMany(productIDs []int32) ([]*models.Product, []int32, error) {
+	products, missing := s.mem.GetProducts(productIDs)
+	return products, missing, nil
+}
+
+// Upsert implements ProductRepository. The write is durable once this returns.
+func (s *PersistentProductStore) Upsert(product *models.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	productCopy := *product
+	productCopy.Version = s.currentVersion(product.ProductID) + 1
+	return s.writeLocked(&productCopy)
+}
+
+// UpsertBatch implements ProductRepository. The whole batch is appended to the
+// WAL with a single fsync, so bulk imports don't pay for one sync per product.
+func (s *PersistentProductStore) UpsertBatch(products []*models.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]*walRecord, len(products))
+	versions := make(map[int32]int64, len(products)) // a product may appear twice in one batch
+	for i, product := range products {
+		version, ok := versions[product.ProductID]
+		if !ok {
+			version = s.currentVersion(product.ProductID)
+		}
+
+		productCopy := *product
+		productCopy.Version = version + 1
+		versions[product.ProductID] = productCopy.Version
+		records[i] = &walRecord{Op: walOpUpsert, ProductID: product.ProductID, Product: &productCopy}
+	}
+
+	if err := s.appendLocked(records...); err != nil {
+		return err
+	}
+	for _, record := range records {
+		s.mem.restoreProduct(record.Product)
+	}
+
+	s.maybeSnapshotLocked()
+	return nil
+}
+
+// CompareAndSwap implements ProductRepository
+func (s *PersistentProductStore) CompareAndSwap(product *models.Product, expectedVersion int64) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.currentVersion(product.ProductID)
+	if current == 0 && expectedVersion != 0 {
+		return nil, ErrProductNotFound
+	}
+	if current != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+
+	productCopy := *product
+	productCopy.Version = current + 1
+	if err := s.writeLocked(&productCopy); err != nil {
+		return nil, err
+	}
+	stored := productCopy
+	return &stored, nil
+}
+
+// currentVersion returns the stored version of a product, or 0 if it does not
+// exist. Callers hold s.mu, which makes the read-modify-write atomic.
+func (s *PersistentProductStore) currentVersion(productID int32) int64 {
+	if current, err := s.mem.GetProduct(productID); err == nil {
+		return current.Version
+	}
+	return 0
+}
+
+// writeLocked logs a fully versioned product and then applies it in memory.
+// Callers hold s.mu.
+func (s *PersistentProductStore) writeLocked(product *models.Product) error {
+	if err := s.appendLocked(&walRecord{Op: walOpUpsert, ProductID: product.ProductID, Product: product}); err != nil {
+		return err
+	}
+	s.mem.restoreProduct(product)
+
+	s.maybeSnapshotLocked()
+	return nil
+}
+
+// Create implements ProductRepository. The ID is assigned under the log lock
+// so it is recorded in the WAL exactly as handed out.
+func (s *PersistentProductStore) Create(product *models.Product) (*models.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	productCopy := *product
+	productCopy.ProductID = s.mem.maxID.Load() + 1
+	productCopy.Version = 1
+	if err := s.writeLocked(&productCopy); err != nil {
+		return nil, err
+	}
+	created := productCopy
+	return &created, nil
+}
+
+// Delete implements ProductRepository
+func (s *PersistentProductStore) Delete(productID int32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.mem.ProductExists(productID) {
+		return ErrProductNotFound
+	}
+	if err := s.appendLocked(&walRecord{Op: walOpDelete, ProductID: productID}); err != nil {
+		return err
+	}
+	s.mem.DeleteProduct(productID)
+
+	s.maybeSnapshotLocked()
+	return nil
+}
+
+// Exists implements ProductRepository
+func (s *PersistentProductStore) Exists(productID int32) bool {
+	return s.mem.ProductExists(productID)
+}
+
+// Count implements ProductRepository
+func (s *PersistentProductStore) Count() int32 {
+	return s.mem.GetProductCount()
+}
+
+// Scan implements ProductRepository
+func (s *PersistentProductStore) Scan(fn func(product *models.Product) bool) error {
+	return s.mem.Scan(fn)
+}
+
+// List implements ProductRepository
+func (s *PersistentProductStore) List(req ListRequest) (*models.ProductListResponse, error) {
+	return s.mem.List(req)
+}
+
+// Search implements ProductRepository
+func (s *PersistentProductStore) Search(req SearchRequest) (*models.SearchResponse, error) {
+	return s.mem.Search(req)
+}
+
+// Suggest implements ProductRepository
+func (s *PersistentProductStore) Suggest(prefix string, limit int) ([]models.Suggestion, error) {
+	return s.mem.Suggest(prefix, limit)
+}
@@ -0,0 +1,312 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testProduct(id int32, sku string) *models.Product {
+	return &models.Product{
+		ProductID:    id,
+		SKU:          sku,
+		Manufacturer: "Test Manufacturer",
+		CategoryID:   1,
+		Weight:       100,
+		SomeOtherID:  1,
+		Name:         fmt.Sprintf("Test Product %d", id),
+		Category:     "Electronics",
+		Description:  "Test description",
+		Brand:        "TestBrand",
+		Price:        9.99,
+	}
+}
+
+func TestPersistentProductStore_ReopenRestoresWrites(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	for i := int32(1); i <= 5; i++ {
+		if err := s.Upsert(testProduct(i, "ABC")); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+	s.Upsert(testProduct(3, "UPDATED"))
+	s.Delete(5)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if reopened.Count() != 4 {
+		t.Errorf("Expected 4 products after reopen, got %d", reopened.Count())
+	}
+	product, err := reopened.Get(3)
+	if err != nil || product.SKU != "UPDATED" {
+		t.Errorf("Expected product 3 to be updated, got %+v (err=%v)", product, err)
+	}
+	if reopened.Exists(5) {
+		t.Error("Deleted product 5 should stay deleted after reopen")
+	}
+}
+
+// Without Close there is no final snapshot, so recovery relies on WAL replay
+func TestPersistentProductStore_ReplaysWALWithoutClose(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir, SnapshotEvery: 3})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	for i := int32(1); i <= 7; i++ {
+		s.Upsert(testProduct(i, "ABC"))
+	}
+	s.wal.Close() // simulate a crash: no final snapshot
+
+	reopened, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if reopened.Count() != 7 {
+		t.Errorf("Expected 7 products from snapshot + WAL, got %d", reopened.Count())
+	}
+}
+
+func TestPersistentProductStore_TruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	s.Upsert(testProduct(1, "ABC"))
+	s.wal.Close()
+
+	// Append half a record, as if the process died mid-write
+	f, _ := os.OpenFile(filepath.Join(dir, walFileName), os.O_WRONLY|os.O_APPEND, 0o644)
+	f.WriteString(`{"seq":2,"op":"upsert","product_id":2,"product":{"product_id":2,"sku":"TO`)
+	f.Close()
+
+	reopened, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if reopened.Count() != 1 || reopened.Exists(2) {
+		t.Errorf("Expected only product 1 after dropping the torn record, got count %d", reopened.Count())
+	}
+
+	// The log must stay appendable after truncation
+	if err := reopened.Upsert(testProduct(2, "DEF")); err != nil {
+		t.Fatalf("Upsert() after recovery error = %v", err)
+	}
+	reopened.wal.Close()
+
+	again, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("second reopen error = %v", err)
+	}
+	defer again.Close()
+	if again.Count() != 2 {
+		t.Errorf("Expected 2 products, got %d", again.Count())
+	}
+}
+
+func TestPersistentProductStore_TruncatesAtFirstTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	// A torn record followed by a later one, as left by a failed append
+	wal := `{"seq":1,"op":"upsert","product_id":1,"product":{"product_id":1,"sku":"ABC"}}
+{"seq":2,"op":"upsert","product_id":2,"product":{"product_id":2,"sku":"TO
+{"seq":3,"op":"upsert","product_id":3,"product":{"product_id":3,"sku":"GHI"}}
+`
+	if err := os.WriteFile(filepath.Join(dir, walFileName), []byte(wal), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	if s.Count() != 1 || !s.Exists(1) {
+		t.Errorf("Expected only product 1 before the torn record, got count %d", s.Count())
+	}
+	if err := s.Upsert(testProduct(4, "JKL")); err != nil {
+		t.Fatalf("Upsert() after recovery error = %v", err)
+	}
+	s.wal.Close()
+
+	reopened, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+	if reopened.Count() != 2 || !reopened.Exists(4) || reopened.Exists(3) {
+		t.Errorf("Expected products 1 and 4, got count %d", reopened.Count())
+	}
+}
+
+func TestPersistentProductStore_ReplayReportsApplyErrors(t *testing.T) {
+	for name, wal := range map[string]string{
+		"unknown op":        `{"seq":1,"op":"rename","product_id":1}`,
+		"missing product":   `{"seq":1,"op":"upsert","product_id":1}`,
+		"delete of missing": `{"seq":1,"op":"delete","product_id":1}`,
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, walFileName), []byte(wal+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir}); err == nil {
+			s.Close()
+			t.Errorf("%s: expected the replay to fail", name)
+		}
+	}
+}
+
+// TestPersistentStoreHelperProcess is not a real test: it is the child process
+// for TestPersistentProductStore_SurvivesKill. It writes products forever and
+// prints each acknowledged ID until the parent kills it with SIGKILL.
+func TestPersistentStoreHelperProcess(t *testing.T) {
+	dir := os.Getenv("PERSISTENT_STORE_HELPER_DIR")
+	if dir == "" {
+		return
+	}
+
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir, SnapshotEvery: 50})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for i := int32(1); ; i++ {
+		if err := s.Upsert(testProduct(i, "ABC")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("ack %d\n", i)
+	}
+}
+
+func TestPersistentProductStore_SurvivesKill(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping crash-recovery test in short mode")
+	}
+	dir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPersistentStoreHelperProcess$")
+	cmd.Env = append(os.Environ(), "PERSISTENT_STORE_HELPER_DIR="+dir)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Collect acknowledgements, then kill -9 the writer mid-stream
+	acked := make([]int32, 0, 300)
+	scanner := bufio.NewScanner(stdout)
+	for len(acked) < 300 && scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "ack ") {
+			continue
+		}
+		id, _ := strconv.Atoi(strings.TrimPrefix(line, "ack "))
+		acked = append(acked, int32(id))
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+
+	if len(acked) < 300 {
+		t.Fatalf("helper acknowledged only %d writes", len(acked))
+	}
+
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("recovery error = %v", err)
+	}
+	defer s.Close()
+
+	for _, id := range acked {
+		if !s.Exists(id) {
+			t.Fatalf("acknowledged product %d was lost after kill -9", id)
+		}
+	}
+}
+
+func TestPersistentProductStore_Create(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	testCreate(t, s)
+	s.Close()
+
+	// Assigned IDs survive a restart and numbering continues after them
+	reopened, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if created, _ := reopened.Create(testProduct(0, "NEW3")); created.ProductID != 44 {
+		t.Errorf("Expected ID 44 after reopening, got %d", created.ProductID)
+	}
+}
+
+func TestPersistentProductStore_CompareAndSwap(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	testCompareAndSwap(t, s)
+	s.Close()
+
+	// Versions are persisted rather than restarted on recovery
+	reopened, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if stored, _ := reopened.Get(1); stored.Version != 3 {
+		t.Errorf("Expected version 3 after reopening, got %d", stored.Version)
+	}
+}
+
+func TestPersistentProductStore_UpsertBatch(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenPersistentProductStore() error = %v", err)
+	}
+	testUpsertBatch(t, s)
+	s.Close()
+
+	// The whole batch is in the WAL
+	reopened, err := OpenPersistentProductStore(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if stored, _ := reopened.Get(2); stored.SKU != "SECOND" || stored.Version != 2 {
+		t.Errorf("Expected product 2 at version 2 after reopening, got %+v", stored)
+	}
+}
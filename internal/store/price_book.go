@@ -0,0 +1,68 @@
+package store
+
+import "time"
+
+// PriceBook is where orders get their prices from. Prices are looked up
+// for a whole order at once.
+type PriceBook interface {
+	// Prices returns the current price of each product that exists and the
+	// IDs of those that do not
+	Prices(productIDs []int32) (prices map[int32]float64, missing []int32, err error)
+}
+
+// CatalogPriceBook prices products at the price stored on the product
+type CatalogPriceBook struct {
+	products ProductRepository
+}
+
+var _ PriceBook = (*CatalogPriceBook)(nil)
+
+// NewCatalogPriceBook creates a price book reading from the product catalog
+func NewCatalogPriceBook(products ProductRepository) *CatalogPriceBook {
+	return &CatalogPriceBook{products: products}
+}
+
+// Prices implements PriceBook
+func (b *CatalogPriceBook) Prices(productIDs []int32) (map[int32]float64, []int32, error) {
+	products, missing, err := b.products.GetMany(productIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prices := make(map[int32]float64, len(products))
+	for _, p := range products {
+		prices[p.ProductID] = p.Price
+	}
+	return prices, missing, nil
+}
+
+// ScheduledPriceBook prices products at their catalog price, or at a
+// scheduled price that is already due but not yet applied to the catalog
+type ScheduledPriceBook struct {
+	catalog PriceBook
+	history *PriceHistory
+}
+
+var _ PriceBook = (*ScheduledPriceBook)(nil)
+
+// NewScheduledPriceBook creates a price book that honours due price changes
+// ahead of the scheduler
+func NewScheduledPriceBook(catalog PriceBook, history *PriceHistory) *ScheduledPriceBook {
+	return &ScheduledPriceBook{catalog: catalog, history: history}
+}
+
+// Prices implements PriceBook
+func (b *ScheduledPriceBook) Prices(productIDs []int32) (map[int32]float64, []int32, error) {
+	prices, missing, err := b.catalog.Prices(productIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	for productID := range prices {
+		if price, ok := b.history.Pending(productID, now); ok {
+			prices[productID] = price
+		}
+	}
+	return prices, missing, nil
+}
@@ -0,0 +1,26 @@
+package store
+
+import "testing"
+
+func TestCatalogPriceBook_Prices(t *testing.T) {
+	products := NewEmptyProductStore()
+	for _, p := range []struct {
+		id    int32
+		price float64
+	}{{1, 9.99}, {2, 0}} {
+		product := testProduct(p.id, "SKU")
+		product.Price = p.price
+		products.Upsert(product)
+	}
+
+	prices, missing, err := NewCatalogPriceBook(products).Prices([]int32{1, 2, 3})
+	if err != nil {
+		t.Fatalf("Prices() error = %v", err)
+	}
+	if len(prices) != 2 || prices[1] != 9.99 || prices[2] != 0 {
+		t.Errorf("Expected prices 9.99 and 0, got %v", prices)
+	}
+	if len(missing) != 1 || missing[0] != 3 {
+		t.Errorf("Expected 3 to be missing, got %v", missing)
+	}
+}
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPriceChangeNotFound = errors.New("price change not found")
+	ErrPriceChangeFinal    = errors.New("only scheduled price changes can be canceled")
+	ErrPriceNotFuture      = errors.New("effective_from must be in the future")
+)
+
+// PriceHistory keeps every product's price timeline: the prices it had, when
+// each took effect, and changes scheduled for later. The catalog price on the
+// product stays the price most reads use; a PriceScheduler moves it to each
+// scheduled price once that price is due.
+//
+// When opened with a file path, every change rewrites that file.
+type PriceHistory struct {
+	mu      sync.RWMutex
+	changes map[int32][]models.PriceChange // product ID -> timeline ordered by EffectiveFrom, then ID
+	maxID   int32
+
+	path string // JSON file backing the history, or "" for memory only
+}
+
+// NewPriceHistory creates an empty in-memory price history
+func NewPriceHistory() *PriceHistory {
+	return &PriceHistory{changes: make(map[int32][]models.PriceChange)}
+}
+
+// OpenPriceHistory loads the price history from path, starting empty if the
+// file does not exist yet, and saves every change back to it
+func OpenPriceHistory(path string) (*PriceHistory, error) {
+	h := NewPriceHistory()
+	h.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read price history: %w", err)
+	}
+
+	var changes []models.PriceChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return nil, fmt.Errorf("decode price history %s: %w", path, err)
+	}
+	for _, change := range changes {
+		h.changes[change.ProductID] = append(h.changes[change.ProductID], change)
+		if change.ID > h.maxID {
+			h.maxID = change.ID
+		}
+	}
+	for _, timeline := range h.changes {
+		sortTimeline(timeline)
+	}
+	return h, nil
+}
+
+// Record adds a price the catalog was set to at the given time. Scheduled
+// changes that were already due by then are superseded by it.
+func (h *PriceHistory) Record(productID int32, price float64, at time.Time) (models.PriceChange, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous := h.backupLocked(productID)
+	timeline := h.changes[productID]
+	for i := range timeline {
+		if timeline[i].Status == models.PriceScheduled && !timeline[i].EffectiveFrom.After(at) {
+			timeline[i].Status = models.PriceSuperseded
+		}
+	}
+
+	change := h.addLocked(productID, price, at, models.PriceApplied, at)
+	if err := h.saveLocked(); err != nil {
+		h.restoreLocked(productID, previous)
+		return models.PriceChange{}, err
+	}
+	return change, nil
+}
+
+// Schedule adds a price change that takes effect at effectiveFrom, which must
+// be later than now
+func (h *PriceHistory) Schedule(productID int32, price float64, effectiveFrom, now time.Time) (models.PriceChange, error) {
+	if !effectiveFrom.After(now) {
+		return models.PriceChange{}, ErrPriceNotFuture
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous := h.backupLocked(productID)
+	change := h.addLocked(productID, price, effectiveFrom, models.PriceScheduled, now)
+	if err := h.saveLocked(); err != nil {
+		h.restoreLocked(productID, previous)
+		return models.PriceChange{}, err
+	}
+	return change, nil
+}
+
+// Cancel removes a scheduled price change. Changes that have taken effect
+// (or were superseded) are history and cannot be canceled; that includes a
+// change already due at now, which readers serve before the scheduler
+// applies it.
+func (h *PriceHistory) Cancel(productID, changeID int32, now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	timeline := h.changes[productID]
+	for i, change := range timeline {
+		if change.ID != changeID {
+			continue
+		}
+		if change.Status != models.PriceScheduled || !change.EffectiveFrom.After(now) {
+			return ErrPriceChangeFinal
+		}
+
+		previous := h.backupLocked(productID)
+		h.changes[productID] = append(timeline[:i:i], timeline[i+1:]...)
+		if err := h.saveLocked(); err != nil {
+			h.restoreLocked(productID, previous)
+			return err
+		}
+		return nil
+	}
+	return ErrPriceChangeNotFound
+}
+
+// Timeline returns the product's price changes, oldest first
+func (h *PriceHistory) Timeline(productID int32) []models.PriceChange {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]models.PriceChange{}, h.changes[productID]...)
+}
+
+// Pending returns the price of a scheduled change that is due at now but has
+// not reached the catalog yet. Readers use it to serve the effective price in
+// the moments before the scheduler applies it.
+func (h *PriceHistory) Pending(productID int32, now time.Time) (float64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	change, ok := dueLocked(h.changes[productID], now)
+	if !ok {
+		return 0, false
+	}
+	return change.Price, true
+}
+
+// Due returns, for every product, the scheduled change that should be its
+// price at now. Earlier scheduled changes that are also due are skipped: only
+// the latest one matters.
+func (h *PriceHistory) Due(now time.Time) []models.PriceChange {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var due []models.PriceChange
+	for _, timeline := range h.changes {
+		if change, ok := dueLocked(timeline, now); ok {
+			due = append(due, change)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	return due
+}
+
+// MarkApplied records that the catalog now carries a scheduled change. Earlier
+// scheduled changes of the product that never reached the catalog are marked
+// superseded. Changes that are no longer scheduled are left alone.
+func (h *PriceHistory) MarkApplied(productID, changeID int32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	timeline := h.changes[productID]
+	at := -1
+	for i := range timeline {
+		if timeline[i].ID == changeID && timeline[i].Status == models.PriceScheduled {
+			at = i
+		}
+	}
+	if at < 0 {
+		return nil
+	}
+
+	previous := h.backupLocked(productID)
+	timeline[at].Status = models.PriceApplied
+	for i := 0; i < at; i++ {
+		if timeline[i].Status == models.PriceScheduled {
+			timeline[i].Status = models.PriceSuperseded
+		}
+	}
+	if err := h.saveLocked(); err != nil {
+		h.restoreLocked(productID, previous)
+		return err
+	}
+	return nil
+}
+
+// dueLocked returns the latest change in the timeline that starts by now, if
+// it is still scheduled
+func dueLocked(timeline []models.PriceChange, now time.Time) (models.PriceChange, bool) {
+	for i := len(timeline) - 1; i >= 0; i-- {
+		if timeline[i].EffectiveFrom.After(now) || timeline[i].Status == models.PriceSuperseded {
+			continue
+		}
+		return timeline[i], timeline[i].Status == models.PriceScheduled
+	}
+	return models.PriceChange{}, false
+}
+
+// addLocked appends a change to the product's timeline. Callers hold h.mu.
+func (h *PriceHistory) addLocked(productID int32, price float64, effectiveFrom time.Time, status string, now time.Time) models.PriceChange {
+	h.maxID++
+	change := models.PriceChange{
+		ID:            h.maxID,
+		ProductID:     productID,
+		Price:         price,
+		EffectiveFrom: effectiveFrom.UTC(),
+		Status:        status,
+		CreatedAt:     now.UTC(),
+	}
+	h.changes[productID] = append(h.changes[productID], change)
+	sortTimeline(h.changes[productID])
+	return change
+}
+
+// backupLocked copies the product's timeline so a failed save can be undone
+func (h *PriceHistory) backupLocked(productID int32) []models.PriceChange {
+	return append([]models.PriceChange(nil), h.changes[productID]...)
+}
+
+// restoreLocked puts back a timeline saved by backupLocked
+func (h *PriceHistory) restoreLocked(productID int32, timeline []models.PriceChange) {
+	if len(timeline) == 0 {
+		delete(h.changes, productID)
+		return
+	}
+	h.changes[productID] = timeline
+}
+
+// saveLocked rewrites the backing file, if there is one. Callers hold h.mu.
+func (h *PriceHistory) saveLocked() error {
+	if h.path == "" {
+		return nil
+	}
+
+	changes := []models.PriceChange{}
+	for _, timeline := range h.changes {
+		changes = append(changes, timeline...)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return writeJSONFile(h.path, changes)
+}
+
+// sortTimeline orders a timeline by start time, breaking ties by ID
+func sortTimeline(timeline []models.PriceChange) {
+	sort.SliceStable(timeline, func(i, j int) bool {
+		if !timeline[i].EffectiveFrom.Equal(timeline[j].EffectiveFrom) {
+			return timeline[i].EffectiveFrom.Before(timeline[j].EffectiveFrom)
+		}
+		return timeline[i].ID < timeline[j].ID
+	})
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPriceHistory_ScheduleAndDue(t *testing.T) {
+	h := NewPriceHistory()
+	now := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := h.Record(1, 20, now); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if _, err := h.Schedule(1, 15, now, now); err != ErrPriceNotFuture {
+		t.Errorf("Expected ErrPriceNotFuture for a change starting now, got %v", err)
+	}
+	early, _ := h.Schedule(1, 18, now.Add(time.Hour), now)
+	late, _ := h.Schedule(1, 15, now.Add(2*time.Hour), now)
+
+	if _, ok := h.Pending(1, now.Add(30*time.Minute)); ok {
+		t.Error("Expected no pending price before the first change starts")
+	}
+	if price, ok := h.Pending(1, now.Add(3*time.Hour)); !ok || price != 15 {
+		t.Errorf("Expected the latest due change (15) to be pending, got %v, %v", price, ok)
+	}
+
+	due := h.Due(now.Add(3 * time.Hour))
+	if len(due) != 1 || due[0].ID != late.ID {
+		t.Fatalf("Expected only the latest due change, got %+v", due)
+	}
+	if err := h.MarkApplied(1, late.ID); err != nil {
+		t.Fatalf("MarkApplied() error = %v", err)
+	}
+
+	statuses := map[int32]string{}
+	for _, change := range h.Timeline(1) {
+		statuses[change.ID] = change.Status
+	}
+	if statuses[early.ID] != models.PriceSuperseded || statuses[late.ID] != models.PriceApplied {
+		t.Errorf("Expected the skipped change superseded and the latest applied, got %v", statuses)
+	}
+	if len(h.Due(now.Add(3*time.Hour))) != 0 {
+		t.Error("Expected nothing due after applying")
+	}
+}
+
+func TestPriceHistory_RecordSupersedesDueChanges(t *testing.T) {
+	h := NewPriceHistory()
+	now := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	scheduled, _ := h.Schedule(1, 15, now.Add(time.Minute), now)
+	future, _ := h.Schedule(1, 10, now.Add(time.Hour), now)
+
+	// An edit made after the change was due but before the scheduler ran wins
+	if _, err := h.Record(1, 25, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if _, ok := h.Pending(1, now.Add(3*time.Minute)); ok {
+		t.Error("Expected the edit to leave nothing pending")
+	}
+
+	timeline := h.Timeline(1)
+	if len(timeline) != 3 || timeline[0].ID != scheduled.ID || timeline[0].Status != models.PriceSuperseded {
+		t.Errorf("Expected the overtaken change to be superseded, got %+v", timeline)
+	}
+	if timeline[2].ID != future.ID || timeline[2].Status != models.PriceScheduled {
+		t.Errorf("Expected the future change to stay scheduled, got %+v", timeline[2])
+	}
+}
+
+func TestPriceHistory_Cancel(t *testing.T) {
+	h := NewPriceHistory()
+	now := time.Now()
+
+	applied, _ := h.Record(1, 20, now)
+	scheduled, _ := h.Schedule(1, 15, now.Add(time.Hour), now)
+
+	if err := h.Cancel(1, applied.ID, now); err != ErrPriceChangeFinal {
+		t.Errorf("Expected ErrPriceChangeFinal for an applied change, got %v", err)
+	}
+	if err := h.Cancel(2, scheduled.ID, now); err != ErrPriceChangeNotFound {
+		t.Errorf("Expected ErrPriceChangeNotFound for another product's change, got %v", err)
+	}
+	if err := h.Cancel(1, scheduled.ID, now.Add(time.Hour)); err != ErrPriceChangeFinal {
+		t.Errorf("Expected ErrPriceChangeFinal for a due change, got %v", err)
+	}
+	if err := h.Cancel(1, scheduled.ID, now); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if timeline := h.Timeline(1); len(timeline) != 1 || timeline[0].ID != applied.ID {
+		t.Errorf("Expected only the applied change to remain, got %+v", timeline)
+	}
+}
+
+func TestPriceHistory_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	now := time.Now()
+
+	h, err := OpenPriceHistory(path)
+	if err != nil {
+		t.Fatalf("OpenPriceHistory() error = %v", err)
+	}
+	h.Record(1, 20, now)
+	h.Schedule(1, 15, now.Add(time.Hour), now)
+
+	reopened, err := OpenPriceHistory(path)
+	if err != nil {
+		t.Fatalf("OpenPriceHistory() reopen error = %v", err)
+	}
+	timeline := reopened.Timeline(1)
+	if len(timeline) != 2 || timeline[0].Price != 20 || timeline[1].Status != models.PriceScheduled {
+		t.Errorf("Expected the timeline to survive reopening, got %+v", timeline)
+	}
+	if next, _ := reopened.Schedule(2, 5, now.Add(time.Hour), now); next.ID != 3 {
+		t.Errorf("Expected numbering to continue at 3, got %d", next.ID)
+	}
+}
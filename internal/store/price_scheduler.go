@@ -0,0 +1,103 @@
+package store
+
+import (
+	"log"
+	"time"
+)
+
+// PriceScheduler moves catalog prices to scheduled price changes once they
+// are due. It checks the price history every interval, so a change reaches
+// the catalog at most one interval late; PriceHistory.Pending covers the gap
+// for reads that need the exact price.
+type PriceScheduler struct {
+	history  *PriceHistory
+	products ProductRepository
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPriceScheduler creates a scheduler that is not running yet
+func NewPriceScheduler(history *PriceHistory, products ProductRepository, interval time.Duration) *PriceScheduler {
+	return &PriceScheduler{
+		history:  history,
+		products: products,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start applies the changes that are already due and then keeps applying
+// them in the background until Close is called
+func (s *PriceScheduler) Start() {
+	if _, err := s.ApplyDue(time.Now()); err != nil {
+		log.Printf("Applying scheduled prices failed: %v", err)
+	}
+	go s.loop()
+}
+
+// loop applies due changes every interval until Close is called
+func (s *PriceScheduler) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			if _, err := s.ApplyDue(now); err != nil {
+				log.Printf("Applying scheduled prices failed: %v", err)
+			}
+		}
+	}
+}
+
+// Close stops the background loop
+func (s *PriceScheduler) Close() {
+	close(s.stop)
+	<-s.done
+}
+
+// ApplyDue sets the catalog price of every product with a scheduled change due
+// at now and returns how many products were updated. The write is a
+// compare-and-swap, so a product edited at the same moment is retried on the
+// next run rather than overwritten.
+func (s *PriceScheduler) ApplyDue(now time.Time) (int, error) {
+	applied := 0
+	for _, change := range s.history.Due(now) {
+		product, err := s.products.Get(change.ProductID)
+		if err == ErrProductNotFound {
+			// Deleted products keep their history; there is no price to move
+			if err := s.history.MarkApplied(change.ProductID, change.ID); err != nil {
+				return applied, err
+			}
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+
+		if product.Price != change.Price {
+			version := product.Version
+			product.Price = change.Price
+			_, err := s.products.CompareAndSwap(product, version)
+			if err == ErrVersionConflict || err == ErrProductNotFound {
+				continue
+			}
+			if err != nil {
+				return applied, err
+			}
+			applied++
+		}
+
+		if err := s.history.MarkApplied(change.ProductID, change.ID); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"testing"
+	"time"
+)
+
+func TestPriceScheduler_ApplyDue(t *testing.T) {
+	products := NewEmptyProductStore()
+	product := testProduct(1, "SKU-1")
+	product.Price = 20
+	products.Upsert(product)
+
+	history := NewPriceHistory()
+	now := time.Now()
+	change, _ := history.Schedule(1, 15, now.Add(time.Hour), now)
+	history.Schedule(2, 5, now.Add(time.Hour), now) // product 2 does not exist
+
+	scheduler := NewPriceScheduler(history, products, time.Minute)
+	if applied, err := scheduler.ApplyDue(now); err != nil || applied != 0 {
+		t.Fatalf("Expected nothing applied before the change is due, got %d, %v", applied, err)
+	}
+
+	applied, err := scheduler.ApplyDue(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("ApplyDue() error = %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("Expected one product updated, got %d", applied)
+	}
+
+	stored, _ := products.Get(1)
+	if stored.Price != 15 || stored.Version != 2 {
+		t.Errorf("Expected price 15 at version 2, got %v at version %d", stored.Price, stored.Version)
+	}
+	if timeline := history.Timeline(1); timeline[0].ID != change.ID || timeline[0].Status != models.PriceApplied {
+		t.Errorf("Expected the change to be marked applied, got %+v", timeline)
+	}
+	if due := history.Due(now.Add(2 * time.Hour)); len(due) != 0 {
+		t.Errorf("Expected nothing left due, got %+v", due)
+	}
+}
+
+func TestScheduledPriceBook_Prices(t *testing.T) {
+	products := NewEmptyProductStore()
+	for _, id := range []int32{1, 2} {
+		product := testProduct(id, "SKU")
+		product.Price = 20
+		products.Upsert(product)
+	}
+
+	history := NewPriceHistory()
+	history.Schedule(1, 15, time.Now().Add(time.Millisecond), time.Now())
+	history.Schedule(2, 10, time.Now().Add(time.Hour), time.Now())
+	time.Sleep(5 * time.Millisecond)
+
+	prices, _, err := NewScheduledPriceBook(NewCatalogPriceBook(products), history).Prices([]int32{1, 2})
+	if err != nil {
+		t.Fatalf("Prices() error = %v", err)
+	}
+	if prices[1] != 15 || prices[2] != 20 {
+		t.Errorf("Expected the due price for 1 and the catalog price for 2, got %v", prices)
+	}
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"sort"
+	"strings"
+)
+
+// ListRequest browses the catalog in product_id order
+type ListRequest struct {
+	Filter SearchFilter
+	After  int32 // keyset cursor: only products with a greater ID are returned
+	Limit  int
+}
+
+// secondaryIndexes keep product IDs sorted per category, brand and
+// manufacturer so a listing walks only the products that can match instead of
+// the whole catalog. ProductStore guards them with index.mu.
+type secondaryIndexes struct {
+	ids            []int32 // every product ID
+	byCategory     idIndex
+	byBrand        idIndex
+	byManufacturer idIndex
+}
+
+// idIndex maps a lowercased field value to the sorted IDs of products holding it
+type idIndex map[string][]int32
+
+func newSecondaryIndexes() *secondaryIndexes {
+	return &secondaryIndexes{byCategory: idIndex{}, byBrand: idIndex{}, byManufacturer: idIndex{}}
+}
+
+func (x *secondaryIndexes) add(p *models.Product) {
+	x.ids = insertID(x.ids, p.ProductID)
+	x.byCategory.add(p.Category, p.ProductID)
+	x.byBrand.add(p.Brand, p.ProductID)
+	x.byManufacturer.add(p.Manufacturer, p.ProductID)
+}
+
+// update moves a rewritten product between the field indexes whose value
+// changed. Its ID is already in ids, so updates never shift that list.
+func (x *secondaryIndexes) update(previous, p *models.Product) {
+	x.byCategory.move(previous.Category, p.Category, p.ProductID)
+	x.byBrand.move(previous.Brand, p.Brand, p.ProductID)
+	x.byManufacturer.move(previous.Manufacturer, p.Manufacturer, p.ProductID)
+}
+
+func (x *secondaryIndexes) remove(p *models.Product) {
+	x.ids = removeID(x.ids, p.ProductID)
+	x.byCategory.remove(p.Category, p.ProductID)
+	x.byBrand.remove(p.Brand, p.ProductID)
+	x.byManufacturer.remove(p.Manufacturer, p.ProductID)
+}
+
+// candidates returns the shortest sorted ID list that holds every product
+// passing the filter. Weight has no index, so it is always checked per product.
+func (x *secondaryIndexes) candidates(f SearchFilter) []int32 {
+	best := x.ids
+	for _, field := range []struct {
+		index  idIndex
+		values []string
+	}{
+		{x.byCategory, f.Categories},
+		{x.byBrand, f.Brands},
+		{x.byManufacturer, f.Manufacturers},
+	} {
+		if len(field.values) == 0 {
+			continue
+		}
+		if ids := field.index.union(field.values); len(ids) < len(best) {
+			best = ids
+		}
+	}
+	return best
+}
+
+func (x idIndex) add(value string, id int32) {
+	key := strings.ToLower(value)
+	x[key] = insertID(x[key], id)
+}
+
+func (x idIndex) remove(value string, id int32) {
+	key := strings.ToLower(value)
+	if ids := removeID(x[key], id); len(ids) > 0 {
+		x[key] = ids
+	} else {
+		delete(x, key)
+	}
+}
+
+// move reindexes id from one value to another, if they differ
+func (x idIndex) move(from, to string, id int32) {
+	if strings.ToLower(from) == strings.ToLower(to) {
+		return
+	}
+	x.remove(from, id)
+	x.add(to, id)
+}
+
+// union returns the sorted IDs holding any of values
+func (x idIndex) union(values []string) []int32 {
+	if len(values) == 1 {
+		return x[strings.ToLower(values[0])]
+	}
+
+	seen := make(map[string]bool, len(values))
+	var ids []int32
+	for _, value := range values {
+		key := strings.ToLower(value)
+		if !seen[key] {
+			seen[key] = true
+			ids = append(ids, x[key]...)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// insertID adds id to a sorted slice unless it is already there. IDs usually
+// arrive in increasing order, which appends without moving anything.
+func insertID(ids []int32, id int32) []int32 {
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+	if i < len(ids) && ids[i] == id {
+		return ids
+	}
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	return ids
+}
+
+// removeID drops id from a sorted slice
+func removeID(ids []int32, id int32) []int32 {
+	i := sort.Search(len(ids), func(i int) bool { return ids[i] >= id })
+	if i == len(ids) || ids[i] != id {
+		return ids
+	}
+	return append(ids[:i], ids[i+1:]...)
+}
+
+// List returns up to req.Limit products passing the filter with IDs above
+// req.After, in product_id order. It walks the secondary index of the most
+// selective filtered field, so browsing a category only touches that category.
+func (s *ProductStore) List(req ListRequest) (*models.ProductListResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	s.index.mu.RLock()
+	defer s.index.mu.RUnlock()
+
+	candidates := s.secondary.candidates(req.Filter)
+	start := sort.Search(len(candidates), func(i int) bool { return candidates[i] > req.After })
+
+	response := &models.ProductListResponse{Products: make([]models.Product, 0, limit)}
+	for _, id := range candidates[start:] {
+		value, exists := s.products.Load(id)
+		if !exists {
+			continue
+		}
+		product := value.(*models.Product)
+		if !req.Filter.matches(product) {
+			continue
+		}
+		if len(response.Products) == limit {
+			response.NextAfter = response.Products[limit-1].ProductID
+			break
+		}
+		response.Products = append(response.Products, *product)
+	}
+	return response, nil
+}
@@ -0,0 +1,128 @@
+package store
+
+import "testing"
+
+// testList checks filtered keyset listing against any repository
+func testList(t *testing.T, repo ProductRepository) {
+	t.Helper()
+
+	for i := int32(1); i <= 10; i++ {
+		p := testProduct(i, "SKU")
+		p.Category = []string{"Home", "Toys"}[i%2]
+		p.Manufacturer = []string{"Acme", "Globex", "Initech"}[i%3]
+		p.Weight = i * 10
+		repo.Upsert(p)
+	}
+
+	// Page through Home (even IDs) two at a time
+	var got []int32
+	after := int32(0)
+	for pages := 0; pages < 10; pages++ {
+		page, err := repo.List(ListRequest{Filter: SearchFilter{Categories: []string{"home"}}, After: after, Limit: 2})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		for _, p := range page.Products {
+			got = append(got, p.ProductID)
+		}
+		if page.NextAfter == 0 {
+			break
+		}
+		after = page.NextAfter
+	}
+	if want := []int32{2, 4, 6, 8, 10}; !equalIDs(got, want) {
+		t.Errorf("Expected Home products %v across pages, got %v", want, got)
+	}
+
+	min, max := int32(30), int32(90)
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []int32
+	}{
+		{"no filter", SearchFilter{}, []int32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{"manufacturers", SearchFilter{Manufacturers: []string{"ACME", "initech"}}, []int32{2, 3, 5, 6, 8, 9}},
+		{"category and manufacturer", SearchFilter{Categories: []string{"Toys"}, Manufacturers: []string{"Globex"}}, []int32{1, 7}},
+		{"weight range", SearchFilter{MinWeight: &min, MaxWeight: &max}, []int32{3, 4, 5, 6, 7, 8, 9}},
+		{"unknown brand", SearchFilter{Brands: []string{"Nope"}}, nil},
+	}
+	for _, tt := range tests {
+		page, err := repo.List(ListRequest{Filter: tt.filter, Limit: 100})
+		if err != nil {
+			t.Fatalf("%s: List() error = %v", tt.name, err)
+		}
+		var ids []int32
+		for _, p := range page.Products {
+			ids = append(ids, p.ProductID)
+		}
+		if !equalIDs(ids, tt.want) || page.NextAfter != 0 {
+			t.Errorf("%s: expected %v, got %v (next_after %d)", tt.name, tt.want, ids, page.NextAfter)
+		}
+	}
+}
+
+func equalIDs(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestProductStore_List(t *testing.T) {
+	testList(t, NewEmptyProductStore())
+}
+
+func TestSQLProductStore_List(t *testing.T) {
+	testList(t, newTestSQLStore(t))
+}
+
+func TestProductStore_ListFollowsWrites(t *testing.T) {
+	store := NewEmptyProductStore()
+	store.AddOrUpdateProduct(testProduct(1, "A"))
+	store.AddOrUpdateProduct(testProduct(2, "B"))
+
+	// Moving a product to another category moves it between index entries
+	moved := testProduct(1, "A")
+	moved.Category = "Garden"
+	store.AddOrUpdateProduct(moved)
+	store.DeleteProduct(2)
+
+	list := func(category string) []int32 {
+		page, _ := store.List(ListRequest{Filter: SearchFilter{Categories: []string{category}}})
+		var ids []int32
+		for _, p := range page.Products {
+			ids = append(ids, p.ProductID)
+		}
+		return ids
+	}
+	if ids := list("Electronics"); len(ids) != 0 {
+		t.Errorf("Expected no Electronics products after the move and delete, got %v", ids)
+	}
+	if ids := list("garden"); !equalIDs(ids, []int32{1}) {
+		t.Errorf("Expected product 1 under Garden, got %v", ids)
+	}
+	if _, ok := store.secondary.byCategory["electronics"]; ok {
+		t.Error("Expected the emptied Electronics entry to be dropped")
+	}
+
+	// A change of case only stays under the same entry
+	moved.Category = "GARDEN"
+	store.AddOrUpdateProduct(moved)
+	if ids := list("Garden"); !equalIDs(ids, []int32{1}) || !equalIDs(store.secondary.ids, []int32{1}) {
+		t.Errorf("Expected product 1 listed once under Garden, got %v (all IDs %v)", ids, store.secondary.ids)
+	}
+}
+
+func BenchmarkProductStore_ListCategory(b *testing.B) {
+	store := NewProductStore()
+	req := ListRequest{Filter: SearchFilter{Categories: []string{"Books"}}, After: 90000, Limit: 20}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.List(req)
+	}
+}
@@ -3,61 +3,83 @@ package store
 import (
 	"CS6650_Online_Store/internal/models"
 	"errors"
-	"fmt"
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 var (
 	ErrProductNotFound = errors.New("product not found")
 	ErrProductExists   = errors.New("product already exists")
+	ErrVersionConflict = errors.New("product version does not match")
 )
 
 // ProductStore handles in-memory storage of products using sync.Map for thread safety
 type ProductStore struct {
-	products sync.Map // map[int32]*models.Product
-	count    int32    // track total number of products for quick access
+	products sync.Map     // map[int32]*models.Product
+	count    atomic.Int32 // track total number of products for quick access
+	maxID    atomic.Int32 // highest product ID ever stored, for assigning new IDs
+
+	// index is the full-text search index; its write lock also serializes
+	// product writes so the map and the indexes never disagree
+	index *searchIndex
+
+	// secondary indexes product IDs by category, brand and manufacturer for
+	// listing; guarded by index.mu
+	secondary *secondaryIndexes
 }
 
-// Sample data arrays for product generation
+// Built-in brand and category names for synthetic catalogs
 var (
 	brands     = []string{"Alpha", "Beta", "Gamma", "Delta", "Epsilon", "Zeta", "Eta", "Theta", "Iota", "Kappa"}
 	categories = []string{"Electronics", "Books", "Home", "Sports", "Clothing", "Beauty", "Toys", "Automotive"}
 )
 
-// NewProductStore creates a new product store and generates 100,000 products
+// NewProductStore creates a new product store seeded with the default
+// 100,000-product catalog
 func NewProductStore() *ProductStore {
-	store := &ProductStore{}
+	store, err := NewSeededProductStore(DefaultSeedConfig())
+	if err != nil {
+		panic(err) // the default catalog is generated and cannot fail
+	}
+	return store
+}
 
-	log.Println("Generating 100,000 products...")
-	store.generateProducts()
-	log.Printf("Successfully generated %d products", store.count)
+// NewSeededProductStore creates a new product store holding the catalog
+// described by cfg
+func NewSeededProductStore(cfg SeedConfig) (*ProductStore, error) {
+	store := NewEmptyProductStore()
 
-	return store
+	log.Printf("Seeding %s...", cfg.Describe())
+	if err := store.seed(cfg); err != nil {
+		return nil, err
+	}
+	log.Printf("Successfully seeded %d products", store.count.Load())
+
+	return store, nil
 }
 
 // NewEmptyProductStore creates a new product store without pre-generating products (for testing)
 func NewEmptyProductStore() *ProductStore {
-	return &ProductStore{}
+	return &ProductStore{index: newSearchIndex(), secondary: newSecondaryIndexes()}
 }
 
-// generateProducts creates 100,000 products at startup
-func (s *ProductStore) generateProducts() {
-	for i := int32(1); i <= 100000; i++ {
-		brandIndex := (i - 1) % int32(len(brands))
-		categoryIndex := (i - 1) % int32(len(categories))
+// seed loads the seed catalog at startup
+func (s *ProductStore) seed(cfg SeedConfig) error {
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
 
-		product := models.NewProduct(
-			i,
-			fmt.Sprintf("Product %s %d", brands[brandIndex], i),
-			categories[categoryIndex],
-			brands[brandIndex],
-			fmt.Sprintf("Description for product %d in %s category by %s", i, categories[categoryIndex], brands[brandIndex]),
-		)
+	return forEachSeedProduct(cfg, func(product *models.Product) {
+		product.Version = s.versionLocked(product.ProductID) + 1
+		s.upsertLocked(product)
+	})
+}
 
-		s.products.Store(i, product)
-		s.count++
+// trackIDLocked remembers the highest ID seen. Callers hold s.index.mu.
+func (s *ProductStore) trackIDLocked(productID int32) {
+	if productID > s.maxID.Load() {
+		s.maxID.Store(productID)
 	}
 }
 
@@ -74,19 +96,128 @@ func (s *ProductStore) GetProduct(productID int32) (*models.Product, error) {
 	return &productCopy, nil
 }
 
+// GetProducts retrieves several products at once. Found products are returned
+// as copies in the order requested; IDs with no product are returned in missing.
+func (s *ProductStore) GetProducts(productIDs []int32) (products []*models.Product, missing []int32) {
+	products = make([]*models.Product, 0, len(productIDs))
+	for _, productID := range productIDs {
+		value, exists := s.products.Load(productID)
+		if !exists {
+			missing = append(missing, productID)
+			continue
+		}
+		productCopy := *value.(*models.Product)
+		products = append(products, &productCopy)
+	}
+	return products, missing
+}
+
 // AddOrUpdateProduct adds a new product or updates existing one
 func (s *ProductStore) AddOrUpdateProduct(product *models.Product) error {
 	// Store a copy to prevent external modification
 	productCopy := *product
 
-	// Check if it's a new product to update count
-	_, exists := s.products.Load(product.ProductID)
-	s.products.Store(product.ProductID, &productCopy)
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+
+	productCopy.Version = s.versionLocked(productCopy.ProductID) + 1
+	s.upsertLocked(&productCopy)
+	return nil
+}
+
+// CompareAndSwapProduct replaces a product only if its stored version is still
+// expectedVersion (0 creates it only if absent), and returns the stored
+// product with its new version
+func (s *ProductStore) CompareAndSwapProduct(product *models.Product, expectedVersion int64) (*models.Product, error) {
+	productCopy := *product
+
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+
+	current := s.versionLocked(productCopy.ProductID)
+	if current == 0 && expectedVersion != 0 {
+		return nil, ErrProductNotFound
+	}
+	if current != expectedVersion {
+		return nil, ErrVersionConflict
+	}
 
+	productCopy.Version = current + 1
+	s.upsertLocked(&productCopy)
+
+	stored := productCopy
+	return &stored, nil
+}
+
+// restoreProduct stores a product exactly as given, keeping its version.
+// Persistence uses it to reload snapshots and replay the WAL.
+func (s *ProductStore) restoreProduct(product *models.Product) {
+	productCopy := *product
+	if productCopy.Version == 0 {
+		productCopy.Version = 1 // written before products were versioned
+	}
+
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+
+	s.upsertLocked(&productCopy)
+}
+
+// versionLocked returns the stored version of a product, or 0 if it does not
+// exist. Callers hold s.index.mu.
+func (s *ProductStore) versionLocked(productID int32) int64 {
+	if value, exists := s.products.Load(productID); exists {
+		return value.(*models.Product).Version
+	}
+	return 0
+}
+
+// CreateProduct stores a copy of product under the next unused ID (one past
+// the highest ID ever stored, so IDs of deleted products are not handed out
+// again while the process runs) and returns the stored product
+func (s *ProductStore) CreateProduct(product *models.Product) (*models.Product, error) {
+	productCopy := *product
+
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+
+	productCopy.ProductID = s.maxID.Load() + 1
+	productCopy.Version = 1
+	s.upsertLocked(&productCopy)
+
+	created := productCopy
+	return &created, nil
+}
+
+// upsertLocked stores an already-copied product. Callers hold s.index.mu.
+func (s *ProductStore) upsertLocked(productCopy *models.Product) {
+	// Swap reports whether a previous value existed, so the count stays
+	// correct even when two goroutines insert the same new ID concurrently
+	previous, exists := s.products.Swap(productCopy.ProductID, productCopy)
+	if exists {
+		s.index.removeLocked(previous.(*models.Product))
+		s.secondary.update(previous.(*models.Product), productCopy)
+	} else {
+		s.count.Add(1)
+		s.secondary.add(productCopy)
+	}
+	s.index.addLocked(productCopy)
+	s.trackIDLocked(productCopy.ProductID)
+}
+
+// DeleteProduct removes a product and keeps the product count in sync
+func (s *ProductStore) DeleteProduct(productID int32) error {
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+
+	previous, exists := s.products.LoadAndDelete(productID)
 	if !exists {
-		s.count++
+		return ErrProductNotFound
 	}
 
+	s.index.removeLocked(previous.(*models.Product))
+	s.secondary.remove(previous.(*models.Product))
+	s.count.Add(-1)
 	return nil
 }
 
@@ -98,7 +229,7 @@ func (s *ProductStore) ProductExists(productID int32) bool {
 
 // GetAllProducts returns all products (useful for debugging - only use for small datasets)
 func (s *ProductStore) GetAllProducts() []*models.Product {
-	products := make([]*models.Product, 0, s.count)
+	products := make([]*models.Product, 0, s.count.Load())
 
 	s.products.Range(func(key, value interface{}) bool {
 		product := value.(*models.Product)
@@ -112,7 +243,7 @@ func (s *ProductStore) GetAllProducts() []*models.Product {
 
 // GetProductCount returns the total number of products
 func (s *ProductStore) GetProductCount() int32 {
-	return s.count
+	return s.count.Load()
 }
 
 // SearchProducts performs a bounded search through products
@@ -125,7 +256,7 @@ func (s *ProductStore) SearchProducts(query string, maxCheck int, maxResults int
 		maxResults = 20 // Default max results
 	}
 
-	results := make([]models.Product, 0, maxResults)
+	results := make([]models.SearchResult, 0, maxResults)
 	checked := 0 // Counter for EVERY product checked (not just matches)
 	totalFound := 0
 
@@ -160,7 +291,7 @@ func (s *ProductStore) SearchProducts(query string, maxCheck int, maxResults int
 			totalFound++
 			// Only add to results if we haven't reached maxResults
 			if len(results) < maxResults {
-				results = append(results, *product)
+				results = append(results, models.SearchResult{Product: *product})
 			}
 		}
 	}
@@ -177,3 +308,82 @@ func (s *ProductStore) SearchProducts(query string, maxCheck int, maxResults int
 		TotalFound: totalFound,
 	}, nil
 }
+
+// Search runs a search with the requested strategy.
+// The index strategy (default) covers the whole catalog; the scan strategy
+// keeps the bounded Homework 6 behavior for load-test experiments.
+func (s *ProductStore) Search(req SearchRequest) (*models.SearchResponse, error) {
+	if req.Strategy == SearchStrategyScan {
+		return s.SearchProducts(req.Query, req.MaxCheck, req.MaxResults)
+	}
+	return s.searchIndexed(req)
+}
+
+// Suggest returns up to limit product names and brands starting with prefix
+// (case-insensitive), most common first. The tries behind it are updated on
+// every write, so new and renamed products are suggested immediately.
+func (s *ProductStore) Suggest(prefix string, limit int) ([]models.Suggestion, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	return s.index.suggest(prefix, limit), nil
+}
+
+// searchIndexed answers a query from the inverted index over name, category,
+// brand and description. Every query term must match; by default results are
+// ranked by BM25 relevance with per-field boosts, ties broken by product ID.
+// Without a query every product matches, so filters alone can browse the catalog.
+func (s *ProductStore) searchIndexed(req SearchRequest) (*models.SearchResponse, error) {
+	limit := req.MaxResults
+	if limit <= 0 {
+		limit = 20 // Default max results
+	}
+
+	hasQuery := req.Query != ""
+	sortBy, order := normalizeSort(req.Sort, req.Order, hasQuery)
+	cursor, err := decodeCursor(req.Cursor, sortBy, order)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []scoredMatch
+	if hasQuery {
+		candidates = s.index.search(req.Query, req.Boosts, req.Fuzzy)
+	} else {
+		candidates = s.index.all()
+	}
+
+	// Apply filters and count facets in one pass over the text matches
+	facets := newFacetCounter()
+	matches := candidates[:0]
+	for _, match := range candidates {
+		product := match.product
+		if !facets.add(req.Filter, product) {
+			continue
+		}
+
+		match.weight = product.Weight
+		if sortBy == SortName {
+			match.name = strings.ToLower(product.Name)
+		}
+		matches = append(matches, match)
+	}
+	totalFound := len(matches)
+
+	page, hasMore := paginate(matches, cursor, req.Offset, limit, searchOrder(sortBy, order))
+
+	results := make([]models.SearchResult, 0, len(page))
+	for _, match := range page {
+		results = append(results, models.SearchResult{Product: *match.product, Score: match.score})
+	}
+
+	response := &models.SearchResponse{
+		Products:   results,
+		TotalFound: totalFound,
+		Facets:     facets.result(),
+	}
+	if hasMore && len(page) > 0 {
+		response.NextCursor = cursorFor(page[len(page)-1], sortBy, order).encode()
+	}
+	return response, nil
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"CS6650_Online_Store/internal/models"
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -257,3 +259,338 @@ func TestProductStore_SearchProducts(t *testing.T) {
 		t.Errorf("Expected total_found = 2 even with maxResults=1, got %d", result.TotalFound)
 	}
 }
+
+func TestProductStore_DeleteProduct(t *testing.T) {
+	store := NewEmptyProductStore()
+
+	product := &models.Product{ProductID: 1, SKU: "ABC123", Manufacturer: "Mfg1", CategoryID: 1, Weight: 100, SomeOtherID: 1, Name: "Product 1", Category: "Electronics", Brand: "Brand1"}
+	store.AddOrUpdateProduct(product)
+
+	// Deleting an existing product removes it and decrements the count
+	if err := store.DeleteProduct(1); err != nil {
+		t.Errorf("DeleteProduct() error = %v", err)
+	}
+	if store.ProductExists(1) {
+		t.Error("Product should not exist after deleting")
+	}
+	if store.GetProductCount() != 0 {
+		t.Errorf("Expected count 0 after delete, got %d", store.GetProductCount())
+	}
+
+	// Deleting again reports not found and leaves the count alone
+	if err := store.DeleteProduct(1); err != ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+	if store.GetProductCount() != 0 {
+		t.Errorf("Expected count to stay 0, got %d", store.GetProductCount())
+	}
+}
+
+// Test that ProductStore works through the ProductRepository interface
+func TestProductStore_Repository(t *testing.T) {
+	var repo ProductRepository = NewEmptyProductStore()
+
+	for i := int32(1); i <= 3; i++ {
+		product := &models.Product{ProductID: i, SKU: "SKU", Manufacturer: "Mfg", CategoryID: 1, Weight: 100, SomeOtherID: 1, Name: "Product", Category: "Electronics", Brand: "Brand"}
+		if err := repo.Upsert(product); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	if repo.Count() != 3 {
+		t.Errorf("Expected count 3, got %d", repo.Count())
+	}
+
+	// Scan visits every product and stops early when asked
+	seen := 0
+	repo.Scan(func(p *models.Product) bool {
+		seen++
+		return true
+	})
+	if seen != 3 {
+		t.Errorf("Expected Scan to visit 3 products, got %d", seen)
+	}
+
+	seen = 0
+	repo.Scan(func(p *models.Product) bool {
+		seen++
+		return false
+	})
+	if seen != 1 {
+		t.Errorf("Expected Scan to stop after 1 product, got %d", seen)
+	}
+
+	if err := repo.Delete(2); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if repo.Exists(2) {
+		t.Error("Product 2 should not exist after Delete")
+	}
+	if _, err := repo.Get(2); err != ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+}
+
+// Test the inverted-index search strategy
+func TestProductStore_SearchIndexed(t *testing.T) {
+	store := NewEmptyProductStore()
+
+	products := []*models.Product{
+		{ProductID: 1, SKU: "ABC1", Manufacturer: "Mfg1", CategoryID: 1, Weight: 100, SomeOtherID: 1, Name: "iPhone 15", Category: "Electronics", Brand: "Apple", Description: "Smartphone"},
+		{ProductID: 2, SKU: "ABC2", Manufacturer: "Mfg2", CategoryID: 2, Weight: 200, SomeOtherID: 2, Name: "MacBook Pro", Category: "Electronics", Brand: "Apple"},
+		{ProductID: 500, SKU: "ABC3", Manufacturer: "Mfg3", CategoryID: 3, Weight: 300, SomeOtherID: 3, Name: "Harry Potter", Category: "Books", Brand: "Scholastic"},
+	}
+	for _, p := range products {
+		store.AddOrUpdateProduct(p)
+	}
+
+	// Brand and description are indexed too, and all terms must match
+	result, _ := store.Search(SearchRequest{Query: "apple smartphone"})
+	if result.TotalFound != 1 || result.Products[0].ProductID != 1 {
+		t.Errorf("Expected only product 1 for 'apple smartphone', got %+v", result)
+	}
+
+	// Products beyond the bounded scan window are found by the index...
+	result, _ = store.Search(SearchRequest{Query: "potter"})
+	if result.TotalFound != 1 {
+		t.Errorf("Expected index search to find product 500, got %d", result.TotalFound)
+	}
+
+	// ...but not by the explicit scan strategy
+	result, _ = store.Search(SearchRequest{Query: "potter", Strategy: SearchStrategyScan, MaxCheck: 100})
+	if result.TotalFound != 0 {
+		t.Errorf("Expected scan of IDs 1..100 to miss product 500, got %d", result.TotalFound)
+	}
+
+	// Updates replace the old terms
+	store.AddOrUpdateProduct(&models.Product{ProductID: 500, SKU: "ABC3", Manufacturer: "Mfg3", CategoryID: 3, Weight: 300, SomeOtherID: 3, Name: "Lord of the Rings", Category: "Books", Brand: "Allen"})
+	if result, _ = store.Search(SearchRequest{Query: "potter"}); result.TotalFound != 0 {
+		t.Errorf("Expected stale term 'potter' to be gone after update, got %d", result.TotalFound)
+	}
+	if result, _ = store.Search(SearchRequest{Query: "rings"}); result.TotalFound != 1 {
+		t.Errorf("Expected updated term 'rings' to match, got %d", result.TotalFound)
+	}
+
+	// Deletes drop the product from the index
+	store.DeleteProduct(1)
+	if result, _ = store.Search(SearchRequest{Query: "apple"}); result.TotalFound != 1 || result.Products[0].ProductID != 2 {
+		t.Errorf("Expected only product 2 for 'apple' after delete, got %+v", result)
+	}
+}
+
+func TestProductStore_SearchIndexedFullCatalog(t *testing.T) {
+	store := NewProductStore()
+
+	result, err := store.Search(SearchRequest{Query: "Product Iota 99999"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if result.TotalFound != 1 || result.Products[0].ProductID != 99999 {
+		t.Errorf("Expected product 99999, got %+v", result)
+	}
+}
+
+func BenchmarkProductStore_SearchIndexed(b *testing.B) {
+	store := NewProductStore()
+	req := SearchRequest{Query: "Product Iota 99999"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.Search(req)
+	}
+}
+
+// testCreate checks server-assigned IDs against any repository
+func testCreate(t *testing.T, repo ProductRepository) {
+	t.Helper()
+
+	repo.Upsert(testProduct(41, "EXISTING"))
+
+	first, err := repo.Create(testProduct(999, "NEW1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if first.ProductID != 42 {
+		t.Errorf("Expected the ID after the highest existing one (42), got %d", first.ProductID)
+	}
+	stored, err := repo.Get(42)
+	if err != nil || stored.SKU != "NEW1" {
+		t.Errorf("Expected the created product to be stored under 42, got %+v (%v)", stored, err)
+	}
+
+	second, _ := repo.Create(testProduct(0, "NEW2"))
+	if second.ProductID != 43 || repo.Count() != 3 {
+		t.Errorf("Expected ID 43 and 3 products, got ID %d and %d products", second.ProductID, repo.Count())
+	}
+}
+
+func TestProductStore_Create(t *testing.T) {
+	store := NewEmptyProductStore()
+	testCreate(t, store)
+
+	// IDs of deleted products are not reused
+	store.DeleteProduct(43)
+	if created, _ := store.Create(testProduct(0, "NEW3")); created.ProductID != 44 {
+		t.Errorf("Expected ID 44 after deleting 43, got %d", created.ProductID)
+	}
+}
+
+func TestProductStore_CreateConcurrent(t *testing.T) {
+	store := NewEmptyProductStore()
+
+	var wg sync.WaitGroup
+	ids := make([]int32, 50)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			created, _ := store.Create(testProduct(0, "SKU"))
+			ids[i] = created.ProductID
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int32]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("ID %d was assigned twice", id)
+		}
+		seen[id] = true
+	}
+	if store.Count() != 50 {
+		t.Errorf("Expected 50 products, got %d", store.Count())
+	}
+}
+
+// testCompareAndSwap checks versioning and CAS against any repository
+func testCompareAndSwap(t *testing.T, repo ProductRepository) {
+	t.Helper()
+
+	repo.Upsert(testProduct(1, "V1"))
+	stored, _ := repo.Get(1)
+	if stored.Version != 1 {
+		t.Fatalf("Expected a new product to start at version 1, got %d", stored.Version)
+	}
+
+	repo.Upsert(testProduct(1, "V2"))
+	if stored, _ = repo.Get(1); stored.Version != 2 {
+		t.Fatalf("Expected Upsert to bump the version to 2, got %d", stored.Version)
+	}
+
+	swapped, err := repo.CompareAndSwap(testProduct(1, "V3"), 2)
+	if err != nil {
+		t.Fatalf("CompareAndSwap() error = %v", err)
+	}
+	if swapped.Version != 3 || swapped.SKU != "V3" {
+		t.Errorf("Expected version 3 with SKU V3, got %+v", swapped)
+	}
+
+	// A writer holding the old version loses
+	if _, err := repo.CompareAndSwap(testProduct(1, "STALE"), 2); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict for a stale version, got %v", err)
+	}
+	if stored, _ = repo.Get(1); stored.SKU != "V3" || stored.Version != 3 {
+		t.Errorf("Expected the stale write to be rejected, got %+v", stored)
+	}
+
+	if _, err := repo.CompareAndSwap(testProduct(2, "NEW"), 1); err != ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound for a missing product, got %v", err)
+	}
+
+	// Version 0 creates a product only while it is absent
+	if created, err := repo.CompareAndSwap(testProduct(2, "NEW"), 0); err != nil || created.Version != 1 {
+		t.Errorf("Expected product 2 to be created at version 1, got %+v (err=%v)", created, err)
+	}
+	if _, err := repo.CompareAndSwap(testProduct(2, "AGAIN"), 0); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict for creating an existing product, got %v", err)
+	}
+	if stored, _ = repo.Get(2); stored.SKU != "NEW" || repo.Count() != 2 {
+		t.Errorf("Expected the second create to be rejected, got %+v", stored)
+	}
+}
+
+func TestProductStore_CompareAndSwap(t *testing.T) {
+	testCompareAndSwap(t, NewEmptyProductStore())
+}
+
+func TestProductStore_CompareAndSwapConcurrent(t *testing.T) {
+	store := NewEmptyProductStore()
+	store.AddOrUpdateProduct(testProduct(1, "START"))
+
+	// Every writer reads version 1; exactly one may win
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wins := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.CompareAndSwapProduct(testProduct(1, "WRITER"), 1); err == nil {
+				mu.Lock()
+				wins++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if wins != 1 {
+		t.Errorf("Expected exactly one successful CAS, got %d", wins)
+	}
+}
+
+// testUpsertBatch checks batched writes against any repository
+func testUpsertBatch(t *testing.T, repo ProductRepository) {
+	t.Helper()
+
+	repo.Upsert(testProduct(1, "OLD"))
+	batch := []*models.Product{testProduct(1, "NEW"), testProduct(2, "FIRST"), testProduct(2, "SECOND")}
+	if err := repo.UpsertBatch(batch); err != nil {
+		t.Fatalf("UpsertBatch() error = %v", err)
+	}
+
+	if repo.Count() != 2 {
+		t.Errorf("Expected 2 products, got %d", repo.Count())
+	}
+	if stored, _ := repo.Get(1); stored.SKU != "NEW" || stored.Version != 2 {
+		t.Errorf("Expected product 1 replaced at version 2, got %+v", stored)
+	}
+	// Repeats within a batch apply in order, each bumping the version
+	if stored, _ := repo.Get(2); stored.SKU != "SECOND" || stored.Version != 2 {
+		t.Errorf("Expected the last write to product 2 at version 2, got %+v", stored)
+	}
+}
+
+func TestProductStore_UpsertBatch(t *testing.T) {
+	testUpsertBatch(t, NewEmptyProductStore())
+}
+
+// testGetMany checks multi-get against any repository
+func testGetMany(t *testing.T, repo ProductRepository) {
+	t.Helper()
+
+	for _, id := range []int32{1, 2, 3} {
+		repo.Upsert(testProduct(id, fmt.Sprintf("SKU-%d", id)))
+	}
+
+	products, missing, err := repo.GetMany([]int32{3, 9, 1, 3})
+	if err != nil {
+		t.Fatalf("GetMany() error = %v", err)
+	}
+	if len(products) != 3 || products[0].ProductID != 3 || products[1].ProductID != 1 || products[2].ProductID != 3 {
+		t.Errorf("Expected products 3, 1, 3 in request order, got %+v", products)
+	}
+	if len(missing) != 1 || missing[0] != 9 {
+		t.Errorf("Expected 9 to be missing, got %v", missing)
+	}
+
+	// Results are copies
+	products[0].SKU = "CHANGED"
+	if stored, _ := repo.Get(3); stored.SKU != "SKU-3" || products[2].SKU != "SKU-3" {
+		t.Errorf("Expected GetMany to return independent copies, got %q and %q", stored.SKU, products[2].SKU)
+	}
+}
+
+func TestProductStore_GetMany(t *testing.T) {
+	testGetMany(t, NewEmptyProductStore())
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+)
+
+// ProductRepository is the storage contract the HTTP handlers depend on.
+// Any backend (in-memory, disk-backed, SQL, or a test fake) can be plugged in
+// as long as it implements these methods.
+type ProductRepository interface {
+	// Get returns a copy of the product or ErrProductNotFound
+	Get(productID int32) (*models.Product, error)
+
+	// GetMany returns copies of the stored products among productIDs, in the
+	// order requested, and the IDs that have no product
+	GetMany(productIDs []int32) ([]*models.Product, []int32, error)
+
+	// Upsert adds a new product or replaces an existing one, bumping its version
+	Upsert(product *models.Product) error
+
+	// UpsertBatch upserts several products at once, in order. Backends use it
+	// to amortize durability costs (one fsync or one transaction per batch).
+	UpsertBatch(products []*models.Product) error
+
+	// CompareAndSwap replaces an existing product only if its stored version
+	// equals expectedVersion; an expectedVersion of 0 creates the product only
+	// if it does not exist yet. It returns the stored product with its new
+	// version, ErrVersionConflict on a mismatch, or ErrProductNotFound.
+	CompareAndSwap(product *models.Product, expectedVersion int64) (*models.Product, error)
+
+	// Create stores a new product under a server-assigned ID (product.ProductID
+	// is ignored) and returns the stored product
+	Create(product *models.Product) (*models.Product, error)
+
+	// Delete removes a product, returning ErrProductNotFound if it does not exist
+	Delete(productID int32) error
+
+	// Exists reports whether a product with the given ID is stored
+	Exists(productID int32) bool
+
+	// Count returns the total number of stored products
+	Count() int32
+
+	// Scan calls fn with a copy of every stored product until fn returns false
+	Scan(fn func(product *models.Product) bool) error
+
+	// List pages through products in product_id order (see ListRequest)
+	List(req ListRequest) (*models.ProductListResponse, error)
+
+	// Search performs a product search (see SearchRequest)
+	Search(req SearchRequest) (*models.SearchResponse, error)
+
+	// Suggest returns up to limit product name and brand completions of prefix
+	Suggest(prefix string, limit int) ([]models.Suggestion, error)
+}
+
+// Compile-time check that the sync.Map store satisfies the interface
+var _ ProductRepository = (*ProductStore)(nil)
+
+// Get implements ProductRepository
+func (s *ProductStore) Get(productID int32) (*models.Product, error) {
+	return s.GetProduct(productID)
+}
+
+// GetMany implements ProductRepository
+func (s *ProductStore) GetMany(productIDs []int32) ([]*models.Product, []int32, error) {
+	products, missing := s.GetProducts(productIDs)
+	return products, missing, nil
+}
+
+// Upsert implements ProductRepository
+func (s *ProductStore) Upsert(product *models.Product) error {
+	return s.AddOrUpdateProduct(product)
+}
+
+// UpsertBatch implements ProductRepository
+func (s *ProductStore) UpsertBatch(products []*models.Product) error {
+	for _, product := range products {
+		if err := s.AddOrUpdateProduct(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareAndSwap implements ProductRepository
+func (s *ProductStore) CompareAndSwap(product *models.Product, expectedVersion int64) (*models.Product, error) {
+	return s.CompareAndSwapProduct(product, expectedVersion)
+}
+
+// Create implements ProductRepository
+func (s *ProductStore) Create(product *models.Product) (*models.Product, error) {
+	return s.CreateProduct(product)
+}
+
+// Delete implements ProductRepository
+func (s *ProductStore) Delete(productID int32) error {
+	return s.DeleteProduct(productID)
+}
+
+// Exists implements ProductRepository
+func (s *ProductStore) Exists(productID int32) bool {
+	return s.ProductExists(productID)
+}
+
+// Count implements ProductRepository
+func (s *ProductStore) Count() int32 {
+	return s.GetProductCount()
+}
+
+// Scan implements ProductRepository
+func (s *ProductStore) Scan(fn func(product *models.Product) bool) error {
+	s.products.Range(func(key, value interface{}) bool {
+		productCopy := *value.(*models.Product)
+		return fn(&productCopy)
+	})
+	return nil
+}
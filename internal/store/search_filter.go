@@ -0,0 +1,111 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"sort"
+	"strings"
+)
+
+// SearchFilter narrows search results on structured product fields.
+// Values of the same field are ORed together; different fields are ANDed.
+type SearchFilter struct {
+	Categories    []string // case-insensitive exact match
+	Brands        []string // case-insensitive exact match
+	Manufacturers []string // case-insensitive exact match
+	MinWeight     *int32   // inclusive
+	MaxWeight     *int32   // inclusive
+}
+
+// IsEmpty reports whether the filter lets every product through
+func (f SearchFilter) IsEmpty() bool {
+	return len(f.Categories) == 0 && len(f.Brands) == 0 && len(f.Manufacturers) == 0 &&
+		f.MinWeight == nil && f.MaxWeight == nil
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f SearchFilter) matchesCategory(p *models.Product) bool {
+	return matchesAny(f.Categories, p.Category)
+}
+
+func (f SearchFilter) matchesBrand(p *models.Product) bool {
+	return matchesAny(f.Brands, p.Brand)
+}
+
+func (f SearchFilter) matchesManufacturer(p *models.Product) bool {
+	return matchesAny(f.Manufacturers, p.Manufacturer)
+}
+
+// matches reports whether a product passes the whole filter
+func (f SearchFilter) matches(p *models.Product) bool {
+	return f.matchesCategory(p) && f.matchesBrand(p) && f.matchesManufacturer(p) && f.matchesWeight(p)
+}
+
+func (f SearchFilter) matchesWeight(p *models.Product) bool {
+	if f.MinWeight != nil && p.Weight < *f.MinWeight {
+		return false
+	}
+	if f.MaxWeight != nil && p.Weight > *f.MaxWeight {
+		return false
+	}
+	return true
+}
+
+// facetCounter tallies category and brand facets. Counts are disjunctive:
+// the category facet ignores the category filter (and likewise for brand),
+// so the sidebar still shows how many results each alternative would give.
+type facetCounter struct {
+	categories map[string]int
+	brands     map[string]int
+}
+
+func newFacetCounter() *facetCounter {
+	return &facetCounter{categories: make(map[string]int), brands: make(map[string]int)}
+}
+
+// add counts a text match and reports whether it passes the whole filter
+func (c *facetCounter) add(f SearchFilter, p *models.Product) bool {
+	otherOK := f.matchesWeight(p) && f.matchesManufacturer(p)
+	categoryOK := f.matchesCategory(p)
+	brandOK := f.matchesBrand(p)
+
+	if otherOK && brandOK {
+		c.categories[p.Category]++
+	}
+	if otherOK && categoryOK {
+		c.brands[p.Brand]++
+	}
+	return otherOK && categoryOK && brandOK
+}
+
+func (c *facetCounter) result() *models.SearchFacets {
+	return &models.SearchFacets{
+		Categories: sortedFacets(c.categories),
+		Brands:     sortedFacets(c.brands),
+	}
+}
+
+// sortedFacets orders facet values by descending count, then by value
+func sortedFacets(counts map[string]int) []models.FacetCount {
+	facets := make([]models.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, models.FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"testing"
+)
+
+func facetTestProducts() []*models.Product {
+	return []*models.Product{
+		{ProductID: 1, SKU: "A", Manufacturer: "M", CategoryID: 3, SomeOtherID: 1, Name: "Novel", Category: "Books", Brand: "Alpha", Weight: 100},
+		{ProductID: 2, SKU: "B", Manufacturer: "M", CategoryID: 3, SomeOtherID: 1, Name: "Cookbook", Category: "Books", Brand: "Beta", Weight: 400},
+		{ProductID: 3, SKU: "C", Manufacturer: "M", CategoryID: 3, SomeOtherID: 1, Name: "Atlas", Category: "Books", Brand: "Alpha", Weight: 900},
+		{ProductID: 4, SKU: "D", Manufacturer: "M", CategoryID: 1, SomeOtherID: 1, Name: "Radio", Category: "Electronics", Brand: "Alpha", Weight: 300},
+		{ProductID: 5, SKU: "E", Manufacturer: "M", CategoryID: 7, SomeOtherID: 1, Name: "Blocks", Category: "Toys", Brand: "Gamma", Weight: 200},
+	}
+}
+
+func weight(w int32) *int32 { return &w }
+
+func facetCount(facets []models.FacetCount, value string) int {
+	for _, f := range facets {
+		if f.Value == value {
+			return f.Count
+		}
+	}
+	return 0
+}
+
+func testSearchFilters(t *testing.T, repo ProductRepository) {
+	t.Helper()
+	for _, p := range facetTestProducts() {
+		if err := repo.Upsert(p); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		req    SearchRequest
+		wantID []int32
+	}{
+		{"category only", SearchRequest{Filter: SearchFilter{Categories: []string{"books"}}}, []int32{1, 2, 3}},
+		{"category and brand", SearchRequest{Filter: SearchFilter{Categories: []string{"Books"}, Brands: []string{"Alpha"}}}, []int32{1, 3}},
+		{"weight range", SearchRequest{Filter: SearchFilter{MinWeight: weight(200), MaxWeight: weight(400)}}, []int32{2, 4, 5}},
+		{"any of several brands", SearchRequest{Filter: SearchFilter{Brands: []string{"beta", "gamma"}}}, []int32{2, 5}},
+		{"with text query", SearchRequest{Query: "alpha", Sort: SortProductID, Filter: SearchFilter{MaxWeight: weight(500)}}, []int32{1, 4}},
+		{"no match", SearchRequest{Filter: SearchFilter{Categories: []string{"Garden"}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := repo.Search(tt.req)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if result.TotalFound != len(tt.wantID) || len(result.Products) != len(tt.wantID) {
+				t.Fatalf("Expected %v, got %d products (total %d)", tt.wantID, len(result.Products), result.TotalFound)
+			}
+			for i, id := range tt.wantID {
+				if result.Products[i].ProductID != id {
+					t.Errorf("Expected result %d to be product %d, got %d", i, id, result.Products[i].ProductID)
+				}
+			}
+		})
+	}
+
+	// Each facet ignores the filter on its own field but honors the others
+	result, err := repo.Search(SearchRequest{Filter: SearchFilter{Categories: []string{"Books"}, Brands: []string{"Alpha"}}})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if result.Facets == nil {
+		t.Fatal("Expected facets in the response")
+	}
+	if got := facetCount(result.Facets.Categories, "Books"); got != 2 {
+		t.Errorf("Expected 2 Alpha books in the category facet, got %d", got)
+	}
+	if got := facetCount(result.Facets.Categories, "Electronics"); got != 1 {
+		t.Errorf("Expected 1 Alpha electronics product in the category facet, got %d", got)
+	}
+	if got := facetCount(result.Facets.Brands, "Beta"); got != 1 {
+		t.Errorf("Expected 1 Beta book in the brand facet, got %d", got)
+	}
+	if got := facetCount(result.Facets.Brands, "Gamma"); got != 0 {
+		t.Errorf("Expected no Gamma books, got %d", got)
+	}
+	if first := result.Facets.Brands[0]; first.Value != "Alpha" || first.Count != 2 {
+		t.Errorf("Expected brand facets ordered by count, got %+v", result.Facets.Brands)
+	}
+}
+
+func TestProductStore_SearchFilters(t *testing.T) {
+	testSearchFilters(t, NewEmptyProductStore())
+}
+
+func TestSQLProductStore_SearchFilters(t *testing.T) {
+	testSearchFilters(t, newTestSQLStore(t))
+}
@@ -0,0 +1,580 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Search strategies
+const (
+	// SearchStrategyIndex looks query terms up in the inverted index and covers the whole catalog
+	SearchStrategyIndex = "index"
+
+	// SearchStrategyScan is the Homework 6 bounded scan that checks exactly MaxCheck products
+	SearchStrategyScan = "scan"
+)
+
+// BM25 tuning parameters (the usual Lucene/Elasticsearch defaults)
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Typo tolerance
+const (
+	// minPrefixExpansion is the shortest unknown term that is completed as a prefix
+	minPrefixExpansion = 3
+
+	// maxTermExpansions caps how many index terms one unknown query term may expand to
+	maxTermExpansions = 50
+)
+
+// SearchRequest describes a product search
+type SearchRequest struct {
+	Query      string
+	Strategy   string // SearchStrategyIndex (default) or SearchStrategyScan
+	MaxCheck   int    // scan strategy only: how many products to examine
+	MaxResults int    // page size
+
+	// Boosts weights matches per field; the zero value means DefaultSearchBoosts
+	Boosts SearchBoosts
+
+	// Fuzzy lets query terms that are not in the index match by prefix or
+	// within a small edit distance (index strategy only)
+	Fuzzy bool
+
+	// Structured filters (index strategy only); Query may be empty when filtering
+	Filter SearchFilter
+
+	// Paging and ordering (index strategy only)
+	Offset int    // results to skip (after the cursor, if any)
+	Cursor string // opaque next_cursor from a previous page
+	Sort   string // SortRelevance (default with a query), SortName, SortWeight or SortProductID (default without)
+	Order  string // OrderAsc or OrderDesc; defaults to desc for relevance, asc otherwise
+}
+
+// ValidSearchStrategy reports whether s names a known strategy ("" means the default)
+func ValidSearchStrategy(s string) bool {
+	return s == "" || s == SearchStrategyIndex || s == SearchStrategyScan
+}
+
+// SearchBoosts weights a term match in each field when scoring relevance
+type SearchBoosts struct {
+	Name        float64
+	Brand       float64
+	Category    float64
+	Description float64
+}
+
+// DefaultSearchBoosts ranks name matches above brand, category and description
+var DefaultSearchBoosts = SearchBoosts{Name: 3, Brand: 2, Category: 1.5, Description: 1}
+
+// ParseSearchBoosts parses a spec such as "name=3,brand=2,category=1.5,description=1".
+// Fields that are not mentioned keep their default boost.
+func ParseSearchBoosts(spec string) (SearchBoosts, error) {
+	boosts := DefaultSearchBoosts
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		field, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return boosts, fmt.Errorf("invalid boost %q: expected field=value", part)
+		}
+		boost, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || boost < 0 {
+			return boosts, fmt.Errorf("invalid boost %q: value must be a non-negative number", part)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "name":
+			boosts.Name = boost
+		case "brand":
+			boosts.Brand = boost
+		case "category":
+			boosts.Category = boost
+		case "description":
+			boosts.Description = boost
+		default:
+			return boosts, fmt.Errorf("invalid boost %q: unknown field", part)
+		}
+	}
+
+	return boosts, nil
+}
+
+// searchField identifies an indexed product field
+type searchField int
+
+const (
+	fieldName searchField = iota
+	fieldBrand
+	fieldCategory
+	fieldDescription
+	numSearchFields
+)
+
+// fieldCounts holds one counter per indexed field
+type fieldCounts [numSearchFields]uint16
+
+func (b SearchBoosts) weights() [numSearchFields]float64 {
+	if b == (SearchBoosts{}) {
+		b = DefaultSearchBoosts
+	}
+	return [numSearchFields]float64{b.Name, b.Brand, b.Category, b.Description}
+}
+
+// posting records that a product contains a term, and how often in each field
+type posting struct {
+	id int32
+	tf fieldCounts
+}
+
+// postingList is ordered by ascending product ID
+type postingList []posting
+
+func (p postingList) find(id int32) int {
+	return sort.Search(len(p), func(i int) bool { return p[i].id >= id })
+}
+
+// add inserts an entry keeping the list sorted. Appending in ID order is O(1).
+func (p postingList) add(entry posting) postingList {
+	n := len(p)
+	if n == 0 || p[n-1].id < entry.id {
+		return append(p, entry)
+	}
+
+	i := p.find(entry.id)
+	if i < n && p[i].id == entry.id {
+		p[i] = entry
+		return p
+	}
+	p = append(p, posting{})
+	copy(p[i+1:], p[i:])
+	p[i] = entry
+	return p
+}
+
+// remove deletes the entry for id if present
+func (p postingList) remove(id int32) postingList {
+	i := p.find(id)
+	if i < len(p) && p[i].id == id {
+		return append(p[:i], p[i+1:]...)
+	}
+	return p
+}
+
+// get returns the entry for id
+func (p postingList) get(id int32) (posting, bool) {
+	i := p.find(id)
+	if i < len(p) && p[i].id == id {
+		return p[i], true
+	}
+	return posting{}, false
+}
+
+// searchIndex is a tokenized inverted index over the searchable product fields.
+// It is maintained incrementally by ProductStore on every write and keeps the
+// per-field statistics BM25 needs, plus tries over the term vocabulary (for
+// typo tolerance) and over product names and brands (for autocomplete).
+type searchIndex struct {
+	mu        sync.RWMutex
+	postings  map[string]postingList
+	docs      map[int32]indexedDoc
+	totalLens [numSearchFields]int64
+
+	terms  *trie // every indexed term
+	names  *trie // full product names
+	brands *trie // brand names
+}
+
+// indexedDoc is the index's view of one product: the stored (immutable)
+// product and its per-field term counts
+type indexedDoc struct {
+	product *models.Product
+	lens    fieldCounts
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]postingList),
+		docs:     make(map[int32]indexedDoc),
+		terms:    &trie{},
+		names:    &trie{},
+		brands:   &trie{},
+	}
+}
+
+// suggestionKey normalizes a name or brand for the autocomplete tries
+func suggestionKey(text string) string {
+	return strings.ToLower(strings.TrimSpace(text))
+}
+
+// tokenize lowercases text and splits it into alphanumeric terms
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// analyze returns the per-field term frequencies and field lengths of a product
+func analyze(p *models.Product) (map[string]fieldCounts, fieldCounts) {
+	terms := make(map[string]fieldCounts)
+	var lens fieldCounts
+
+	fields := [numSearchFields]string{p.Name, p.Brand, p.Category, p.Description}
+	for f, text := range fields {
+		for _, term := range tokenize(text) {
+			tf := terms[term]
+			tf[f]++
+			terms[term] = tf
+			lens[f]++
+		}
+	}
+	return terms, lens
+}
+
+// addLocked indexes a product. Callers hold idx.mu for writing.
+func (idx *searchIndex) addLocked(p *models.Product) {
+	terms, lens := analyze(p)
+	for term, tf := range terms {
+		list := idx.postings[term]
+		if len(list) == 0 {
+			idx.terms.add(term, term)
+		}
+		idx.postings[term] = list.add(posting{id: p.ProductID, tf: tf})
+	}
+	if key := suggestionKey(p.Name); key != "" {
+		idx.names.add(key, strings.TrimSpace(p.Name))
+	}
+	if key := suggestionKey(p.Brand); key != "" {
+		idx.brands.add(key, strings.TrimSpace(p.Brand))
+	}
+
+	idx.docs[p.ProductID] = indexedDoc{product: p, lens: lens}
+	for f := range lens {
+		idx.totalLens[f] += int64(lens[f])
+	}
+}
+
+// removeLocked drops a product's postings. Callers hold idx.mu for writing.
+func (idx *searchIndex) removeLocked(p *models.Product) {
+	terms, _ := analyze(p)
+	for term := range terms {
+		list := idx.postings[term].remove(p.ProductID)
+		if len(list) == 0 {
+			delete(idx.postings, term)
+			idx.terms.remove(term)
+		} else {
+			idx.postings[term] = list
+		}
+	}
+	idx.names.remove(suggestionKey(p.Name))
+	idx.brands.remove(suggestionKey(p.Brand))
+
+	lens := idx.docs[p.ProductID].lens
+	for f := range lens {
+		idx.totalLens[f] -= int64(lens[f])
+	}
+	delete(idx.docs, p.ProductID)
+}
+
+// scoredMatch is a matching product with its relevance score.
+// name is only filled in when results are sorted by it.
+type scoredMatch struct {
+	id      int32
+	score   float64
+	name    string // lowercased
+	weight  int32
+	product *models.Product
+}
+
+// termExpansion is one index term a query term matches, with its posting
+// list, IDF and a penalty weight (1 for the exact term)
+type termExpansion struct {
+	list   postingList
+	idf    float64
+	weight float64
+}
+
+// expandLocked returns the index terms a query term matches. Known terms match
+// only themselves. With fuzzy enabled an unknown term instead matches the terms
+// it is a prefix of and those within maxEditDistance edits, each scored lower
+// the further it is from what was typed. Callers hold idx.mu for reading.
+func (idx *searchIndex) expandLocked(term string, fuzzy bool, docCount float64) []termExpansion {
+	idf := func(list postingList) float64 {
+		df := float64(len(list))
+		return math.Log(1 + (docCount-df+0.5)/(df+0.5))
+	}
+
+	if list, ok := idx.postings[term]; ok {
+		return []termExpansion{{list: list, idf: idf(list), weight: 1}}
+	}
+	if !fuzzy {
+		return nil
+	}
+
+	// Closest candidates first: typos by edit distance, then prefix completions
+	distances := make(map[string]int)
+	for _, m := range idx.terms.fuzzy(term, maxEditDistance(term)) {
+		distances[m.key] = m.distance
+	}
+	if len(term) >= minPrefixExpansion {
+		for _, entry := range idx.terms.withPrefix(term, maxTermExpansions) {
+			if _, ok := distances[entry.key]; !ok {
+				distances[entry.key] = 1 // a completion counts as one edit
+			}
+		}
+	}
+
+	candidates := make([]string, 0, len(distances))
+	for key := range distances {
+		candidates = append(candidates, key)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if distances[a] != distances[b] {
+			return distances[a] < distances[b]
+		}
+		return a < b
+	})
+	if len(candidates) > maxTermExpansions {
+		candidates = candidates[:maxTermExpansions]
+	}
+
+	expansions := make([]termExpansion, 0, len(candidates))
+	for _, key := range candidates {
+		list := idx.postings[key]
+		expansions = append(expansions, termExpansion{list: list, idf: idf(list), weight: 1 / float64(1+distances[key])})
+	}
+	return expansions
+}
+
+// maxEditDistance follows the usual AUTO fuzziness: exact for very short
+// terms, one typo up to five characters, two beyond that
+func maxEditDistance(term string) int {
+	switch {
+	case len(term) <= 2:
+		return 0
+	case len(term) <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// search returns every product matching all query terms, scored with BM25F:
+// per-field term frequencies are length-normalized, weighted by the field
+// boosts and summed before BM25 saturation is applied. With fuzzy enabled a
+// query term matches through its best-scoring expansion.
+func (idx *searchIndex) search(query string, boosts SearchBoosts, fuzzy bool) []scoredMatch {
+	terms := uniqueTerms(tokenize(query))
+	if len(terms) == 0 {
+		return nil
+	}
+	weights := boosts.weights()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	docCount := float64(len(idx.docs))
+	groups := make([][]termExpansion, 0, len(terms))
+	sizes := make(map[int]int, len(terms))
+	for _, term := range terms {
+		group := idx.expandLocked(term, fuzzy, docCount)
+		if len(group) == 0 {
+			return nil // AND semantics: an unknown term matches nothing
+		}
+		size := 0
+		for _, e := range group {
+			size += len(e.list)
+		}
+		sizes[len(groups)] = size
+		groups = append(groups, group)
+	}
+
+	// Walk the query term with the fewest postings and probe the others
+	order := make([]int, len(groups))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return sizes[order[i]] < sizes[order[j]] })
+	sorted := make([][]termExpansion, len(groups))
+	for i, g := range order {
+		sorted[i] = groups[g]
+	}
+	groups = sorted
+
+	var avgLens [numSearchFields]float64
+	for f := range avgLens {
+		if docCount > 0 {
+			avgLens[f] = float64(idx.totalLens[f]) / docCount
+		}
+	}
+
+	// termScore is the BM25F contribution of one posting
+	termScore := func(entry posting, lens fieldCounts, idf float64) float64 {
+		weighted := 0.0
+		for f := range entry.tf {
+			if entry.tf[f] == 0 || avgLens[f] == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(lens[f])/avgLens[f]
+			weighted += weights[f] * float64(entry.tf[f]) / norm
+		}
+		return idf * weighted * (bm25K1 + 1) / (weighted + bm25K1)
+	}
+
+	var seen map[int32]struct{}
+	if len(groups[0]) > 1 {
+		seen = make(map[int32]struct{}, sizes[order[0]])
+	}
+
+	matches := make([]scoredMatch, 0, sizes[order[0]])
+	for _, first := range groups[0] {
+		for _, candidate := range first.list {
+			if seen != nil {
+				if _, dup := seen[candidate.id]; dup {
+					continue
+				}
+				seen[candidate.id] = struct{}{}
+			}
+
+			doc := idx.docs[candidate.id]
+			score, inAll := 0.0, true
+			for g, group := range groups {
+				best, found := 0.0, false
+				for _, e := range group {
+					entry, ok := candidate, true
+					if g > 0 || seen != nil {
+						entry, ok = e.list.get(candidate.id)
+					}
+					if !ok {
+						continue
+					}
+					found = true
+					best = max(best, e.weight*termScore(entry, doc.lens, e.idf))
+				}
+				if !found {
+					inAll = false
+					break
+				}
+				score += best
+			}
+			if inAll {
+				matches = append(matches, scoredMatch{id: candidate.id, score: score, product: doc.product})
+			}
+		}
+	}
+	return matches
+}
+
+// suggest returns the most common names and brands starting with prefix
+func (idx *searchIndex) suggest(prefix string, limit int) []models.Suggestion {
+	key := suggestionKey(prefix)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var suggestions []models.Suggestion
+	for _, source := range []struct {
+		field string
+		trie  *trie
+	}{
+		{"name", idx.names},
+		{"brand", idx.brands},
+	} {
+		for _, entry := range source.trie.topWithPrefix(key, limit) {
+			suggestions = append(suggestions, models.Suggestion{Text: entry.display, Field: source.field, Count: entry.count})
+		}
+	}
+	return topSuggestions(suggestions, limit)
+}
+
+// topSuggestions orders completions by descending count, then text, and keeps limit
+func topSuggestions(suggestions []models.Suggestion, limit int) []models.Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return strings.ToLower(a.Text) < strings.ToLower(b.Text)
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// all returns every indexed product with a zero score, for filter-only searches
+func (idx *searchIndex) all() []scoredMatch {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	matches := make([]scoredMatch, 0, len(idx.docs))
+	for id, doc := range idx.docs {
+		matches = append(matches, scoredMatch{id: id, product: doc.product})
+	}
+	return matches
+}
+
+// uniqueTerms drops repeated query terms so "apple apple" scores like "apple"
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]struct{}, len(terms))
+	unique := terms[:0]
+	for _, term := range terms {
+		if _, ok := seen[term]; !ok {
+			seen[term] = struct{}{}
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// topMatches returns the k best matches in order, without sorting the full set
+func topMatches(matches []scoredMatch, k int, better func(a, b scoredMatch) bool) []scoredMatch {
+	if k >= len(matches) {
+		sort.Slice(matches, func(i, j int) bool { return better(matches[i], matches[j]) })
+		return matches
+	}
+
+	// Bounded heap with the worst kept match at the root
+	h := &matchHeap{better: better, items: make([]scoredMatch, 0, k)}
+	for _, m := range matches {
+		if len(h.items) < k {
+			heap.Push(h, m)
+		} else if better(m, h.items[0]) {
+			h.items[0] = m
+			heap.Fix(h, 0)
+		}
+	}
+
+	top := h.items
+	sort.Slice(top, func(i, j int) bool { return better(top[i], top[j]) })
+	return top
+}
+
+type matchHeap struct {
+	items  []scoredMatch
+	better func(a, b scoredMatch) bool
+}
+
+func (h *matchHeap) Len() int           { return len(h.items) }
+func (h *matchHeap) Less(i, j int) bool { return h.better(h.items[j], h.items[i]) }
+func (h *matchHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *matchHeap) Push(x interface{}) { h.items = append(h.items, x.(scoredMatch)) }
+func (h *matchHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"testing"
+)
+
+func TestProductStore_SearchRanking(t *testing.T) {
+	store := NewEmptyProductStore()
+
+	products := []*models.Product{
+		// "garden" only in the description
+		{ProductID: 1, SKU: "A", Manufacturer: "M", CategoryID: 3, Weight: 1, SomeOtherID: 1, Name: "Hose Reel", Category: "Home", Brand: "Acme", Description: "Great for any garden"},
+		// "garden" in the category
+		{ProductID: 2, SKU: "B", Manufacturer: "M", CategoryID: 3, Weight: 1, SomeOtherID: 1, Name: "Rake", Category: "Garden", Brand: "Acme"},
+		// "garden" in the name
+		{ProductID: 3, SKU: "C", Manufacturer: "M", CategoryID: 3, Weight: 1, SomeOtherID: 1, Name: "Garden Gloves", Category: "Home", Brand: "Acme"},
+		// "garden" in the brand
+		{ProductID: 4, SKU: "D", Manufacturer: "M", CategoryID: 3, Weight: 1, SomeOtherID: 1, Name: "Shovel", Category: "Home", Brand: "Garden"},
+	}
+	for _, p := range products {
+		store.AddOrUpdateProduct(p)
+	}
+
+	result, err := store.Search(SearchRequest{Query: "garden"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	// Default boosts: name > brand > category > description
+	want := []int32{3, 4, 2, 1}
+	if len(result.Products) != len(want) {
+		t.Fatalf("Expected %d results, got %d", len(want), len(result.Products))
+	}
+	for i, id := range want {
+		if result.Products[i].ProductID != id {
+			t.Errorf("Position %d: expected product %d, got %d", i, id, result.Products[i].ProductID)
+		}
+		if result.Products[i].Score <= 0 {
+			t.Errorf("Position %d: expected a positive score, got %v", i, result.Products[i].Score)
+		}
+	}
+
+	// Custom boosts change the order
+	boosts := SearchBoosts{Name: 1, Brand: 1, Category: 1, Description: 10}
+	result, _ = store.Search(SearchRequest{Query: "garden", Boosts: boosts})
+	if result.Products[0].ProductID != 1 {
+		t.Errorf("Expected description boost to rank product 1 first, got %d", result.Products[0].ProductID)
+	}
+}
+
+func TestProductStore_SearchTieBreaking(t *testing.T) {
+	store := NewEmptyProductStore()
+
+	// Identical text means identical scores, so product ID decides the order
+	for _, id := range []int32{30, 10, 20} {
+		store.AddOrUpdateProduct(&models.Product{ProductID: id, SKU: "A", Manufacturer: "M", CategoryID: 1, Weight: 1, SomeOtherID: 1, Name: "Same Name", Category: "Electronics", Brand: "Acme"})
+	}
+
+	for i := 0; i < 5; i++ {
+		result, _ := store.Search(SearchRequest{Query: "same", MaxResults: 2})
+		if result.TotalFound != 3 || len(result.Products) != 2 {
+			t.Fatalf("Expected 2 of 3 results, got %d of %d", len(result.Products), result.TotalFound)
+		}
+		if result.Products[0].ProductID != 10 || result.Products[1].ProductID != 20 {
+			t.Errorf("Expected ties ordered by ID [10 20], got [%d %d]", result.Products[0].ProductID, result.Products[1].ProductID)
+		}
+	}
+}
+
+func TestParseSearchBoosts(t *testing.T) {
+	boosts, err := ParseSearchBoosts("name=5, description=0.5")
+	if err != nil {
+		t.Fatalf("ParseSearchBoosts() error = %v", err)
+	}
+	want := SearchBoosts{Name: 5, Brand: DefaultSearchBoosts.Brand, Category: DefaultSearchBoosts.Category, Description: 0.5}
+	if boosts != want {
+		t.Errorf("Expected %+v, got %+v", want, boosts)
+	}
+
+	invalid := []string{"name", "name=abc", "name=-1", "color=2"}
+	for _, spec := range invalid {
+		if _, err := ParseSearchBoosts(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestTopMatches(t *testing.T) {
+	matches := []scoredMatch{{id: 1, score: 0.5}, {id: 2, score: 2}, {id: 3, score: 1}, {id: 4, score: 2}, {id: 5, score: 0.1}}
+
+	top := topMatches(append([]scoredMatch(nil), matches...), 3, byRelevance)
+	want := []int32{2, 4, 3}
+	for i, id := range want {
+		if top[i].id != id {
+			t.Errorf("Position %d: expected %d, got %d", i, id, top[i].id)
+		}
+	}
+
+	// k larger than the input returns everything sorted
+	all := topMatches(append([]scoredMatch(nil), matches...), 10, byRelevance)
+	if len(all) != 5 || all[4].id != 5 {
+		t.Errorf("Expected all 5 matches with product 5 last, got %+v", all)
+	}
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Sort orders for search results
+const (
+	SortRelevance = "relevance"
+	SortName      = "name"
+	SortWeight    = "weight"
+	SortProductID = "product_id"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed or was issued for a different sort
+var ErrInvalidCursor = errors.New("invalid search cursor")
+
+// ValidSearchSort reports whether s names a known sort ("" means relevance)
+func ValidSearchSort(s string) bool {
+	return s == "" || s == SortRelevance || s == SortName || s == SortWeight || s == SortProductID
+}
+
+// ValidSearchOrder reports whether s names a known order ("" means the sort's default)
+func ValidSearchOrder(s string) bool {
+	return s == "" || s == OrderAsc || s == OrderDesc
+}
+
+// normalizeSort fills in the defaults: text queries sort by relevance (best
+// first), filter-only browsing by product ID, and everything else ascending
+func normalizeSort(sortBy, order string, hasQuery bool) (string, string) {
+	if sortBy == "" {
+		sortBy = SortProductID
+		if hasQuery {
+			sortBy = SortRelevance
+		}
+	}
+	if order == "" {
+		order = OrderAsc
+		if sortBy == SortRelevance {
+			order = OrderDesc
+		}
+	}
+	return sortBy, order
+}
+
+// searchCursor marks the last result of a page by its sort key. The next page
+// starts strictly after that key, so products inserted or removed elsewhere in
+// the result set never shift the page boundary.
+type searchCursor struct {
+	Sort   string  `json:"s"`
+	Order  string  `json:"o"`
+	ID     int32   `json:"id"`
+	Score  float64 `json:"sc,omitempty"`
+	Name   string  `json:"n,omitempty"`
+	Weight int32   `json:"w,omitempty"`
+}
+
+// encode returns the opaque form handed to clients as next_cursor
+func (c searchCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses an opaque cursor and checks it belongs to the same sort
+func decodeCursor(token, sortBy, order string) (*searchCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor searchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sortBy || cursor.Order != order {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// cursorFor builds the cursor pointing just past m
+func cursorFor(m scoredMatch, sortBy, order string) searchCursor {
+	cursor := searchCursor{Sort: sortBy, Order: order, ID: m.id}
+	switch sortBy {
+	case SortRelevance:
+		cursor.Score = m.score
+	case SortName:
+		cursor.Name = m.name
+	case SortWeight:
+		cursor.Weight = m.weight
+	}
+	return cursor
+}
+
+// match turns the cursor back into a comparable position
+func (c *searchCursor) match() scoredMatch {
+	return scoredMatch{id: c.ID, score: c.Score, name: c.Name, weight: c.Weight}
+}
+
+// byRelevance orders by descending score, breaking ties by ascending product ID
+var byRelevance = searchOrder(SortRelevance, OrderDesc)
+
+// searchOrder returns a total ordering for the requested sort. Ties on the
+// sort key are always broken by ascending product ID so pages are deterministic.
+func searchOrder(sortBy, order string) func(a, b scoredMatch) bool {
+	desc := order == OrderDesc
+
+	return func(a, b scoredMatch) bool {
+		var c int
+		switch sortBy {
+		case SortRelevance:
+			c = cmp.Compare(a.score, b.score)
+		case SortName:
+			c = strings.Compare(a.name, b.name)
+		case SortWeight:
+			c = cmp.Compare(a.weight, b.weight)
+		case SortProductID:
+			c = cmp.Compare(a.id, b.id)
+		}
+
+		if c != 0 {
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return a.id < b.id
+	}
+}
+
+// paginate applies cursor, offset and limit to the full match set using the
+// given order, and returns the page plus whether more results follow it
+func paginate(matches []scoredMatch, cursor *searchCursor, offset, limit int, before func(a, b scoredMatch) bool) ([]scoredMatch, bool) {
+	if cursor != nil {
+		position := cursor.match()
+		after := matches[:0]
+		for _, m := range matches {
+			if before(position, m) {
+				after = append(after, m)
+			}
+		}
+		matches = after
+	}
+
+	// One extra result tells us whether there is a next page
+	top := topMatches(matches, offset+limit+1, before)
+	hasMore := len(top) > offset+limit
+
+	if offset >= len(top) {
+		return nil, false
+	}
+	end := min(offset+limit, len(top))
+	return top[offset:end], hasMore
+}
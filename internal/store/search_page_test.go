@@ -0,0 +1,116 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"fmt"
+	"testing"
+)
+
+func newPagingTestStore() *ProductStore {
+	store := NewEmptyProductStore()
+	for i := int32(1); i <= 25; i++ {
+		store.AddOrUpdateProduct(&models.Product{
+			ProductID: i, SKU: "SKU", Manufacturer: "M", CategoryID: 1, SomeOtherID: 1,
+			Name:     fmt.Sprintf("Widget %c", 'A'+(25-i)), // names run backwards from Y to A
+			Weight:   (i % 5) * 100,
+			Category: "Electronics",
+			Brand:    "Acme",
+		})
+	}
+	return store
+}
+
+// collectPages follows next_cursor until the last page
+func collectPages(t *testing.T, repo ProductRepository, req SearchRequest) []int32 {
+	t.Helper()
+
+	var ids []int32
+	for page := 0; page < 100; page++ {
+		result, err := repo.Search(req)
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		for _, p := range result.Products {
+			ids = append(ids, p.ProductID)
+		}
+		if result.NextCursor == "" {
+			return ids
+		}
+		req.Cursor = result.NextCursor
+	}
+	t.Fatal("pagination did not terminate")
+	return nil
+}
+
+func TestProductStore_SearchPagination(t *testing.T) {
+	store := newPagingTestStore()
+
+	// Offset paging
+	result, _ := store.Search(SearchRequest{Query: "widget", Sort: SortProductID, MaxResults: 10, Offset: 20})
+	if len(result.Products) != 5 || result.Products[0].ProductID != 21 || result.NextCursor != "" {
+		t.Errorf("Expected last page [21..25] without cursor, got %d products starting at %d (cursor %q)",
+			len(result.Products), result.Products[0].ProductID, result.NextCursor)
+	}
+	if result.TotalFound != 25 {
+		t.Errorf("Expected total_found 25, got %d", result.TotalFound)
+	}
+
+	// Cursor paging visits every result exactly once, in order
+	ids := collectPages(t, store, SearchRequest{Query: "widget", Sort: SortProductID, Order: OrderDesc, MaxResults: 7})
+	if len(ids) != 25 || ids[0] != 25 || ids[24] != 1 {
+		t.Errorf("Expected IDs 25..1, got %v", ids)
+	}
+}
+
+func TestProductStore_SearchSorting(t *testing.T) {
+	store := newPagingTestStore()
+
+	result, _ := store.Search(SearchRequest{Query: "widget", Sort: SortName, MaxResults: 3})
+	if result.Products[0].Name != "Widget A" || result.Products[2].Name != "Widget C" {
+		t.Errorf("Expected names ascending from Widget A, got %s..%s", result.Products[0].Name, result.Products[2].Name)
+	}
+
+	// Equal weights tie-break on product ID, across page boundaries
+	ids := collectPages(t, store, SearchRequest{Query: "widget", Sort: SortWeight, Order: OrderDesc, MaxResults: 4})
+	want := []int32{4, 9, 14, 19, 24, 3, 8}
+	for i, id := range want {
+		if ids[i] != id {
+			t.Fatalf("Expected weight-desc order to start %v, got %v", want, ids[:len(want)])
+		}
+	}
+	if len(ids) != 25 {
+		t.Errorf("Expected 25 results across pages, got %d", len(ids))
+	}
+}
+
+func TestProductStore_SearchCursorStableUnderWrites(t *testing.T) {
+	store := newPagingTestStore()
+
+	req := SearchRequest{Query: "widget", Sort: SortProductID, MaxResults: 10}
+	first, _ := store.Search(req)
+
+	// Writes on both sides of the page boundary while the client is paging
+	store.DeleteProduct(3)
+	store.AddOrUpdateProduct(&models.Product{ProductID: 5, SKU: "SKU", Manufacturer: "M", CategoryID: 1, SomeOtherID: 1, Name: "Widget Renamed", Category: "Electronics", Brand: "Acme"})
+	store.AddOrUpdateProduct(&models.Product{ProductID: 26, SKU: "SKU", Manufacturer: "M", CategoryID: 1, SomeOtherID: 1, Name: "Widget New", Category: "Electronics", Brand: "Acme"})
+
+	req.Cursor = first.NextCursor
+	second, _ := store.Search(req)
+	if second.Products[0].ProductID != 11 {
+		t.Errorf("Expected the second page to start at 11 despite earlier writes, got %d", second.Products[0].ProductID)
+	}
+}
+
+func TestProductStore_SearchInvalidCursor(t *testing.T) {
+	store := newPagingTestStore()
+
+	if _, err := store.Search(SearchRequest{Query: "widget", Cursor: "not-a-cursor"}); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor for garbage, got %v", err)
+	}
+
+	// A cursor issued for one sort cannot be replayed against another
+	result, _ := store.Search(SearchRequest{Query: "widget", Sort: SortName, MaxResults: 5})
+	if _, err := store.Search(SearchRequest{Query: "widget", Sort: SortWeight, Cursor: result.NextCursor}); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor for a mismatched sort, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Seed modes
+const (
+	SeedNone      = "none"      // start with an empty catalog
+	SeedSynthetic = "synthetic" // generate products
+	SeedFixture   = "fixture"   // load products from a JSONL or CSV file
+)
+
+// Distributions for assigning brands and categories to synthetic products
+const (
+	// DistributionCyclic assigns brands and categories round-robin by product
+	// ID, so every value is equally common (the original catalog)
+	DistributionCyclic = "cyclic"
+
+	// DistributionUniform draws brands and categories at random
+	DistributionUniform = "uniform"
+
+	// DistributionZipf draws brands and categories from a Zipf distribution,
+	// so the first few are far more common than the rest, as in real catalogs
+	DistributionZipf = "zipf"
+)
+
+// SeedConfig describes the catalog a store starts with when it has no data
+type SeedConfig struct {
+	Mode string
+
+	// Synthetic catalog shape
+	Size         int     // number of products, IDs 1..Size
+	Brands       int     // distinct brands
+	Categories   int     // distinct categories
+	Distribution string  // cyclic, uniform or zipf
+	ZipfS        float64 // Zipf skew exponent, > 1; larger is more skewed
+	RandomSeed   int64   // makes uniform and zipf catalogs reproducible
+
+	// FixturePath is a .jsonl or .csv file of products for the fixture mode
+	FixturePath string
+}
+
+// DefaultSeedConfig generates the original catalog: 100,000 products spread
+// evenly over 10 brands and 8 categories
+func DefaultSeedConfig() SeedConfig {
+	return SeedConfig{
+		Mode:         SeedSynthetic,
+		Size:         100000,
+		Brands:       len(brands),
+		Categories:   len(categories),
+		Distribution: DistributionCyclic,
+		ZipfS:        1.1,
+		RandomSeed:   1,
+	}
+}
+
+// Validate checks the config before any products are generated
+func (c SeedConfig) Validate() error {
+	switch c.Mode {
+	case SeedNone:
+		return nil
+	case SeedFixture:
+		if c.FixturePath == "" {
+			return errors.New("fixture seeding requires a fixture path")
+		}
+		return nil
+	case SeedSynthetic:
+	default:
+		return fmt.Errorf("unknown seed mode %q (expected none, synthetic or fixture)", c.Mode)
+	}
+
+	if c.Size < 0 {
+		return errors.New("seed size must not be negative")
+	}
+	if c.Brands < 1 || c.Categories < 1 {
+		return errors.New("seed brands and categories must be at least 1")
+	}
+	switch c.Distribution {
+	case DistributionCyclic, DistributionUniform:
+	case DistributionZipf:
+		if c.ZipfS <= 1 {
+			return errors.New("zipf exponent must be greater than 1")
+		}
+	default:
+		return fmt.Errorf("unknown seed distribution %q (expected cyclic, uniform or zipf)", c.Distribution)
+	}
+	return nil
+}
+
+// Describe summarizes the config for startup logs
+func (c SeedConfig) Describe() string {
+	switch c.Mode {
+	case SeedSynthetic:
+		return fmt.Sprintf("%d synthetic products (%d brands, %d categories, %s)", c.Size, c.Brands, c.Categories, c.Distribution)
+	case SeedFixture:
+		return "products from " + c.FixturePath
+	}
+	return "no products"
+}
+
+// forEachSeedProduct builds the seed catalog and hands each product to fn.
+// It is shared by every backend so they all seed the same catalog.
+func forEachSeedProduct(cfg SeedConfig, fn func(product *models.Product)) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	switch cfg.Mode {
+	case SeedSynthetic:
+		generateSynthetic(cfg, fn)
+	case SeedFixture:
+		return loadFixture(cfg.FixturePath, fn)
+	}
+	return nil
+}
+
+// generateSynthetic builds cfg.Size products named after their brand
+func generateSynthetic(cfg SeedConfig, fn func(product *models.Product)) {
+	brandNames := seedNames(brands, cfg.Brands, "Brand")
+	categoryNames := seedNames(categories, cfg.Categories, "Category")
+
+	random := rand.New(rand.NewSource(cfg.RandomSeed))
+	pickBrand := newSeedPicker(cfg, random, len(brandNames))
+	pickCategory := newSeedPicker(cfg, random, len(categoryNames))
+
+	for i := 1; i <= cfg.Size; i++ {
+		brand := brandNames[pickBrand(i)]
+		categoryIndex := pickCategory(i)
+		category := categoryNames[categoryIndex]
+
+		product := models.NewProduct(
+			int32(i),
+			fmt.Sprintf("Product %s %d", brand, i),
+			category,
+			brand,
+			fmt.Sprintf("Description for product %d in %s category by %s", i, category, brand),
+		)
+		product.CategoryID = int32(categoryIndex + 1)
+		product.Price = seedPrice(i)
+		fn(product)
+	}
+}
+
+// seedPrice spreads synthetic prices between $5.99 and $499.99
+func seedPrice(i int) float64 {
+	return float64(5+(i*37)%495) + 0.99
+}
+
+// seedNames returns n names, starting with the built-in ones and numbering
+// any beyond them ("Brand 11", "Category 9", ...)
+func seedNames(builtIn []string, n int, prefix string) []string {
+	names := make([]string, n)
+	for i := range names {
+		if i < len(builtIn) {
+			names[i] = builtIn[i]
+		} else {
+			names[i] = fmt.Sprintf("%s %d", prefix, i+1)
+		}
+	}
+	return names
+}
+
+// newSeedPicker returns a function choosing one of n values for product i
+func newSeedPicker(cfg SeedConfig, random *rand.Rand, n int) func(i int) int {
+	switch cfg.Distribution {
+	case DistributionUniform:
+		return func(int) int { return random.Intn(n) }
+	case DistributionZipf:
+		if n == 1 {
+			return func(int) int { return 0 }
+		}
+		zipf := rand.NewZipf(random, cfg.ZipfS, 1, uint64(n-1))
+		return func(int) int { return int(zipf.Uint64()) }
+	}
+	return func(i int) int { return (i - 1) % n }
+}
+
+// loadFixture reads products from a JSONL or CSV file, chosen by extension.
+// Every product must pass Validate; the first bad record aborts the load.
+func loadFixture(path string, fn func(product *models.Product)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open fixture: %w", err)
+	}
+	defer f.Close()
+
+	format := models.FormatJSONL
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		format = models.FormatCSV
+	}
+
+	reader, err := models.NewProductReader(format, f)
+	if err != nil {
+		return fmt.Errorf("read fixture %s: %w", path, err)
+	}
+	for {
+		product, line, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var recordErr *models.RecordError
+		if errors.As(err, &recordErr) {
+			err = recordErr.Err
+		}
+		if err == nil {
+			product.Version = 0
+			err = product.Validate()
+		}
+		if err != nil {
+			return fmt.Errorf("fixture %s line %d: %w", path, line, err)
+		}
+		fn(product)
+	}
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeedConfig_DefaultMatchesOriginalCatalog(t *testing.T) {
+	cfg := DefaultSeedConfig()
+	cfg.Size = 20
+
+	store, err := NewSeededProductStore(cfg)
+	if err != nil {
+		t.Fatalf("NewSeededProductStore() error = %v", err)
+	}
+	if store.Count() != 20 {
+		t.Fatalf("Expected 20 products, got %d", store.Count())
+	}
+
+	// Brands and categories cycle with the product ID
+	p, _ := store.Get(12)
+	if p.Name != "Product Beta 12" || p.Brand != "Beta" || p.Category != "Sports" || p.CategoryID != 4 || p.Weight != 112 {
+		t.Errorf("Expected the original product 12, got %+v", p)
+	}
+	if p.Version != 1 {
+		t.Errorf("Expected seeded products at version 1, got %d", p.Version)
+	}
+}
+
+func TestSeedConfig_Synthetic(t *testing.T) {
+	cfg := DefaultSeedConfig()
+	cfg.Size = 5000
+	cfg.Brands = 50
+	cfg.Categories = 12
+	cfg.Distribution = DistributionZipf
+	cfg.RandomSeed = 42
+
+	counts := make(map[string]int)
+	var first []string
+	err := forEachSeedProduct(cfg, func(p *models.Product) {
+		counts[p.Brand]++
+		if len(first) < 10 {
+			first = append(first, p.Brand)
+		}
+		if p.CategoryID < 1 || p.CategoryID > 12 {
+			t.Fatalf("Expected category IDs 1..12, got %d", p.CategoryID)
+		}
+	})
+	if err != nil {
+		t.Fatalf("forEachSeedProduct() error = %v", err)
+	}
+
+	// The first brand dominates and extra brands are numbered
+	if counts["Alpha"] < 5*counts["Kappa"] || counts["Alpha"] < 1000 {
+		t.Errorf("Expected a skewed brand distribution, got Alpha=%d Kappa=%d", counts["Alpha"], counts["Kappa"])
+	}
+	if counts["Brand 11"] == 0 {
+		t.Errorf("Expected generated brand names beyond the built-in ones, got %v", counts)
+	}
+
+	// The same random seed gives the same catalog
+	i := 0
+	forEachSeedProduct(cfg, func(p *models.Product) {
+		if i < len(first) && p.Brand != first[i] {
+			t.Fatalf("Expected product %d to have brand %s again, got %s", i+1, first[i], p.Brand)
+		}
+		i++
+	})
+}
+
+func TestSeedConfig_Fixture(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "products.csv")
+	os.WriteFile(good, []byte("product_id,sku,manufacturer,category_id,weight,some_other_id,name,category,brand\n"+
+		"7,S7,M,1,10,1,Lamp,Home,Acme\n9,S9,M,1,10,1,Desk,Home,Acme\n"), 0o644)
+
+	store, err := NewSeededProductStore(SeedConfig{Mode: SeedFixture, FixturePath: good})
+	if err != nil {
+		t.Fatalf("NewSeededProductStore() error = %v", err)
+	}
+	if store.Count() != 2 || !store.Exists(7) || !store.Exists(9) {
+		t.Errorf("Expected products 7 and 9 from the fixture, got %d products", store.Count())
+	}
+
+	bad := filepath.Join(dir, "products.jsonl")
+	os.WriteFile(bad, []byte(`{"product_id": 1, "sku": "S"}`+"\n"), 0o644)
+	if _, err := NewSeededProductStore(SeedConfig{Mode: SeedFixture, FixturePath: bad}); err == nil {
+		t.Error("Expected an invalid fixture product to fail seeding")
+	}
+}
+
+func TestSeedConfig_Validate(t *testing.T) {
+	none, err := NewSeededProductStore(SeedConfig{Mode: SeedNone})
+	if err != nil || none.Count() != 0 {
+		t.Errorf("Expected an empty store, got %v products (%v)", none.Count(), err)
+	}
+
+	invalid := []func(c *SeedConfig){
+		func(c *SeedConfig) { c.Mode = "random" },
+		func(c *SeedConfig) { c.Mode = SeedFixture },
+		func(c *SeedConfig) { c.Size = -1 },
+		func(c *SeedConfig) { c.Brands = 0 },
+		func(c *SeedConfig) { c.Distribution = "normal" },
+		func(c *SeedConfig) { c.Distribution, c.ZipfS = DistributionZipf, 1 },
+	}
+	for i, mutate := range invalid {
+		cfg := DefaultSeedConfig()
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected config %d to be invalid: %+v", i, cfg)
+		}
+	}
+}
@@ -0,0 +1,785 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	_ "github.com/glebarez/go-sqlite" // registers the pure-Go "sqlite" driver
+	_ "github.com/lib/pq"             // registers the "postgres" driver
+)
+
+// Supported SQL drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// productColumns lists the client-writable products table columns in models.Product field order
+const productColumns = "product_id, sku, manufacturer, category_id, weight, some_other_id, name, category, description, brand, price"
+
+// productSelectColumns adds the store-managed version column
+const productSelectColumns = productColumns + ", version"
+
+// productMigrations are applied in order; the index of each entry + 1 is its schema version.
+// Never edit an existing entry - append a new one instead.
+var productMigrations = []string{
+	`CREATE TABLE products (
+		product_id    INTEGER PRIMARY KEY,
+		sku           VARCHAR(100)  NOT NULL,
+		manufacturer  VARCHAR(200)  NOT NULL,
+		category_id   INTEGER       NOT NULL,
+		weight        INTEGER       NOT NULL,
+		some_other_id INTEGER       NOT NULL,
+		name          VARCHAR(200)  NOT NULL,
+		category      VARCHAR(100)  NOT NULL,
+		description   VARCHAR(1000) NOT NULL DEFAULT '',
+		brand         VARCHAR(100)  NOT NULL
+	)`,
+	`CREATE INDEX idx_products_category ON products (category)`,
+	`ALTER TABLE products ADD COLUMN version BIGINT NOT NULL DEFAULT 1`,
+	// Listing filters compare LOWER(field) and page by product_id
+	`CREATE INDEX idx_products_category_lower ON products (LOWER(category), product_id)`,
+	`CREATE INDEX idx_products_brand_lower ON products (LOWER(brand), product_id)`,
+	`CREATE INDEX idx_products_manufacturer_lower ON products (LOWER(manufacturer), product_id)`,
+	`ALTER TABLE products ADD COLUMN price NUMERIC(12, 2) NOT NULL DEFAULT 0`,
+}
+
+// SQLProductStore persists products in a relational database.
+// SQLite is used for local development and Postgres in production.
+type SQLProductStore struct {
+	db     *sql.DB
+	driver string
+}
+
+var _ ProductRepository = (*SQLProductStore)(nil)
+
+// OpenSQLProductStore connects to the database, applies pending migrations and
+// loads the seed catalog, if one is given, when the products table is empty
+func OpenSQLProductStore(driver, dsn string, seed *SeedConfig) (*SQLProductStore, error) {
+	db, err := openSQL(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SQLProductStore{db: db, driver: driver}
+	if err := migrate(s.db, s.rebind, "schema_migrations", productMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if seed != nil && seed.Mode != SeedNone && s.Count() == 0 {
+		log.Printf("Products table is empty, seeding %s...", seed.Describe())
+		if err := s.seed(*seed); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// openSQL connects to a SQLite or Postgres database
+func openSQL(driver, dsn string) (*sql.DB, error) {
+	if driver != DriverSQLite && driver != DriverPostgres {
+		return nil, fmt.Errorf("unsupported sql driver %q", driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", driver, err)
+	}
+	if driver == DriverSQLite {
+		// SQLite allows a single writer; one connection avoids SQLITE_BUSY errors
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect %s: %w", driver, err)
+	}
+	return db, nil
+}
+
+// migrate brings the schema up to date, recording each applied version in the
+// versions table. Each store keeps its own versions table so several stores
+// can share one database.
+func migrate(db *sql.DB, rebind func(string) string, versions string, migrations []string) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + versions + ` (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("create %s: %w", versions, err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM ` + versions).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	for version := current + 1; version <= len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("apply migration %d: %w", version, err)
+		}
+		if _, err := tx.Exec(rebind(`INSERT INTO `+versions+` (version) VALUES (?)`), version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("record migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit migration %d: %w", version, err)
+		}
+		log.Printf("Applied schema migration %d (%s)", version, versions)
+	}
+
+	return nil
+}
+
+// rebind converts ? placeholders to $1, $2, ... for Postgres
+func (s *SQLProductStore) rebind(query string) string {
+	return rebindQuery(s.driver, query)
+}
+
+// rebindQuery converts ? placeholders to the driver's placeholder style
+func rebindQuery(driver, query string) string {
+	if driver != DriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// upsertSQL works on both SQLite (3.24+) and Postgres
+const upsertSQL = `INSERT INTO products (` + productColumns + `)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (product_id) DO UPDATE SET
+		sku = excluded.sku,
+		manufacturer = excluded.manufacturer,
+		category_id = excluded.category_id,
+		weight = excluded.weight,
+		some_other_id = excluded.some_other_id,
+		name = excluded.name,
+		category = excluded.category,
+		description = excluded.description,
+		brand = excluded.brand,
+		price = excluded.price,
+		version = products.version + 1`
+
+// casSQL updates a product only if it still has the expected version
+const casSQL = `UPDATE products SET
+		sku = ?, manufacturer = ?, category_id = ?, weight = ?, some_other_id = ?,
+		name = ?, category = ?, description = ?, brand = ?, price = ?, version = version + 1
+	WHERE product_id = ? AND version = ?`
+
+// insertIfAbsentSQL creates a product only if its ID is still free
+const insertIfAbsentSQL = `INSERT INTO products (` + productColumns + `)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (product_id) DO NOTHING`
+
+func productArgs(p *models.Product) []interface{} {
+	return []interface{}{p.ProductID, p.SKU, p.Manufacturer, p.CategoryID, p.Weight, p.SomeOtherID, p.Name, p.Category, p.Description, p.Brand, p.Price}
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row rowScanner) (*models.Product, error) {
+	var p models.Product
+	err := row.Scan(&p.ProductID, &p.SKU, &p.Manufacturer, &p.CategoryID, &p.Weight, &p.SomeOtherID, &p.Name, &p.Category, &p.Description, &p.Brand, &p.Price, &p.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// seed bulk-loads the seed catalog in a single transaction
+func (s *SQLProductStore) seed(cfg SeedConfig) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(s.rebind(upsertSQL))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	var execErr error
+	seedErr := forEachSeedProduct(cfg, func(product *models.Product) {
+		if execErr == nil {
+			_, execErr = stmt.Exec(productArgs(product)...)
+		}
+	})
+	if seedErr == nil {
+		seedErr = execErr
+	}
+	if seedErr != nil {
+		tx.Rollback()
+		return fmt.Errorf("seed products: %w", seedErr)
+	}
+
+	return tx.Commit()
+}
+
+// Close releases the database connection pool
+func (s *SQLProductStore) Close() error {
+	return s.db.Close()
+}
+
+// Get implements ProductRepository
+func (s *SQLProductStore) Get(productID int32) (*models.Product, error) {
+	row := s.db.QueryRow(s.rebind(`SELECT `+productSelectColumns+` FROM products WHERE product_id = ?`), productID)
+	product, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	return product, err
+}
+
+// GetMany implements ProductRepository with a single IN query
+func (s *SQLProductStore) GetMany(productIDs []int32) ([]*models.Product, []int32, error) {
+	if len(productIDs) == 0 {
+		return []*models.Product{}, nil, nil
+	}
+
+	placeholders := make([]string, len(productIDs))
+	args := make([]interface{}, len(productIDs))
+	for i, productID := range productIDs {
+		placeholders[i] = "?"
+		args[i] = productID
+	}
+	rows, err := s.db.Query(s.rebind(`SELECT `+productSelectColumns+` FROM products WHERE product_id IN (`+strings.Join(placeholders, ", ")+`)`), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[int32]*models.Product, len(productIDs))
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		found[product.ProductID] = product
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Answer in request order, copying repeats so callers never share a product
+	products := make([]*models.Product, 0, len(found))
+	var missing []int32
+	for _, productID := range productIDs {
+		product, ok := found[productID]
+		if !ok {
+			missing = append(missing, productID)
+			continue
+		}
+		productCopy := *product
+		products = append(products, &productCopy)
+	}
+	return products, missing, nil
+}
+
+// Upsert implements ProductRepository
+func (s *SQLProductStore) Upsert(product *models.Product) error {
+	_, err := s.db.Exec(s.rebind(upsertSQL), productArgs(product)...)
+	return err
+}
+
+// UpsertBatch implements ProductRepository in a single transaction
+func (s *SQLProductStore) UpsertBatch(products []*models.Product) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(s.rebind(upsertSQL))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, product := range products {
+		if _, err := stmt.Exec(productArgs(product)...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// maxCreateAttempts bounds retries when concurrent creates race for the same ID
+const maxCreateAttempts = 5
+
+// Create implements ProductRepository by inserting under MAX(product_id)+1.
+// A concurrent writer may claim the same ID first, in which case the insert
+// is skipped by ON CONFLICT and retried with a fresh ID.
+func (s *SQLProductStore) Create(product *models.Product) (*models.Product, error) {
+	productCopy := *product
+	insert := s.rebind(`INSERT INTO products (` + productColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (product_id) DO NOTHING`)
+
+	for attempt := 0; attempt < maxCreateAttempts; attempt++ {
+		if err := s.db.QueryRow(`SELECT COALESCE(MAX(product_id), 0) + 1 FROM products`).Scan(&productCopy.ProductID); err != nil {
+			return nil, err
+		}
+
+		result, err := s.db.Exec(insert, productArgs(&productCopy)...)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			productCopy.Version = 1
+			return &productCopy, nil
+		}
+	}
+	return nil, fmt.Errorf("could not assign a product ID after %d attempts", maxCreateAttempts)
+}
+
+// CompareAndSwap implements ProductRepository with a conditional UPDATE, or a
+// conditional INSERT for expectedVersion 0
+func (s *SQLProductStore) CompareAndSwap(product *models.Product, expectedVersion int64) (*models.Product, error) {
+	if expectedVersion == 0 {
+		result, err := s.db.Exec(s.rebind(insertIfAbsentSQL), productArgs(product)...)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, ErrVersionConflict
+		}
+		stored := *product
+		stored.Version = 1
+		return &stored, nil
+	}
+
+	p := product
+	result, err := s.db.Exec(s.rebind(casSQL),
+		p.SKU, p.Manufacturer, p.CategoryID, p.Weight, p.SomeOtherID, p.Name, p.Category, p.Description, p.Brand, p.Price,
+		p.ProductID, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if !s.Exists(p.ProductID) {
+			return nil, ErrProductNotFound
+		}
+		return nil, ErrVersionConflict
+	}
+
+	stored := *product
+	stored.Version = expectedVersion + 1
+	return &stored, nil
+}
+
+// Delete implements ProductRepository
+func (s *SQLProductStore) Delete(productID int32) error {
+	result, err := s.db.Exec(s.rebind(`DELETE FROM products WHERE product_id = ?`), productID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// Exists implements ProductRepository
+func (s *SQLProductStore) Exists(productID int32) bool {
+	var one int
+	err := s.db.QueryRow(s.rebind(`SELECT 1 FROM products WHERE product_id = ?`), productID).Scan(&one)
+	return err == nil
+}
+
+// Count implements ProductRepository
+func (s *SQLProductStore) Count() int32 {
+	var count int32
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM products`).Scan(&count); err != nil {
+		log.Printf("Failed to count products: %v", err)
+	}
+	return count
+}
+
+// scanChunkSize is how many rows Scan reads per query
+const scanChunkSize = 500
+
+// Scan implements ProductRepository in product_id order. Rows are read in
+// keyset chunks and fn runs between queries, so a slow consumer (such as an
+// export streaming to a client) never holds a connection - with SQLite, the
+// only one - for longer than one chunk takes to read.
+func (s *SQLProductStore) Scan(fn func(product *models.Product) bool) error {
+	var after int32
+	for {
+		chunk, err := s.scanChunk(after)
+		if err != nil {
+			return err
+		}
+		for _, product := range chunk {
+			if !fn(product) {
+				return nil
+			}
+		}
+		if len(chunk) < scanChunkSize {
+			return nil
+		}
+		after = chunk[len(chunk)-1].ProductID
+	}
+}
+
+// scanChunk reads up to scanChunkSize products with IDs above after
+func (s *SQLProductStore) scanChunk(after int32) ([]*models.Product, error) {
+	rows, err := s.db.Query(s.rebind(`SELECT `+productSelectColumns+` FROM products WHERE product_id > ? ORDER BY product_id LIMIT ?`), after, scanChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunk := make([]*models.Product, 0, scanChunkSize)
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		chunk = append(chunk, product)
+	}
+	return chunk, rows.Err()
+}
+
+// List implements ProductRepository with keyset pagination on product_id. The
+// filters use the LOWER(field) indexes, so a listing reads only matching rows.
+func (s *SQLProductStore) List(req ListRequest) (*models.ProductListResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	where, args := sqlFilters(req.Filter).where("")
+	if where == "" {
+		where = " WHERE product_id > ?"
+	} else {
+		where += " AND product_id > ?"
+	}
+	args = append(args, req.After, limit+1)
+
+	rows, err := s.db.Query(s.rebind(`SELECT `+productSelectColumns+` FROM products`+where+` ORDER BY product_id LIMIT ?`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &models.ProductListResponse{Products: make([]models.Product, 0, limit)}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		if len(response.Products) == limit {
+			response.NextAfter = response.Products[limit-1].ProductID
+			break
+		}
+		response.Products = append(response.Products, *product)
+	}
+	return response, rows.Err()
+}
+
+// Search implements ProductRepository by pushing a case-insensitive LIKE match
+// on name, brand, category and description down to the database. By default
+// results are ranked by the sum of the boosts of the matching fields, ties
+// broken by product ID. Cursors use keyset pagination on (sort key, product_id).
+// Without a query every row matches and only the structured filters apply.
+// The whole table is always searched, so the strategy and MaxCheck are ignored.
+func (s *SQLProductStore) Search(req SearchRequest) (*models.SearchResponse, error) {
+	limit := req.MaxResults
+	if limit <= 0 {
+		limit = 20
+	}
+
+	hasQuery := req.Query != ""
+	sortBy, order := normalizeSort(req.Sort, req.Order, hasQuery)
+	cursor, err := decodeCursor(req.Cursor, sortBy, order)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := "%" + escapeLike(strings.ToLower(req.Query)) + "%"
+	columns := [numSearchFields]string{"name", "brand", "category", "description"}
+	weights := req.Boosts.weights()
+
+	conditions := make([]string, 0, len(columns))
+	scoreTerms := make([]string, 0, len(columns))
+	matchArgs := make([]interface{}, 0, len(columns))
+	for f, column := range columns {
+		match := "LOWER(" + column + `) LIKE ? ESCAPE '\'`
+		conditions = append(conditions, match)
+		// Boosts are numbers formatted by us, so inlining them is safe
+		scoreTerms = append(scoreTerms, "CASE WHEN "+match+" THEN "+strconv.FormatFloat(weights[f], 'f', -1, 64)+" ELSE 0 END")
+		matchArgs = append(matchArgs, pattern)
+	}
+	score := "(" + strings.Join(scoreTerms, " + ") + ")"
+	scoreArgs := matchArgs
+	if !hasQuery {
+		score, scoreArgs = "0", nil
+	}
+
+	filters := sqlFilters(req.Filter)
+	if hasQuery {
+		filters.text = sqlClause{"(" + strings.Join(conditions, " OR ") + ")", matchArgs}
+	}
+	where, args := filters.where("")
+
+	var totalFound int
+	if err := s.db.QueryRow(s.rebind(`SELECT COUNT(*) FROM products`+where), args...).Scan(&totalFound); err != nil {
+		return nil, err
+	}
+
+	facets, err := s.searchFacets(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	queryArgs := append(append([]interface{}{}, scoreArgs...), args...)
+	query := `SELECT ` + productSelectColumns + `, score FROM (SELECT ` + productSelectColumns + `, ` + score + ` AS score FROM products` + where + `) AS matches`
+
+	sortExpr := map[string]string{
+		SortRelevance: "score",
+		SortName:      "LOWER(name)",
+		SortWeight:    "weight",
+		SortProductID: "product_id",
+	}[sortBy]
+	direction, after := "ASC", ">"
+	if order == OrderDesc {
+		direction, after = "DESC", "<"
+	}
+
+	if cursor != nil {
+		var key interface{}
+		switch sortBy {
+		case SortRelevance:
+			key = cursor.Score
+		case SortName:
+			key = cursor.Name
+		case SortWeight:
+			key = cursor.Weight
+		}
+
+		if sortBy == SortProductID {
+			query += ` WHERE product_id ` + after + ` ?`
+			queryArgs = append(queryArgs, cursor.ID)
+		} else {
+			query += ` WHERE (` + sortExpr + ` ` + after + ` ? OR (` + sortExpr + ` = ? AND product_id > ?))`
+			queryArgs = append(queryArgs, key, key, cursor.ID)
+		}
+	}
+
+	query += ` ORDER BY ` + sortExpr + ` ` + direction
+	if sortBy != SortProductID {
+		query += `, product_id ASC`
+	}
+
+	// One extra row tells us whether there is a next page
+	query += ` LIMIT ? OFFSET ?`
+	queryArgs = append(queryArgs, limit+1, req.Offset)
+
+	rows, err := s.db.Query(s.rebind(query), queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]models.SearchResult, 0, limit)
+	hasMore := false
+	for rows.Next() {
+		if len(results) == limit {
+			hasMore = true
+			break
+		}
+
+		var result models.SearchResult
+		p := &result.Product
+		err := rows.Scan(&p.ProductID, &p.SKU, &p.Manufacturer, &p.CategoryID, &p.Weight, &p.SomeOtherID, &p.Name, &p.Category, &p.Description, &p.Brand, &p.Price, &p.Version, &result.Score)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	response := &models.SearchResponse{
+		Products:   results,
+		TotalFound: totalFound,
+		Facets:     facets,
+	}
+	if hasMore {
+		last := results[len(results)-1]
+		response.NextCursor = cursorFor(scoredMatch{
+			id:     last.ProductID,
+			score:  last.Score,
+			name:   strings.ToLower(last.Name),
+			weight: last.Weight,
+		}, sortBy, order).encode()
+	}
+	return response, nil
+}
+
+// Suggest implements ProductRepository with a prefix LIKE on name and brand,
+// grouping case variants of the same value like the in-memory tries do.
+func (s *SQLProductStore) Suggest(prefix string, limit int) ([]models.Suggestion, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	pattern := escapeLike(suggestionKey(prefix)) + "%"
+
+	var suggestions []models.Suggestion
+	for _, field := range []string{"name", "brand"} {
+		rows, err := s.db.Query(s.rebind(`SELECT MIN(`+field+`), COUNT(*) FROM products WHERE LOWER(`+field+`) LIKE ? ESCAPE '\' `+
+			`GROUP BY LOWER(`+field+`) ORDER BY COUNT(*) DESC, LOWER(`+field+`) LIMIT ?`), pattern, limit)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			suggestion := models.Suggestion{Field: field}
+			if err := rows.Scan(&suggestion.Text, &suggestion.Count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return topSuggestions(suggestions, limit), nil
+}
+
+// sqlClause is a boolean SQL expression with its placeholder arguments
+type sqlClause struct {
+	sql  string
+	args []interface{}
+}
+
+// searchClauses holds the WHERE conditions of a search, kept apart so facet
+// queries can drop the condition on the field they count
+type searchClauses struct {
+	text, category, brand, manufacturer, weight sqlClause
+}
+
+// sqlFilters translates a SearchFilter into SQL conditions
+func sqlFilters(f SearchFilter) searchClauses {
+	var c searchClauses
+	c.category = inClause("category", f.Categories)
+	c.brand = inClause("brand", f.Brands)
+	c.manufacturer = inClause("manufacturer", f.Manufacturers)
+
+	var weight []string
+	if f.MinWeight != nil {
+		weight = append(weight, "weight >= ?")
+		c.weight.args = append(c.weight.args, *f.MinWeight)
+	}
+	if f.MaxWeight != nil {
+		weight = append(weight, "weight <= ?")
+		c.weight.args = append(c.weight.args, *f.MaxWeight)
+	}
+	c.weight.sql = strings.Join(weight, " AND ")
+	return c
+}
+
+// inClause matches column case-insensitively against any of values
+func inClause(column string, values []string) sqlClause {
+	if len(values) == 0 {
+		return sqlClause{}
+	}
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		placeholders[i] = "?"
+		args[i] = strings.ToLower(v)
+	}
+	return sqlClause{"LOWER(" + column + ") IN (" + strings.Join(placeholders, ", ") + ")", args}
+}
+
+// where joins every condition except the one on skip ("category" or "brand")
+func (c searchClauses) where(skip string) (string, []interface{}) {
+	clauses := []sqlClause{c.text, c.manufacturer, c.weight}
+	if skip != "category" {
+		clauses = append(clauses, c.category)
+	}
+	if skip != "brand" {
+		clauses = append(clauses, c.brand)
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, clause := range clauses {
+		if clause.sql != "" {
+			conditions = append(conditions, clause.sql)
+			args = append(args, clause.args...)
+		}
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// searchFacets counts matches per category and brand, each facet ignoring
+// the filter on its own field
+func (s *SQLProductStore) searchFacets(c searchClauses) (*models.SearchFacets, error) {
+	facets := &models.SearchFacets{}
+	for _, facet := range []struct {
+		column string
+		into   *[]models.FacetCount
+	}{
+		{"category", &facets.Categories},
+		{"brand", &facets.Brands},
+	} {
+		where, args := c.where(facet.column)
+		rows, err := s.db.Query(s.rebind(`SELECT `+facet.column+`, COUNT(*) FROM products`+where+` GROUP BY `+facet.column), args...)
+		if err != nil {
+			return nil, err
+		}
+
+		counts := make(map[string]int)
+		for rows.Next() {
+			var value string
+			var count int
+			if err := rows.Scan(&value, &count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			counts[value] = count
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		*facet.into = sortedFacets(counts)
+	}
+	return facets, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"path/filepath"
+	"testing"
+)
+
+func newTestSQLStore(t *testing.T) *SQLProductStore {
+	t.Helper()
+
+	s, err := OpenSQLProductStore(DriverSQLite, filepath.Join(t.TempDir(), "products.db"), nil)
+	if err != nil {
+		t.Fatalf("OpenSQLProductStore() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLProductStore_CRUD(t *testing.T) {
+	s := newTestSQLStore(t)
+
+	if _, err := s.Get(1); err != ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+
+	product := testProduct(1, "ABC123")
+	if err := s.Upsert(product); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	product.SKU = "XYZ789"
+	if err := s.Upsert(product); err != nil {
+		t.Fatalf("Upsert() update error = %v", err)
+	}
+
+	retrieved, err := s.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	product.Version = 2 // inserted, then updated once
+	if *retrieved != *product {
+		t.Errorf("Expected %+v, got %+v", product, retrieved)
+	}
+	if s.Count() != 1 || !s.Exists(1) {
+		t.Errorf("Expected one stored product, count=%d", s.Count())
+	}
+
+	if err := s.Delete(1); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := s.Delete(1); err != ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound on second delete, got %v", err)
+	}
+	if s.Count() != 0 {
+		t.Errorf("Expected count 0 after delete, got %d", s.Count())
+	}
+}
+
+func TestSQLProductStore_MigrationsAreIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.db")
+
+	s, err := OpenSQLProductStore(DriverSQLite, path, nil)
+	if err != nil {
+		t.Fatalf("OpenSQLProductStore() error = %v", err)
+	}
+	s.Upsert(testProduct(1, "ABC"))
+	s.Close()
+
+	// Reopening must not re-run migrations or lose data
+	reopened, err := OpenSQLProductStore(DriverSQLite, path, nil)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	if !reopened.Exists(1) {
+		t.Error("Product should survive reopening the database")
+	}
+}
+
+func TestSQLProductStore_Search(t *testing.T) {
+	s := newTestSQLStore(t)
+
+	products := []*models.Product{
+		{ProductID: 1, SKU: "ABC1", Manufacturer: "Mfg1", CategoryID: 1, Weight: 100, SomeOtherID: 1, Name: "iPhone 15", Category: "Electronics", Brand: "Apple"},
+		{ProductID: 2, SKU: "ABC2", Manufacturer: "Mfg2", CategoryID: 2, Weight: 200, SomeOtherID: 2, Name: "MacBook Pro", Category: "Electronics", Brand: "Apple"},
+		{ProductID: 3, SKU: "ABC3", Manufacturer: "Mfg3", CategoryID: 3, Weight: 300, SomeOtherID: 3, Name: "100% Cotton", Category: "Clothing", Brand: "Hanes"},
+	}
+	for _, p := range products {
+		s.Upsert(p)
+	}
+
+	result, err := s.Search(SearchRequest{Query: "electronics", MaxResults: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(result.Products) != 1 || result.TotalFound != 2 {
+		t.Errorf("Expected 1 result of 2 found, got %d of %d", len(result.Products), result.TotalFound)
+	}
+
+	// LIKE wildcards in the query are matched literally
+	result, _ = s.Search(SearchRequest{Query: "%", MaxResults: 10})
+	if result.TotalFound != 1 || result.Products[0].ProductID != 3 {
+		t.Errorf("Expected only product 3 to match '%%', got %+v", result)
+	}
+}
+
+func TestSQLProductStore_SeedIfEmpty(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping 100,000 product seed in short mode")
+	}
+
+	seed := DefaultSeedConfig()
+	s, err := OpenSQLProductStore(DriverSQLite, filepath.Join(t.TempDir(), "products.db"), &seed)
+	if err != nil {
+		t.Fatalf("OpenSQLProductStore() error = %v", err)
+	}
+	defer s.Close()
+
+	if s.Count() != 100000 {
+		t.Errorf("Expected 100000 seeded products, got %d", s.Count())
+	}
+}
+
+func TestSQLProductStore_SearchRanking(t *testing.T) {
+	s := newTestSQLStore(t)
+
+	s.Upsert(&models.Product{ProductID: 1, SKU: "A", Manufacturer: "M", CategoryID: 3, Weight: 1, SomeOtherID: 1, Name: "Hose Reel", Category: "Home", Brand: "Acme", Description: "Great for any garden"})
+	s.Upsert(&models.Product{ProductID: 2, SKU: "B", Manufacturer: "M", CategoryID: 3, Weight: 1, SomeOtherID: 1, Name: "Garden Gloves", Category: "Home", Brand: "Acme"})
+
+	result, err := s.Search(SearchRequest{Query: "garden"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(result.Products) != 2 || result.Products[0].ProductID != 2 {
+		t.Fatalf("Expected the name match (product 2) first, got %+v", result.Products)
+	}
+	if result.Products[0].Score <= result.Products[1].Score {
+		t.Errorf("Expected descending scores, got %v then %v", result.Products[0].Score, result.Products[1].Score)
+	}
+}
+
+func TestSQLProductStore_SearchPagination(t *testing.T) {
+	s := newTestSQLStore(t)
+	for i := int32(1); i <= 25; i++ {
+		s.Upsert(&models.Product{ProductID: i, SKU: "SKU", Manufacturer: "M", CategoryID: 1, SomeOtherID: 1, Weight: (i % 5) * 100, Name: "Widget", Category: "Electronics", Brand: "Acme"})
+	}
+
+	ids := collectPages(t, s, SearchRequest{Query: "widget", Sort: SortWeight, Order: OrderDesc, MaxResults: 4})
+	want := []int32{4, 9, 14, 19, 24, 3, 8}
+	for i, id := range want {
+		if ids[i] != id {
+			t.Fatalf("Expected weight-desc order to start %v, got %v", want, ids[:len(want)])
+		}
+	}
+	if len(ids) != 25 {
+		t.Errorf("Expected 25 results across pages, got %d", len(ids))
+	}
+
+	// Relevance cursors work too (every row scores the same here)
+	if ids := collectPages(t, s, SearchRequest{Query: "widget", MaxResults: 10}); len(ids) != 25 || ids[0] != 1 {
+		t.Errorf("Expected 25 relevance-ordered results starting at 1, got %v", ids)
+	}
+}
+
+func TestSQLProductStore_Create(t *testing.T) {
+	testCreate(t, newTestSQLStore(t))
+}
+
+func TestSQLProductStore_CompareAndSwap(t *testing.T) {
+	testCompareAndSwap(t, newTestSQLStore(t))
+}
+
+func TestSQLProductStore_UpsertBatch(t *testing.T) {
+	testUpsertBatch(t, newTestSQLStore(t))
+}
+
+func TestSQLProductStore_GetMany(t *testing.T) {
+	testGetMany(t, newTestSQLStore(t))
+}
+
+// Scan must not hold SQLite's only connection while fn runs, so fn can query
+// the store itself
+func TestSQLProductStore_ScanReleasesConnection(t *testing.T) {
+	s := newTestSQLStore(t)
+
+	products := make([]*models.Product, 2*scanChunkSize+1)
+	for i := range products {
+		products[i] = testProduct(int32(i+1), "ABC")
+	}
+	if err := s.UpsertBatch(products); err != nil {
+		t.Fatalf("UpsertBatch() error = %v", err)
+	}
+
+	var last int32
+	scanned := 0
+	err := s.Scan(func(product *models.Product) bool {
+		if product.ProductID != last+1 || !s.Exists(product.ProductID) {
+			t.Fatalf("Expected product %d in order, got %d", last+1, product.ProductID)
+		}
+		last = product.ProductID
+		scanned++
+		return true
+	})
+	if err != nil || scanned != len(products) {
+		t.Errorf("Expected %d products scanned, got %d (err=%v)", len(products), scanned, err)
+	}
+
+	scanned = 0
+	s.Scan(func(product *models.Product) bool {
+		scanned++
+		return scanned < scanChunkSize+10
+	})
+	if scanned != scanChunkSize+10 {
+		t.Errorf("Expected the scan to stop when fn returns false, got %d products", scanned)
+	}
+}
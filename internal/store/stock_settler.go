@@ -0,0 +1,101 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"log"
+	"time"
+)
+
+// StockSettler settles the stock reservations of orders that another process
+// finishes: async orders keep their stock reserved while they wait in the
+// queue, and the order processor only records whether payment went through.
+// It checks the order store every interval, selling the units of paid orders
+// and releasing those of failed ones.
+type StockSettler struct {
+	inventory *InventoryStore
+	orders    OrderStore
+	interval  time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStockSettler creates a settler that is not running yet
+func NewStockSettler(inventory *InventoryStore, orders OrderStore, interval time.Duration) *StockSettler {
+	return &StockSettler{
+		inventory: inventory,
+		orders:    orders,
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start settles the reservations of orders that are already finished and
+// then keeps settling them in the background until Close is called
+func (s *StockSettler) Start() {
+	if _, err := s.Settle(); err != nil {
+		log.Printf("Settling order stock failed: %v", err)
+	}
+	go s.loop()
+}
+
+// loop settles finished orders every interval until Close is called
+func (s *StockSettler) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.Settle(); err != nil {
+				log.Printf("Settling order stock failed: %v", err)
+			}
+		}
+	}
+}
+
+// Close stops the background loop
+func (s *StockSettler) Close() {
+	close(s.stop)
+	<-s.done
+}
+
+// Settle commits the reservation of every paid order (completed, shipped,
+// refunding or refunded) and releases that of every failed order, returning
+// how many reservations it settled. Reservations of orders still pending or
+// processing are kept, and so are those of cancelled orders, which the
+// cancellation releases itself.
+func (s *StockSettler) Settle() (int, error) {
+	settled := 0
+	for _, orderID := range s.inventory.Reservations() {
+		order, err := s.orders.Get(orderID)
+		if err == ErrOrderNotFound {
+			continue // not recorded yet
+		}
+		if err != nil {
+			return settled, err
+		}
+
+		switch order.Status {
+		case models.StatusCompleted, models.StatusShipped, models.StatusRefunding, models.StatusRefunded:
+			err = s.inventory.Commit(orderID)
+		case models.StatusFailed:
+			err = s.inventory.Release(orderID)
+		default:
+			continue
+		}
+		if err == ErrReservationUnknown {
+			continue // settled by the server in the meantime
+		}
+		if err != nil {
+			return settled, err
+		}
+		settled++
+	}
+	return settled, nil
+}
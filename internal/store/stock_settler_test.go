@@ -0,0 +1,52 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStockSettler_Settle(t *testing.T) {
+	inventory := NewInventoryStore()
+	inventory.SetOnHand(42, DefaultWarehouseID, 10)
+	orders := NewMemoryOrderStore()
+
+	now := time.Now()
+	for _, o := range []struct{ id, status string }{
+		{"paid", models.StatusCompleted},
+		{"declined", models.StatusFailed},
+		{"queued", models.StatusPending},
+		{"unrecorded", ""},
+	} {
+		if _, err := inventory.Reserve(o.id, []ReservationLine{{ProductID: 42, Quantity: 2}}, ReserveOptions{}); err != nil {
+			t.Fatalf("Reserve() error = %v", err)
+		}
+		if o.status == "" {
+			continue
+		}
+		order := &models.Order{OrderID: o.id, CustomerID: 1}
+		order.Begin(now)
+		if o.status != models.StatusPending {
+			order.Transition(models.StatusProcessing, now, "")
+			order.Transition(o.status, now, "")
+		}
+		orders.Create(order)
+	}
+
+	settler := NewStockSettler(inventory, orders, time.Minute)
+	settled, err := settler.Settle()
+	if err != nil || settled != 2 {
+		t.Fatalf("Expected the paid and the failed order settled, got %d, %v", settled, err)
+	}
+	if inv := inventory.Get(42); inv.OnHand != 8 || inv.Reserved != 4 {
+		t.Errorf("Expected 2 units sold and 4 still held, got %+v", inv)
+	}
+	if held := inventory.Reservations(); !reflect.DeepEqual(held, []string{"queued", "unrecorded"}) {
+		t.Errorf("Expected the unfinished orders to keep their stock, got %v", held)
+	}
+
+	if settled, err := settler.Settle(); err != nil || settled != 0 {
+		t.Errorf("Expected nothing left to settle, got %d, %v", settled, err)
+	}
+}
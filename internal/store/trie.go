@@ -0,0 +1,300 @@
+package store
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+)
+
+// trie is a compressed prefix tree (radix tree) over lowercased keys. Each key
+// carries a count - how many products contain it - and the display form it was
+// first added with. Keys are compared byte-wise. It is not safe for concurrent
+// use; searchIndex guards it with its own lock.
+type trie struct {
+	root trieNode
+}
+
+type trieNode struct {
+	label    string      // edge label leading into this node
+	children []*trieNode // ordered by the first byte of their label
+	count    int         // products holding the key ending here (0 = not a key)
+	display  string      // original spelling of the key ending here
+	maxCount int         // largest count in this subtree, for top-k pruning
+}
+
+func commonPrefixLen(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// child returns the index of the child whose label starts with b, or where to insert it
+func (n *trieNode) child(b byte) (int, bool) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].label[0] >= b })
+	return i, i < len(n.children) && n.children[i].label[0] == b
+}
+
+func (n *trieNode) updateMax() {
+	n.maxCount = n.count
+	for _, c := range n.children {
+		n.maxCount = max(n.maxCount, c.maxCount)
+	}
+}
+
+// add counts one more occurrence of key
+func (t *trie) add(key, display string) {
+	t.root.add(key, display)
+}
+
+func (n *trieNode) add(key, display string) {
+	defer n.updateMax()
+
+	if key == "" {
+		if n.count == 0 {
+			n.display = display
+		}
+		n.count++
+		return
+	}
+
+	i, found := n.child(key[0])
+	if !found {
+		leaf := &trieNode{label: key}
+		leaf.add("", display)
+		n.children = append(n.children, nil)
+		copy(n.children[i+1:], n.children[i:])
+		n.children[i] = leaf
+		return
+	}
+
+	c := n.children[i]
+	common := commonPrefixLen(c.label, key)
+	if common < len(c.label) {
+		// Split the edge where the keys diverge
+		mid := &trieNode{label: c.label[:common], children: []*trieNode{c}, maxCount: c.maxCount}
+		c.label = c.label[common:]
+		n.children[i] = mid
+		c = mid
+	}
+	c.add(key[common:], display)
+}
+
+// remove drops one occurrence of key and reports whether it was present
+func (t *trie) remove(key string) bool {
+	return t.root.remove(key)
+}
+
+func (n *trieNode) remove(key string) bool {
+	if key == "" {
+		if n.count == 0 {
+			return false
+		}
+		n.count--
+		if n.count == 0 {
+			n.display = ""
+		}
+		n.updateMax()
+		return true
+	}
+
+	i, found := n.child(key[0])
+	if !found || !strings.HasPrefix(key, n.children[i].label) {
+		return false
+	}
+	c := n.children[i]
+	if !c.remove(key[len(c.label):]) {
+		return false
+	}
+
+	// Prune empty leaves and merge pass-through nodes back into their child
+	switch {
+	case c.count == 0 && len(c.children) == 0:
+		n.children = append(n.children[:i], n.children[i+1:]...)
+	case c.count == 0 && len(c.children) == 1:
+		only := c.children[0]
+		only.label = c.label + only.label
+		n.children[i] = only
+	}
+	n.updateMax()
+	return true
+}
+
+// find returns the node at the end of prefix and the full key leading to it.
+// The prefix may end part-way along an edge.
+func (t *trie) find(prefix string) (*trieNode, string) {
+	n, path := &t.root, ""
+	for prefix != "" {
+		i, found := n.child(prefix[0])
+		if !found {
+			return nil, ""
+		}
+		c := n.children[i]
+		common := commonPrefixLen(c.label, prefix)
+		if common < len(prefix) && common < len(c.label) {
+			return nil, ""
+		}
+		n, path, prefix = c, path+c.label, prefix[common:]
+	}
+	return n, path
+}
+
+// trieEntry is a key with its count and display form
+type trieEntry struct {
+	key     string
+	display string
+	count   int
+}
+
+// withPrefix returns every key starting with prefix, in key order
+func (t *trie) withPrefix(prefix string, limit int) []trieEntry {
+	n, path := t.find(prefix)
+	if n == nil {
+		return nil
+	}
+
+	var entries []trieEntry
+	var walk func(n *trieNode, path string) bool
+	walk = func(n *trieNode, path string) bool {
+		if n.count > 0 {
+			entries = append(entries, trieEntry{key: path, display: n.display, count: n.count})
+			if limit > 0 && len(entries) >= limit {
+				return false
+			}
+		}
+		for _, c := range n.children {
+			if !walk(c, path+c.label) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(n, path)
+	return entries
+}
+
+// topWithPrefix returns the k most frequent keys starting with prefix, ties in
+// key order. It expands subtrees best-first by their maxCount, so only a small
+// part of a large subtree is visited.
+func (t *trie) topWithPrefix(prefix string, k int) []trieEntry {
+	n, path := t.find(prefix)
+	if n == nil || k <= 0 {
+		return nil
+	}
+
+	frontier := &trieFrontier{{node: n, trieEntry: trieEntry{key: path, count: n.maxCount}}}
+	var top []trieEntry
+	for frontier.Len() > 0 && len(top) < k {
+		item := heap.Pop(frontier).(trieFrontierItem)
+		if item.node == nil {
+			top = append(top, item.trieEntry)
+			continue
+		}
+		if item.node.count > 0 {
+			heap.Push(frontier, trieFrontierItem{trieEntry: trieEntry{key: item.key, display: item.node.display, count: item.node.count}})
+		}
+		for _, c := range item.node.children {
+			heap.Push(frontier, trieFrontierItem{node: c, trieEntry: trieEntry{key: item.key + c.label, count: c.maxCount}})
+		}
+	}
+	return top
+}
+
+// trieFrontierItem is either a finished key (node == nil) or a subtree whose
+// best possible entry has count and sorts no earlier than key
+type trieFrontierItem struct {
+	trieEntry
+	node *trieNode
+}
+
+type trieFrontier []trieFrontierItem
+
+func (f trieFrontier) Len() int { return len(f) }
+func (f trieFrontier) Less(i, j int) bool {
+	if f[i].count != f[j].count {
+		return f[i].count > f[j].count
+	}
+	if f[i].key != f[j].key {
+		return f[i].key < f[j].key
+	}
+	return f[i].node == nil // a finished key before the subtree it heads
+}
+func (f trieFrontier) Swap(i, j int)       { f[i], f[j] = f[j], f[i] }
+func (f *trieFrontier) Push(x interface{}) { *f = append(*f, x.(trieFrontierItem)) }
+func (f *trieFrontier) Pop() interface{} {
+	old := *f
+	last := old[len(old)-1]
+	*f = old[:len(old)-1]
+	return last
+}
+
+// fuzzyMatch is a key within the allowed edit distance of a query term
+type fuzzyMatch struct {
+	key      string
+	distance int
+}
+
+// fuzzy returns every key within maxDist edits of term, counting insertions,
+// deletions, substitutions and swaps of adjacent bytes (optimal string
+// alignment distance). It walks the trie carrying the last two rows of the
+// edit-distance table and abandons a branch as soon as no cell in the current
+// row is within maxDist.
+func (t *trie) fuzzy(term string, maxDist int) []fuzzyMatch {
+	row := make([]int, len(term)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	var matches []fuzzyMatch
+	var walk func(n *trieNode, path string, prev, row []int)
+	walk = func(n *trieNode, path string, prev, row []int) {
+		for _, c := range n.children {
+			before, next, key, alive := prev, row, path, true
+			for j := 0; j < len(c.label) && alive; j++ {
+				before, next = next, editDistanceRow(before, next, term, key, c.label[j])
+				key += c.label[j : j+1]
+				alive = minInts(next) <= maxDist
+			}
+			if !alive {
+				continue
+			}
+			if c.count > 0 && next[len(term)] <= maxDist {
+				matches = append(matches, fuzzyMatch{key: key, distance: next[len(term)]})
+			}
+			walk(c, key, before, next)
+		}
+	}
+	walk(&t.root, "", nil, row)
+	return matches
+}
+
+// editDistanceRow computes the next row of the edit-distance table after
+// appending b to path. prev2 is the row before prev (nil at the root).
+func editDistanceRow(prev2, prev []int, term, path string, b byte) []int {
+	row := make([]int, len(prev))
+	row[0] = prev[0] + 1
+	for i := 1; i < len(row); i++ {
+		cost := 1
+		if term[i-1] == b {
+			cost = 0
+		}
+		row[i] = min(row[i-1]+1, prev[i]+1, prev[i-1]+cost)
+
+		// Adjacent swap: term[i-2:i] matches the last two path bytes reversed
+		if prev2 != nil && i > 1 && term[i-1] == path[len(path)-1] && term[i-2] == b {
+			row[i] = min(row[i], prev2[i-2]+1)
+		}
+	}
+	return row
+}
+
+func minInts(values []int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		m = min(m, v)
+	}
+	return m
+}
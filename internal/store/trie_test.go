@@ -0,0 +1,178 @@
+package store
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"sort"
+	"testing"
+)
+
+func TestTrie_AddRemove(t *testing.T) {
+	tr := &trie{}
+	for _, key := range []string{"apple", "app", "apricot", "banana", "app"} {
+		tr.add(key, key)
+	}
+
+	entries := tr.withPrefix("ap", 0)
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.key)
+	}
+	if want := []string{"app", "apple", "apricot"}; len(keys) != 3 || keys[0] != want[0] || keys[1] != want[1] || keys[2] != want[2] {
+		t.Fatalf("Expected %v in key order, got %v", want, keys)
+	}
+	if entries[0].count != 2 {
+		t.Errorf("Expected 'app' to be counted twice, got %d", entries[0].count)
+	}
+
+	// Removing keys prunes and re-merges edges without losing neighbours
+	tr.remove("app")
+	tr.remove("app")
+	tr.remove("apricot")
+	if tr.remove("missing") {
+		t.Error("Removing an unknown key should report false")
+	}
+	if got := tr.withPrefix("a", 0); len(got) != 1 || got[0].key != "apple" {
+		t.Errorf("Expected only 'apple' left under 'a', got %+v", got)
+	}
+	if len(tr.root.children) != 2 || tr.root.children[0].label != "apple" {
+		t.Errorf("Expected the 'ap' edge merged back into 'apple', got %q", tr.root.children[0].label)
+	}
+	if got := tr.withPrefix("appl", 0); len(got) != 1 {
+		t.Errorf("Expected a prefix ending mid-edge to match, got %+v", got)
+	}
+}
+
+func TestTrie_TopWithPrefix(t *testing.T) {
+	tr := &trie{}
+	counts := map[string]int{"alpha": 5, "alps": 1, "altitude": 5, "also": 3, "beta": 9}
+	for key, n := range counts {
+		for i := 0; i < n; i++ {
+			tr.add(key, key)
+		}
+	}
+
+	top := tr.topWithPrefix("al", 3)
+	want := []string{"alpha", "altitude", "also"}
+	if len(top) != len(want) {
+		t.Fatalf("Expected %v, got %+v", want, top)
+	}
+	for i, key := range want {
+		if top[i].key != key || top[i].count != counts[key] {
+			t.Errorf("Expected #%d to be %s (%d), got %+v", i, key, counts[key], top[i])
+		}
+	}
+
+	// Counts drop as keys are removed
+	for i := 0; i < 5; i++ {
+		tr.remove("alpha")
+	}
+	if top := tr.topWithPrefix("al", 1); top[0].key != "altitude" {
+		t.Errorf("Expected altitude after removing alpha, got %+v", top)
+	}
+}
+
+func TestTrie_Fuzzy(t *testing.T) {
+	tr := &trie{}
+	for _, key := range []string{"gamma", "game", "gym", "electronics", "electric"} {
+		tr.add(key, key)
+	}
+
+	tests := []struct {
+		term    string
+		maxDist int
+		want    []string
+	}{
+		{"gama", 1, []string{"game", "gamma"}},
+		{"gamm", 0, nil},
+		{"electronic", 2, []string{"electric", "electronics"}},
+		{"elctronics", 1, []string{"electronics"}},
+		{"gmama", 1, []string{"gamma"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range tr.fuzzy(tt.term, tt.maxDist) {
+			got = append(got, m.key)
+		}
+		sort.Strings(got)
+		if len(got) != len(tt.want) {
+			t.Errorf("fuzzy(%q, %d) = %v, want %v", tt.term, tt.maxDist, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("fuzzy(%q, %d) = %v, want %v", tt.term, tt.maxDist, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestProductStore_FuzzySearch(t *testing.T) {
+	store := NewEmptyProductStore()
+	store.AddOrUpdateProduct(&models.Product{ProductID: 1, SKU: "A", Manufacturer: "M", CategoryID: 1, Weight: 1, SomeOtherID: 1, Name: "Radio", Category: "Electronics", Brand: "Gamma"})
+	store.AddOrUpdateProduct(&models.Product{ProductID: 2, SKU: "B", Manufacturer: "M", CategoryID: 2, Weight: 1, SomeOtherID: 1, Name: "Shirt", Category: "Clothing", Brand: "Alpha"})
+
+	for _, query := range []string{"electronic", "gama", "electr", "gama rdio", "raido"} {
+		result, _ := store.Search(SearchRequest{Query: query, Fuzzy: true})
+		if result.TotalFound != 1 || result.Products[0].ProductID != 1 {
+			t.Errorf("Expected fuzzy %q to find product 1, got %+v", query, result.Products)
+		}
+
+		if result, _ := store.Search(SearchRequest{Query: query}); result.TotalFound != 0 {
+			t.Errorf("Expected exact %q to find nothing, got %d", query, result.TotalFound)
+		}
+	}
+
+	// A term that is in the index matches only itself
+	store.AddOrUpdateProduct(&models.Product{ProductID: 3, SKU: "C", Manufacturer: "M", CategoryID: 1, Weight: 1, SomeOtherID: 1, Name: "Game", Category: "Toys", Brand: "Acme"})
+	result, _ := store.Search(SearchRequest{Query: "game", Fuzzy: true})
+	if result.TotalFound != 1 || result.Products[0].ProductID != 3 {
+		t.Errorf("Expected a known term to match only exactly, got %+v", result.Products)
+	}
+}
+
+func testSuggest(t *testing.T, repo ProductRepository) {
+	t.Helper()
+	products := []*models.Product{
+		{ProductID: 1, SKU: "A", Manufacturer: "M", CategoryID: 1, Weight: 1, SomeOtherID: 1, Name: "Gamepad", Category: "Electronics", Brand: "Gamma"},
+		{ProductID: 2, SKU: "B", Manufacturer: "M", CategoryID: 1, Weight: 1, SomeOtherID: 1, Name: "Garden Hose", Category: "Home", Brand: "Gamma"},
+		{ProductID: 3, SKU: "C", Manufacturer: "M", CategoryID: 1, Weight: 1, SomeOtherID: 1, Name: "Gadget", Category: "Electronics", Brand: "Beta"},
+	}
+	for _, p := range products {
+		repo.Upsert(p)
+	}
+
+	suggestions, err := repo.Suggest("ga", 3)
+	if err != nil {
+		t.Fatalf("Suggest() error = %v", err)
+	}
+	want := []models.Suggestion{
+		{Text: "Gamma", Field: "brand", Count: 2},
+		{Text: "Gadget", Field: "name", Count: 1},
+		{Text: "Gamepad", Field: "name", Count: 1},
+	}
+	if len(suggestions) != len(want) {
+		t.Fatalf("Expected %+v, got %+v", want, suggestions)
+	}
+	for i := range want {
+		if suggestions[i] != want[i] {
+			t.Errorf("Expected suggestion %d to be %+v, got %+v", i, want[i], suggestions[i])
+		}
+	}
+
+	// Renames are reflected immediately
+	products[2].Name = "Widget"
+	repo.Upsert(products[2])
+	suggestions, _ = repo.Suggest("GAD", 10)
+	if len(suggestions) != 0 {
+		t.Errorf("Expected no suggestions for a renamed product, got %+v", suggestions)
+	}
+}
+
+func TestProductStore_Suggest(t *testing.T) {
+	testSuggest(t, NewEmptyProductStore())
+}
+
+func TestSQLProductStore_Suggest(t *testing.T) {
+	testSuggest(t, newTestSQLStore(t))
+}
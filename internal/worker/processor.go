@@ -2,7 +2,10 @@ package worker
 
 import (
 	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/payment"
+	"CS6650_Online_Store/internal/store"
 	"encoding/json"
+	"errors"
 	"log"
 	"os"
 	"strconv"
@@ -22,13 +25,16 @@ type OrderProcessor struct {
 
 	// Payment gateway bottleneck - same as synchronous handler
 	// This simulates the real payment processor limitation
-	paymentGateway chan struct{}
+	payments payment.Gateway
 
 	// WaitGroup to track active workers
 	wg sync.WaitGroup
 
 	// Channel to signal shutdown
 	shutdown chan struct{}
+
+	// Order records shared with the server; nil keeps no record
+	orders store.OrderStore
 }
 
 // NewOrderProcessor creates a new order processor
@@ -56,17 +62,54 @@ func NewOrderProcessor() (*OrderProcessor, error) {
 	}
 
 	processor := &OrderProcessor{
-		sqsClient:      sqs.New(sess),
-		queueURL:       queueURL,
-		workerCount:    workerCount,
-		paymentGateway: make(chan struct{}, 1), // Buffer size 1 = only 1 payment at a time
-		shutdown:       make(chan struct{}),
+		sqsClient:   sqs.New(sess),
+		queueURL:    queueURL,
+		workerCount: workerCount,
+		payments:    payment.NewSimulatedGateway(3 * time.Second), // only 1 payment at a time
+		shutdown:    make(chan struct{}),
 	}
 
 	log.Printf("Order processor initialized - Queue: %s, Workers: %d", queueURL, workerCount)
 	return processor, nil
 }
 
+// SetOrderStore makes the processor record each order's status in the store
+// the server reads GET /orders/{id} from
+func (p *OrderProcessor) SetOrderStore(orders store.OrderStore) {
+	p.orders = orders
+}
+
+// setStatus moves the order to a new status through the order state machine,
+// in the order store as well. An order already in that status is left alone,
+// so a message retried after a failure picks up where it stopped. An order
+// the store does not know yet (the server records orders elsewhere) is added
+// as received from the queue.
+func (p *OrderProcessor) setStatus(order *models.Order, status, reason string) error {
+	move := func(o *models.Order) error {
+		if o.Status == status {
+			return nil
+		}
+		return o.Transition(status, time.Now(), reason)
+	}
+
+	if p.orders == nil {
+		return move(order)
+	}
+
+	updated, err := p.orders.Update(order.OrderID, move)
+	if err == store.ErrOrderNotFound {
+		if err := move(order); err != nil {
+			return err
+		}
+		return p.orders.Create(order)
+	}
+	if err != nil {
+		return err
+	}
+	*order = *updated
+	return nil
+}
+
 // Start begins processing orders from SQS
 // This is the main loop that continuously polls SQS and spawns worker goroutines
 func (p *OrderProcessor) Start() {
@@ -99,9 +142,9 @@ func (p *OrderProcessor) pollAndProcess() {
 	// - MaxNumberOfMessages: 10 (receive up to 10 messages)
 	input := &sqs.ReceiveMessageInput{
 		QueueUrl:            aws.String(p.queueURL),
-		MaxNumberOfMessages: aws.Int64(10),               // Receive up to 10 messages
-		WaitTimeSeconds:     aws.Int64(20),               // Long polling - wait up to 20s
-		VisibilityTimeout:   aws.Int64(30),               // 30 seconds to process before message becomes visible again
+		MaxNumberOfMessages: aws.Int64(10), // Receive up to 10 messages
+		WaitTimeSeconds:     aws.Int64(20), // Long polling - wait up to 20s
+		VisibilityTimeout:   aws.Int64(30), // 30 seconds to process before message becomes visible again
 		MessageAttributeNames: aws.StringSlice([]string{
 			"All", // Receive all message attributes
 		}),
@@ -136,42 +179,82 @@ func (p *OrderProcessor) processMessage(message *sqs.Message) {
 		return
 	}
 
+	// Delete message from SQS once the order is finished with
+	if p.processOrder(&order) && p.deleteMessage(message, order.OrderID) {
+		log.Printf("Order %s %s and removed from queue", order.OrderID, order.Status)
+	}
+}
+
+// processOrder charges a queued order and records the outcome, reporting
+// whether the order is finished with and its message can be deleted. The
+// order keeps its stock reserved on the server until then; the server's
+// StockSettler sells it once the order is completed, or releases it once the
+// order has failed.
+func (p *OrderProcessor) processOrder(order *models.Order) bool {
 	log.Printf("Processing order %s (customer %d) with %d items",
 		order.OrderID, order.CustomerID, len(order.Items))
 
+	err := p.setStatus(order, models.StatusProcessing, "")
+	if errors.Is(err, models.ErrInvalidTransition) {
+		// A redelivered message for an order that is already finished (or was
+		// cancelled): it must not be charged again
+		log.Printf("Skipping order %s: %v", order.OrderID, err)
+		return true
+	}
+	if err != nil {
+		log.Printf("Failed to record order %s as processing: %v", order.OrderID, err)
+	}
+
 	// Simulate payment processing with bottleneck
 	// This is the same 3-second bottleneck as synchronous processing
 	startTime := time.Now()
 
-	// Acquire payment gateway lock (blocks if busy)
-	p.paymentGateway <- struct{}{}
-	log.Printf("Order %s acquired payment gateway lock", order.OrderID)
-
-	// Simulate 3-second payment verification
-	time.Sleep(3 * time.Second)
-
-	// Release lock
-	<-p.paymentGateway
+	// Charge through the gateway (blocks while it is busy). The gateway
+	// charges an order only once, so a message redelivered while the order is
+	// still processing (a worker died or ran past the visibility timeout)
+	// does not charge it again. A failed charge fails the order, as it does
+	// for sync orders; if that cannot be recorded the message is left on the
+	// queue so the order is retried.
+	if err := p.payments.Charge(order.OrderID, order.Total); err != nil {
+		log.Printf("Payment for order %s failed: %v", order.OrderID, err)
+		err = p.setStatus(order, models.StatusFailed, "payment failed")
+		if err != nil && !errors.Is(err, models.ErrInvalidTransition) {
+			log.Printf("Failed to record order %s as failed: %v", order.OrderID, err)
+			return false
+		}
+		return true
+	}
 
 	processingTime := time.Since(startTime)
 	log.Printf("Order %s payment completed in %v", order.OrderID, processingTime)
 
-	// Update order status
-	order.Status = models.StatusCompleted
+	// Update order status; if it cannot be recorded the message is left on
+	// the queue so the order is retried. Orders can only be cancelled while
+	// pending, so a refused transition means another delivery of the message
+	// already finished the order.
+	err = p.setStatus(order, models.StatusCompleted, "payment verified")
+	if errors.Is(err, models.ErrInvalidTransition) {
+		log.Printf("Order %s not completed: %v", order.OrderID, err)
+	} else if err != nil {
+		log.Printf("Failed to record order %s as completed: %v", order.OrderID, err)
+		return false
+	}
+	return true
+}
 
-	// Delete message from SQS (order processed successfully)
+// deleteMessage removes a handled message from the queue, reporting whether it did
+func (p *OrderProcessor) deleteMessage(message *sqs.Message, orderID string) bool {
 	deleteInput := &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(p.queueURL),
 		ReceiptHandle: message.ReceiptHandle,
 	}
 
 	if _, err := p.sqsClient.DeleteMessage(deleteInput); err != nil {
-		log.Printf("Failed to delete message for order %s: %v", order.OrderID, err)
+		log.Printf("Failed to delete message for order %s: %v", orderID, err)
 		// Message will become visible again and be reprocessed
-		return
+		return false
 	}
-
-	log.Printf("Order %s completed and removed from queue", order.OrderID)
+	return true
 }
 
 // Stop gracefully stops the processor
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"CS6650_Online_Store/internal/models"
+	"CS6650_Online_Store/internal/store"
+	"errors"
+	"testing"
+	"time"
+)
+
+// declinedGateway refuses every charge
+type declinedGateway struct{}
+
+func (declinedGateway) Charge(orderID string, amount float64) error {
+	return errors.New("card declined")
+}
+
+func (declinedGateway) Refund(orderID string, amount float64) error { return nil }
+
+func TestOrderProcessor_FailedPaymentReleasesStock(t *testing.T) {
+	// The server's side: stock reserved for a queued order
+	inventory := store.NewInventoryStore()
+	inventory.SetOnHand(42, store.DefaultWarehouseID, 5)
+	orders := store.NewMemoryOrderStore()
+
+	order := models.Order{OrderID: "order-1", CustomerID: 7, Items: []models.Item{{ProductID: 42, Quantity: 2}}, Total: 20}
+	allocations, err := inventory.Reserve(order.OrderID, []store.ReservationLine{{ProductID: 42, Quantity: 2}}, store.ReserveOptions{})
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	order.Allocations = allocations
+	order.Begin(time.Now())
+	orders.Create(&order)
+
+	p := &OrderProcessor{payments: declinedGateway{}, orders: orders}
+	queued := order
+	if !p.processOrder(&queued) {
+		t.Fatal("Expected the message of a declined order to be deleted")
+	}
+	stored, _ := orders.Get(order.OrderID)
+	if stored.Status != models.StatusFailed {
+		t.Fatalf("Expected the order to fail, got %s", stored.Status)
+	}
+
+	// The settler gives the failed order's units back
+	if _, err := store.NewStockSettler(inventory, orders, time.Minute).Settle(); err != nil {
+		t.Fatalf("Settle() error = %v", err)
+	}
+	if inv := inventory.Get(42); inv.OnHand != 5 || inv.Available != 5 {
+		t.Errorf("Expected all 5 units available again, got %+v", inv)
+	}
+
+	// A redelivered message is dropped without charging again
+	if !p.processOrder(&order) {
+		t.Error("Expected a redelivered message of a failed order to be deleted")
+	}
+}
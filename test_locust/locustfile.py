@@ -2,6 +2,23 @@ from locust import HttpUser, FastHttpUser, task, between
 import random
 import json
 
+
+def if_match_headers(client, product_id):
+    """
+    Fetch the product's current ETag so an update can send it in If-Match.
+    The server rejects updates of existing products without one (428).
+    A product that does not exist yet needs no header.
+    """
+    with client.get(
+        f"/products/{product_id}",
+        catch_response=True,
+        name="/products/[id] (etag)"
+    ) as response:
+        if response.status_code in (200, 404):
+            response.success()
+        etag = response.headers.get("ETag") if response.status_code == 200 else None
+    return {"If-Match": etag} if etag else {}
+
 class ProductAPIUser(HttpUser):
     """
     Simulates user behavior for the Product API.
@@ -45,6 +62,7 @@ class ProductAPIUser(HttpUser):
         with self.client.post(
             f"/products/{product_id}/details",
             json=product_data,
+            headers=if_match_headers(self.client, product_id),
             catch_response=True,
             name="/products/[id]/details"
         ) as response:
@@ -109,6 +127,7 @@ class ProductAPIUser(HttpUser):
         with self.client.post(
             f"/products/{product_id}/details",
             json=product_data,
+            headers=if_match_headers(self.client, product_id),
             catch_response=True,
             name="/products/[id]/details"
         ) as response:
@@ -198,7 +217,8 @@ class FastProductAPIUser(FastHttpUser):
             "weight": random.randint(100, 5000),
             "some_other_id": random.randint(1, 1000)
         }
-        self.client.post(f"/products/{product_id}/details", json=product_data)
+        self.client.post(f"/products/{product_id}/details", json=product_data,
+                         headers=if_match_headers(self.client, product_id))
     
     @task(10)
     def get_product(self):
@@ -217,5 +237,6 @@ class FastProductAPIUser(FastHttpUser):
             "weight": random.randint(100, 5000),
             "some_other_id": random.randint(1, 1000)
         }
-        self.client.post(f"/products/{product_id}/details", json=product_data)
+        self.client.post(f"/products/{product_id}/details", json=product_data,
+                         headers=if_match_headers(self.client, product_id))
         self.product_ids.append(product_id)
\ No newline at end of file